@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// agentEvent is what happened to the agent since its last reported status.
+type agentEvent int
+
+const (
+	// agentEventLoaded is a state fetched and installed by a reload.
+	agentEventLoaded agentEvent = iota
+	// agentEventLoadFailed is a reload that failed to fetch or install the state.
+	agentEventLoadFailed
+	// agentEventReady is Init completing, reported by Config.ReportLifecycle.
+	agentEventReady
+	// agentEventStopped is Close, reported by Config.ReportLifecycle.
+	agentEventStopped
+)
+
+// AgentStoppedError is the error of the status posted by Close with
+// Config.ReportLifecycle: upstream has no stopped status, so a stopped agent is
+// reported in error.
+const AgentStoppedError = "agent stopped"
+
+// TriggerReason is what started a reload, sent along the agent status it posts.
+type TriggerReason string
+
+const (
+	// TriggerReasonStartup is the first load, by Init or by Start on a client not
+	// loaded yet.
+	TriggerReasonStartup TriggerReason = "startup"
+	// TriggerReasonTimer is a reload of the Start loop on IntervalCheck.
+	TriggerReasonTimer TriggerReason = "timer"
+	// TriggerReasonManual is a call to Reload, ReloadDetailed or ReloadAsync.
+	TriggerReasonManual TriggerReason = "manual"
+	// TriggerReasonTrigger is a reload of the Start loop asked by TriggerReload.
+	TriggerReasonTrigger TriggerReason = "trigger"
+)
+
+type triggerReasonKey struct{}
+
+func withTriggerReason(ctx context.Context, reason TriggerReason) context.Context {
+	return context.WithValue(ctx, triggerReasonKey{}, reason)
+}
+
+// triggerReason returns the reason of the reload running with ctx, or "" outside
+// of a reload, e.g. for the lifecycle statuses.
+func triggerReason(ctx context.Context) TriggerReason {
+	reason, _ := ctx.Value(triggerReasonKey{}).(TriggerReason)
+	return reason
+}
+
+func (e agentEvent) String() string {
+	switch e {
+	case agentEventLoaded:
+		return "loaded"
+	case agentEventLoadFailed:
+		return "load failed"
+	case agentEventReady:
+		return "ready"
+	case agentEventStopped:
+		return "stopped"
+	default:
+		return fmt.Sprintf("agentEvent(%d)", int(e))
+	}
+}
+
+// nextAgentStatus returns the status to report after event, current being the
+// status of the last load (empty before the first one, e.g. for a state installed
+// by Warmup). Announcing a ready agent after a failed load is an error.
+func nextAgentStatus(current types.AgentStatus, event agentEvent) (types.AgentStatus, error) {
+	if current != "" && !current.IsValid() {
+		return "", fmt.Errorf("invalid agent status: %s", current)
+	}
+	switch event {
+	case agentEventLoaded:
+		return types.AgentStatusSuccess, nil
+	case agentEventLoadFailed:
+		return types.AgentStatusError, nil
+	case agentEventReady:
+		if current == types.AgentStatusError {
+			return "", fmt.Errorf("invalid agent status transition: %s from %q", event, current)
+		}
+		return types.AgentStatusSuccess, nil
+	case agentEventStopped:
+		return types.AgentStatusError, nil
+	default:
+		return "", fmt.Errorf("unknown agent event: %s", event)
+	}
+}
+
+// transition moves agentStatus on after event and returns the status to report.
+// Stopping does not change the status of the last load. The caller holds reloadMu.
+func (c *client) transition(event agentEvent) (types.AgentStatus, error) {
+	status, err := nextAgentStatus(c.agentStatus, event)
+	if err != nil {
+		return "", err
+	}
+	if event == agentEventStopped {
+		return status, nil
+	}
+	c.agentStatus = status
+	return status, nil
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextAgentStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		current types.AgentStatus
+		event   agentEvent
+		want    types.AgentStatus
+		wantErr bool
+	}{
+		{name: "first load", current: "", event: agentEventLoaded, want: types.AgentStatusSuccess},
+		{name: "load after success", current: types.AgentStatusSuccess, event: agentEventLoaded, want: types.AgentStatusSuccess},
+		{name: "recovery", current: types.AgentStatusError, event: agentEventLoaded, want: types.AgentStatusSuccess},
+		{name: "first load failed", current: "", event: agentEventLoadFailed, want: types.AgentStatusError},
+		{name: "failure after success", current: types.AgentStatusSuccess, event: agentEventLoadFailed, want: types.AgentStatusError},
+		{name: "failure after failure", current: types.AgentStatusError, event: agentEventLoadFailed, want: types.AgentStatusError},
+		{name: "ready after success", current: types.AgentStatusSuccess, event: agentEventReady, want: types.AgentStatusSuccess},
+		{name: "ready without load", current: "", event: agentEventReady, want: types.AgentStatusSuccess},
+		{name: "ready after failure", current: types.AgentStatusError, event: agentEventReady, wantErr: true},
+		{name: "stopped after success", current: types.AgentStatusSuccess, event: agentEventStopped, want: types.AgentStatusError},
+		{name: "stopped after failure", current: types.AgentStatusError, event: agentEventStopped, want: types.AgentStatusError},
+		{name: "invalid current", current: types.AgentStatus("pending"), event: agentEventLoaded, wantErr: true},
+		{name: "unknown event", current: types.AgentStatusSuccess, event: agentEvent(42), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextAgentStatus(tt.current, tt.event)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAgentEvent_String(t *testing.T) {
+	assert.Equal(t, "loaded", agentEventLoaded.String())
+	assert.Equal(t, "load failed", agentEventLoadFailed.String())
+	assert.Equal(t, "ready", agentEventReady.String())
+	assert.Equal(t, "agentEvent(42)", agentEvent(42).String())
+}
+
+func TestClient_Reload_TracksAgentStatus(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(nil, errors.New("connection refused"))
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.Error(t, c.Reload())
+	assert.Equal(t, types.AgentStatusError, c.agentStatus)
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, types.AgentStatusSuccess, c.agentStatus)
+}
+
+func TestClient_reportLifecycle_AfterFailedLoad(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ReportLifecycle = true
+	c.State.Store(&State{ProjectVersion: 3, RedirectMatcher: types.NewRedirectTreeMatcher()})
+	c.agentStatus = types.AgentStatusError
+
+	err := c.reportLifecycle(context.Background(), agentEventReady)
+
+	assert.Error(t, err)
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func agentHits(mockHTTP *mockHTTPClient) int {
+	hits := 0
+	for _, call := range mockHTTP.calls {
+		if call.Method == http.MethodPatch {
+			hits++
+		}
+	}
+	return hits
+}
+
+func TestClient_Reload_RegistersOnce(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
+	assert.NoError(t, c.Reload())
+	for i := 0; i < 3; i++ {
+		mockHTTP.expect(makeVersionResponse("4"), nil)
+		mockHTTP.expect(makeAgentResponse(), nil)
+		assert.NoError(t, c.Reload())
+	}
+
+	assert.Len(t, agentPosts(t, mockHTTP, c.config().GetUrlApiAgents()), 1)
+	assert.Equal(t, 3, agentHits(mockHTTP))
+}
+
+func TestClient_Reload_RepeatedFailureSendsHit(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	for i := 0; i < 2; i++ {
+		mockHTTP.expect(makeVersionResponse("2"), nil)
+		mockHTTP.expect(makeVersionResponse("2"), nil)
+		mockHTTP.expect(nil, errors.New("connection refused"))
+		mockHTTP.expect(makeAgentResponse(), nil)
+		assert.Error(t, c.Reload())
+	}
+	expectLoad(mockHTTP, testLoad{version: "4"})
+	assert.NoError(t, c.Reload())
+
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	assert.Len(t, posts, 2)
+	assert.Equal(t, types.AgentStatusError, posts[0].Status)
+	assert.Equal(t, types.AgentStatusSuccess, posts[1].Status)
+	assert.Equal(t, 1, agentHits(mockHTTP))
+}
+
+func TestClient_Reload_ExtraAgentTypes(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ExtraAgentTypes = []types.AgentType{types.AgentTypeTraefik, types.AgentTypeDefault}
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Init())
+	mockHTTP.expect(makeVersionResponse("4"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	if assert.Len(t, posts, 2) {
+		assert.Equal(t, types.AgentTypeDefault, posts[0].Type)
+		assert.Equal(t, types.AgentTypeTraefik, posts[1].Type)
+		for _, post := range posts {
+			assert.Equal(t, "test-node", post.Name)
+			assert.Equal(t, 4, post.Version)
+			assert.Equal(t, types.AgentStatusSuccess, post.Status)
+		}
+	}
+	assert.Equal(t, 1, agentHits(mockHTTP))
+}
+
+func TestClient_Init_InvalidExtraAgentType(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ExtraAgentTypes = []types.AgentType{"nginx"}
+
+	err := c.Init()
+
+	assert.EqualError(t, err, "invalid agent type: nginx")
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func postedTriggerReasons(t *testing.T, mockHTTP *mockHTTPClient, url string) []TriggerReason {
+	t.Helper()
+	var reasons []TriggerReason
+	for _, call := range mockHTTP.calls {
+		if call.Method != http.MethodPost || call.URL.String() != url {
+			continue
+		}
+		body, _ := call.GetBody()
+		var payload agentStatusPayload
+		assert.NoError(t, json.NewDecoder(body).Decode(&payload))
+		reasons = append(reasons, payload.TriggerReason)
+	}
+	return reasons
+}
+
+func TestClient_TriggerReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		reload func(c *client) error
+		want   TriggerReason
+	}{
+		{name: "Init", reload: (*client).Init, want: TriggerReasonStartup},
+		{name: "Reload", reload: (*client).Reload, want: TriggerReasonManual},
+		{name: "ReloadDetailed", reload: func(c *client) error {
+			_, err := c.ReloadDetailed(context.Background())
+			return err
+		}, want: TriggerReasonManual},
+		{name: "ReloadAsync", reload: func(c *client) error { return (<-c.ReloadAsync()).Err }, want: TriggerReasonManual},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, _ := newTestClient()
+			expectLoad(mockHTTP, testLoad{version: "4"})
+
+			assert.NoError(t, tt.reload(c))
+
+			assert.Equal(t, []TriggerReason{tt.want}, postedTriggerReasons(t, mockHTTP, c.config().GetUrlApiAgents()))
+		})
+	}
+}
+
+func TestClient_TriggerReason_Start(t *testing.T) {
+	tests := []struct {
+		name    string
+		startup bool
+		trigger bool
+		want    TriggerReason
+	}{
+		{name: "startup", startup: true, want: TriggerReasonStartup},
+		{name: "timer", want: TriggerReasonTimer},
+		{name: "TriggerReload", trigger: true, want: TriggerReasonTrigger},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, fakeClock := newTestClient()
+			c.config().SkipReloadOnStart = !tt.startup
+			expectLoad(mockHTTP, testLoad{version: "4"})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.Start(ctx)
+				close(done)
+			}()
+			if !tt.startup {
+				fakeClock.BlockUntil(1)
+				if tt.trigger {
+					c.TriggerReload()
+				} else {
+					fakeClock.Advance(5 * time.Minute)
+				}
+			}
+			assert.Eventually(t, func() bool { return c.GetStateVersion() == 4 }, time.Second, 10*time.Millisecond)
+			cancel()
+			<-done
+
+			assert.Equal(t, []TriggerReason{tt.want}, postedTriggerReasons(t, mockHTTP, c.config().GetUrlApiAgents()))
+		})
+	}
+}
+
+func TestClient_TriggerReason_Lifecycle(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ReportLifecycle = true
+	expectLoad(mockHTTP, testLoad{version: "4"})
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.Close())
+
+	assert.Equal(t, []TriggerReason{TriggerReasonStartup, ""}, postedTriggerReasons(t, mockHTTP, c.config().GetUrlApiAgents()))
+}
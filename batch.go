@@ -0,0 +1,145 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/jonboulle/clockwork"
+)
+
+// statusBatcher coalesces the agent statuses of a MultiClient's projects over
+// Config.AgentStatusBatchWindow and posts them in a single request. Only the
+// latest status of each project is kept.
+type statusBatcher struct {
+	window time.Duration
+	clock  clockwork.Clock
+
+	mu      sync.Mutex
+	pending map[*client]pendingStatus
+	order   []*client
+	timer   clockwork.Timer
+	// unsupported is set once the manager answered the batch endpoint with 404
+	// or 405: statuses are then posted one by one.
+	unsupported bool
+}
+
+// pendingStatus is a status waiting for the batch, with the reason of the reload
+// that produced it.
+type pendingStatus struct {
+	agent  types.Agent
+	reason TriggerReason
+}
+
+type agentBatchEntry struct {
+	NamespaceCode string `json:"namespace_code"`
+	ProjectCode   string `json:"project_code"`
+	agentStatusPayload
+}
+
+func newStatusBatcher(window time.Duration, clock clockwork.Clock) *statusBatcher {
+	return &statusBatcher{window: window, clock: clock, pending: make(map[*client]pendingStatus)}
+}
+
+func (b *statusBatcher) enqueue(ctx context.Context, c *client, agent types.Agent) error {
+	if err := types.ValidateAgent(agent); err != nil {
+		return err
+	}
+	if err := validateAgentVersion(c.config().AgentVersion); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if b.unsupported {
+		b.mu.Unlock()
+		return c.sendAgentStatus(ctx, agent)
+	}
+	if _, found := b.pending[c]; !found {
+		b.order = append(b.order, c)
+	}
+	b.pending[c] = pendingStatus{agent: agent, reason: triggerReason(ctx)}
+	if b.timer == nil {
+		b.timer = b.clock.AfterFunc(b.window, func() {
+			if err := b.flush(context.Background()); err != nil {
+				c.logger().Warn("flushing agent statuses", "error", err)
+			}
+		})
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// flush posts the pending statuses now.
+func (b *statusBatcher) flush(ctx context.Context) error {
+	b.mu.Lock()
+	order, pending := b.order, b.pending
+	b.order, b.pending = nil, make(map[*client]pendingStatus)
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+	if len(order) == 0 {
+		return nil
+	}
+
+	err := b.send(ctx, order, pending)
+	if !isEndpointMissing(err) {
+		if err != nil {
+			for _, c := range order {
+				c.forgetPosted(pending[c].agent)
+			}
+		}
+		return err
+	}
+	b.mu.Lock()
+	b.unsupported = true
+	b.mu.Unlock()
+	var errs []error
+	for _, c := range order {
+		if err := c.sendAgentStatus(withTriggerReason(ctx, pending[c].reason), pending[c].agent); err != nil {
+			c.forgetPosted(pending[c].agent)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *statusBatcher) send(ctx context.Context, order []*client, pending map[*client]pendingStatus) error {
+	entries := make([]agentBatchEntry, 0, len(order))
+	for _, c := range order {
+		for _, typ := range c.config().GetAgentTypes() {
+			agent := pending[c].agent
+			agent.Type = typ
+			entries = append(entries, agentBatchEntry{
+				NamespaceCode:      c.config().NamespaceCode,
+				ProjectCode:        c.config().ProjectCode,
+				agentStatusPayload: agentStatusPayload{Agent: agent, AgentVersion: c.config().AgentVersion, TriggerReason: pending[c].reason},
+			})
+		}
+	}
+	jsonEntries, errMarshal := json.Marshal(entries)
+	if errMarshal != nil {
+		return errMarshal
+	}
+
+	// Every project shares the same manager and HTTP configuration.
+	sender := order[0]
+	url := sender.config().GetUrlApiAgentsBatch()
+	req, err := sender.newRequest(ctx, EndpointAgentStatusBatch, http.MethodPost, url, bytes.NewReader(jsonEntries))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, errReq := sender.do(EndpointAgentStatusBatch, req)
+	if errReq != nil {
+		return errReq
+	}
+
+	return sender.handleResponse(resp, url, nil)
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// postNotifyingHTTPClient signals every POST once the inner client answered it.
+type postNotifyingHTTPClient struct {
+	*mockHTTPClient
+	posted chan struct{}
+}
+
+func (n *postNotifyingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := n.mockHTTPClient.Do(req)
+	if req.Method == http.MethodPost {
+		n.posted <- struct{}{}
+	}
+	return resp, err
+}
+
+func decodeBatch(t *testing.T, req *http.Request) []agentBatchEntry {
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(t, err)
+	var entries []agentBatchEntry
+	assert.NoError(t, json.Unmarshal(body, &entries))
+	return entries
+}
+
+func TestMultiClient_BatchedStatuses(t *testing.T) {
+	notifying := &postNotifyingHTTPClient{posted: make(chan struct{}, 1)}
+	m, mockHTTP, fakeClock := newTestMultiClient(t, func(cfg *Config) {
+		cfg.AgentStatusBatchWindow = time.Second
+		notifying.mockHTTPClient = cfg.Http.Client.(*mockHTTPClient)
+		cfg.Http.Client = notifying
+	})
+
+	expectLoad(mockHTTP, testLoad{version: "3", noAgent: true})
+	expectLoad(mockHTTP, testLoad{version: "8", noAgent: true})
+	expectLoad(mockHTTP, testLoad{version: "9", noAgent: true})
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, m.Init())
+	assert.NoError(t, m.Project("ns", "blog").Reload())
+	assert.Len(t, mockHTTP.calls, 12)
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(time.Second)
+	select {
+	case <-notifying.posted:
+	case <-time.After(time.Second):
+		t.Fatal("statuses were not flushed")
+	}
+
+	assert.Len(t, mockHTTP.calls, 13)
+	batch := mockHTTP.calls[12]
+	assert.Equal(t, "http://localhost:8080/api/agents/batch", batch.URL.String())
+	entries := decodeBatch(t, batch)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "shop", entries[0].ProjectCode)
+		assert.Equal(t, 3, entries[0].Version)
+		assert.Equal(t, TriggerReasonStartup, entries[0].TriggerReason)
+		assert.Equal(t, "ns", entries[1].NamespaceCode)
+		assert.Equal(t, "blog", entries[1].ProjectCode)
+		assert.Equal(t, 9, entries[1].Version)
+		assert.Equal(t, types.AgentStatusSuccess, entries[1].Status)
+		assert.Equal(t, TriggerReasonManual, entries[1].TriggerReason)
+	}
+}
+
+func TestMultiClient_Flush_FallsBackWithoutBatchEndpoint(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t, func(cfg *Config) {
+		cfg.AgentStatusBatchWindow = time.Minute
+	})
+
+	expectLoad(mockHTTP, testLoad{version: "3", noAgent: true})
+	expectLoad(mockHTTP, testLoad{version: "8", noAgent: true})
+	mockHTTP.expect(makeErrorResponse(http.StatusNotFound), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	assert.NoError(t, m.Init())
+	assert.NoError(t, m.Flush(context.Background()))
+
+	assert.Len(t, mockHTTP.calls, 11)
+	assert.Equal(t, "http://localhost:8080/api/agents/batch", mockHTTP.calls[8].URL.String())
+	assert.Equal(t, "http://localhost:8080/api/namespace/ns/project/shop/agents", mockHTTP.calls[9].URL.String())
+	assert.Equal(t, "http://localhost:8080/api/namespace/ns/project/blog/agents", mockHTTP.calls[10].URL.String())
+	assert.Equal(t, []TriggerReason{TriggerReasonStartup}, postedTriggerReasons(t, mockHTTP, mockHTTP.calls[10].URL.String()))
+
+	assert.NoError(t, m.Project("ns", "shop").Reload())
+	assert.Len(t, mockHTTP.calls, 16)
+	assert.Equal(t, http.MethodPost, mockHTTP.calls[15].Method)
+	assert.Equal(t, "http://localhost:8080/api/namespace/ns/project/shop/agents", mockHTTP.calls[15].URL.String())
+}
+
+func TestMultiClient_BatchedStatuses_ExtraAgentTypes(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t, func(cfg *Config) {
+		cfg.AgentStatusBatchWindow = time.Minute
+		cfg.ExtraAgentTypes = []types.AgentType{types.AgentTypeTraefik}
+	})
+
+	expectLoad(mockHTTP, testLoad{version: "3", noAgent: true})
+	expectLoad(mockHTTP, testLoad{version: "8", noAgent: true})
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, m.Init())
+	assert.NoError(t, m.Flush(context.Background()))
+
+	entries := decodeBatch(t, mockHTTP.calls[len(mockHTTP.calls)-1])
+	if assert.Len(t, entries, 4) {
+		assert.Equal(t, "shop", entries[0].ProjectCode)
+		assert.Equal(t, types.AgentTypeDefault, entries[0].Type)
+		assert.Equal(t, "shop", entries[1].ProjectCode)
+		assert.Equal(t, types.AgentTypeTraefik, entries[1].Type)
+		assert.Equal(t, "blog", entries[3].ProjectCode)
+		assert.Equal(t, types.AgentTypeTraefik, entries[3].Type)
+	}
+}
+
+func TestMultiClient_Flush_WithoutBatching(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t)
+
+	assert.NoError(t, m.Flush(context.Background()))
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestMultiClient_Flush_FailedBatchIsPostedAgain(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t, func(cfg *Config) {
+		cfg.AgentStatusBatchWindow = time.Minute
+	})
+	shop := m.Project("ns", "shop")
+	expectFailedLoad := func() {
+		mockHTTP.expect(makeVersionResponse("4"), nil)
+		mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
+	}
+
+	expectLoad(mockHTTP, testLoad{version: "3", noAgent: true})
+	expectLoad(mockHTTP, testLoad{version: "8", noAgent: true})
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, m.Init())
+	assert.NoError(t, m.Flush(context.Background()))
+	expectFailedLoad()
+	assert.Error(t, shop.Reload())
+	mockHTTP.expect(makeErrorResponse(http.StatusServiceUnavailable), nil)
+	assert.Error(t, m.Flush(context.Background()))
+
+	expectFailedLoad()
+	assert.Error(t, shop.Reload())
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, m.Flush(context.Background()))
+
+	batch := mockHTTP.calls[len(mockHTTP.calls)-1]
+	assert.Equal(t, "http://localhost:8080/api/agents/batch", batch.URL.String())
+	entries := decodeBatch(t, batch)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "shop", entries[0].ProjectCode)
+		assert.Equal(t, types.AgentStatusError, entries[0].Status)
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops reloads for a cooldown after too many consecutive failures,
+// then lets a single probe through. It is guarded by the client reloadMu.
+type circuitBreaker struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+}
+
+func (b *circuitBreaker) allow(now time.Time, cooldown time.Duration) error {
+	if b.state == CircuitOpen {
+		if now.Sub(b.openedAt) < cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+	}
+	return nil
+}
+
+// record counts the retryable failures only: a manager answering 4xx is up, and
+// a canceled reload tells nothing.
+func (b *circuitBreaker) record(err error, now time.Time, threshold int) {
+	switch ClassifyError(err) {
+	case ErrorClassCanceled:
+		return
+	case ErrorClassNone, ErrorClassFatal:
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= threshold {
+		b.state = CircuitOpen
+		b.openedAt = now
+	}
+}
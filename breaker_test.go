@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitState_String(t *testing.T) {
+	assert.Equal(t, "closed", CircuitClosed.String())
+	assert.Equal(t, "open", CircuitOpen.String())
+	assert.Equal(t, "half-open", CircuitHalfOpen.String())
+}
+
+func TestClient_Reload_CircuitBreaker(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().CircuitBreakerThreshold = 2
+	c.config().CircuitBreakerCooldown = time.Minute
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	// closed: failures go through until the threshold is reached
+	mockHTTP.expect(nil, errors.New("network error"))
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.EqualError(t, c.Reload(), "network error")
+	assert.Equal(t, CircuitClosed, c.breaker.state)
+	assert.EqualError(t, c.Reload(), "network error")
+	assert.Equal(t, CircuitOpen, c.breaker.state)
+
+	// open: fails fast without any request
+	fakeClock.Advance(30 * time.Second)
+	assert.ErrorIs(t, c.Reload(), ErrCircuitOpen)
+	assert.Len(t, mockHTTP.calls, 2)
+
+	// half-open: a failing probe re-opens the circuit
+	fakeClock.Advance(30 * time.Second)
+	mockHTTP.expect(nil, errors.New("still down"))
+	assert.EqualError(t, c.Reload(), "still down")
+	assert.Equal(t, CircuitOpen, c.breaker.state)
+	assert.ErrorIs(t, c.Reload(), ErrCircuitOpen)
+	assert.Len(t, mockHTTP.calls, 3)
+
+	// half-open: a successful probe closes the circuit
+	fakeClock.Advance(time.Minute)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, CircuitClosed, c.breaker.state)
+	assert.Equal(t, 0, c.breaker.failures)
+}
+
+func TestClient_Reload_CircuitBreakerHalfOpenState(t *testing.T) {
+	c, _, fakeClock := newTestClient()
+	c.config().CircuitBreakerThreshold = 1
+	c.config().CircuitBreakerCooldown = time.Minute
+	c.breaker = circuitBreaker{state: CircuitOpen, failures: 1, openedAt: fakeClock.Now()}
+
+	fakeClock.Advance(time.Minute)
+
+	assert.NoError(t, c.breaker.allow(fakeClock.Now(), c.config().CircuitBreakerCooldown))
+	assert.Equal(t, CircuitHalfOpen, c.breaker.state)
+}
+
+func TestClient_Reload_CircuitBreakerDisabled(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	for i := 0; i < 5; i++ {
+		mockHTTP.expect(nil, errors.New("network error"))
+		assert.EqualError(t, c.Reload(), "network error")
+	}
+
+	assert.Len(t, mockHTTP.calls, 5)
+	assert.Equal(t, CircuitClosed, c.breaker.state)
+}
+
+func TestClient_Reload_CircuitBreakerIgnoresFatalErrors(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().CircuitBreakerThreshold = 1
+	c.config().CircuitBreakerCooldown = time.Minute
+
+	mockHTTP.expect(makeErrorResponse(http.StatusUnauthorized), nil)
+	assert.Error(t, c.Reload())
+	assert.Equal(t, CircuitClosed, c.breaker.state)
+
+	mockHTTP.expect(nil, context.Canceled)
+	assert.Error(t, c.Reload())
+	assert.Equal(t, CircuitClosed, c.breaker.state)
+	assert.Equal(t, 0, c.breaker.failures)
+
+	mockHTTP.expect(makeErrorResponse(http.StatusServiceUnavailable), nil)
+	assert.Error(t, c.Reload())
+	assert.Equal(t, CircuitOpen, c.breaker.state)
+}
@@ -4,201 +4,1390 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"regexp"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/flectolab/flecto-manager/common/types"
 	"github.com/jonboulle/clockwork"
 )
 
+var validAgentVersionRegex = regexp.MustCompile(`^[a-zA-Z0-9._+-]{1,100}$`)
+
+var (
+	ErrEmptyVersion = errors.New("empty version")
+	// ErrInvalidVersion matches the errors of Config.VersionParser, returned as is.
+	ErrInvalidVersion       = errors.New("invalid version")
+	ErrReloadBudgetExceeded = errors.New("reload budget exceeded")
+	// ErrProjectNotFound is returned when the version endpoint answers 404, e.g.
+	// once the project was deleted. The APIError is wrapped along.
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrStateVerification wraps the error of Config.VerifyState.
+	ErrStateVerification = errors.New("state verification failed")
+	// ErrRedirectNotFound and ErrPageNotFound are returned by FetchRedirect and
+	// FetchPage when the project has no such rule.
+	ErrRedirectNotFound = errors.New("redirect not found")
+	ErrPageNotFound     = errors.New("page not found")
+
+	// errStateUnchanged reports that Config.DetectByContentHash found nothing new to install.
+	errStateUnchanged = errors.New("state content unchanged")
+)
+
 type Client interface {
 	Init() error
+	Warmup(ctx context.Context) error
+	WaitReady(ctx context.Context) error
+	IsInitialized() bool
 	GetStateVersion() int
 	RedirectMatch(host, uri string) (*types.Redirect, string)
+	RedirectMatchStatus(host, uri string) (string, int, bool)
+	RedirectMatchDebug(host, uri string) RedirectMatchResult
+	RedirectMatchFull(host, fullURI string) (*types.Redirect, string)
+	ResolveRedirectChain(host, uri string) (finalTarget string, hops int, loopDetected bool)
 	PageMatch(host, uri string) *types.Page
+	PageMatchMethods(host, uri string) (*types.Page, []string)
+	PageMatchMethod(host, uri, method string) *types.Page
+	PageMatchDebug(host, uri string) PageMatchResult
+	PageMatchAccept(host, uri, accept string) *types.Page
+	PageMatchResponse(host, uri string) (*PageResponse, bool)
 	Reload() error
+	ReloadDetailed(ctx context.Context) (ReloadResult, error)
+	ReloadAsync() <-chan ReloadResult
+	LoadFromData(version int, redirects []types.Redirect, pages []types.Page) error
+	LoadFromReader(r io.Reader) error
+	FetchRedirects(ctx context.Context) ([]types.Redirect, error)
+	FetchPages(ctx context.Context) ([]types.Page, error)
+	FetchRedirect(ctx context.Context, source string) (*types.Redirect, error)
+	FetchPage(ctx context.Context, path string) (*types.Page, error)
+	TriggerReload()
 	Start(ctx context.Context)
+	Pause()
+	Resume()
+	IsPaused() bool
+	Close() error
+	Reconfigure(cfg *Config) error
+	Stats() ReloadStats
+	StateExport(maxContent int) StateExport
+	ListRedirects() []types.Redirect
+	ListPages() []types.Page
+	StaleSince() time.Time
+	Healthy() bool
+	ShouldServeMaintenance() bool
+	MaintenanceResponse() (*PageResponse, bool)
 }
 
-func New(cfg *Config) Client {
-	c := &client{cfg: cfg, httpClient: cfg.Http.Client, clock: clockwork.NewRealClock()}
-	c.State.Store(&State{RedirectMatcher: types.NewRedirectTreeMatcher(), PageMatcher: types.NewPageTreeMatcher()})
+// Option customizes a client built by New.
+type Option func(*client)
+
+// WithClock replaces the real clock driving Start, backoff and time budgets.
+// It is meant for tests and simulations, e.g. with clockwork.NewFakeClock.
+func WithClock(clock clockwork.Clock) Option {
+	return func(c *client) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+func New(cfg *Config, opts ...Option) Client {
+	c := &client{clock: clockwork.NewRealClock(), trigger: make(chan struct{}, 1), requestSlots: newRequestSlots(cfg.MaxConcurrentRequests)}
+	c.cfg.Store(cfg)
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.State.Store(emptyState())
 	return c
 }
 
+func emptyState() *State {
+	return &State{RedirectMatcher: types.NewRedirectTreeMatcher(), PageMatcher: types.NewPageTreeMatcher()}
+}
+
 type State struct {
-	ProjectVersion  int
+	ProjectVersion int
+	// VersionKey is the ParsedVersion.Key of ProjectVersion.
+	VersionKey      string
 	RedirectMatcher types.RedirectTreeMatcher
 	PageMatcher     types.PageTreeMatcher
+	// Redirects and Pages are the rules loaded in the matchers.
+	Redirects []types.Redirect
+	Pages     []types.Page
+	// ContentHash is the ContentHash of Redirects and Pages.
+	ContentHash string
+	// foldedKeys is set when the matchers were built with Config.CaseInsensitivePaths,
+	// decodedKeys with Config.DecodePathForMatch.
+	foldedKeys  bool
+	decodedKeys bool
 }
 
 type client struct {
-	cfg        *Config
-	httpClient HTTPClient
-	State      atomic.Value
-	clock      clockwork.Clock
-	reloadMu   sync.Mutex
+	// cfg is swapped by Reconfigure and read through config().
+	cfg      atomic.Pointer[Config]
+	State    atomic.Value
+	clock    clockwork.Clock
+	reloadMu sync.Mutex
+	trigger  chan struct{}
+
+	// lastReport, lastReportAt, lastPosted, agentStatus, versionTolerations,
+	// breaker and progressAt are guarded by reloadMu.
+	lastReport   string
+	lastReportAt time.Time
+	// lastPosted identifies the last agent status posted, hits excluded.
+	lastPosted string
+	// agentStatus is the status of the last load, empty before the first one.
+	agentStatus types.AgentStatus
+	// versionTolerations counts the consecutive invalid versions ignored.
+	versionTolerations int
+	breaker            circuitBreaker
+	// progressAt is when the running reload last reported progress, zero outside reloads.
+	progressAt time.Time
+
+	// stateUnsupported is set once the manager answered the combined state
+	// endpoint with 404 or 405. Guarded by reloadMu.
+	stateUnsupported bool
+
+	stats reloadCounters
+	// staleSince holds the UnixNano of the first failed reload, zero when fresh.
+	staleSince atomic.Int64
+	paused     atomic.Bool
+	// followUp is set when a triggered reload was skipped by an in-flight one.
+	followUp  atomic.Bool
+	closed    chan struct{}
+	closeInit sync.Once
+	closeOnce sync.Once
+	// ready is closed once a state has been loaded.
+	ready     chan struct{}
+	readyInit sync.Once
+	readyOnce sync.Once
+	tokenFile tokenFile
+	// statusBatch is shared by the projects of a MultiClient batching their statuses.
+	statusBatch *statusBatcher
+	// requestSlots bounds the requests in flight, nil means no limit. It is shared
+	// by the projects of a MultiClient.
+	requestSlots chan struct{}
 }
 
 func (c *client) Init() error {
-	if !c.cfg.AgentType.IsValid() {
-		return fmt.Errorf("invalid agent type: %s", c.cfg.AgentType)
+	if err := validateAgentTypes(c.config()); err != nil {
+		return err
 	}
 
-	err := c.Reload()
-	if err != nil {
+	if err := validateAgentVersion(c.config().AgentVersion); err != nil {
 		return err
 	}
 
-	return nil
+	if err := validateAcceptFormat(c.config().Http.AcceptFormat); err != nil {
+		return err
+	}
+
+	if err := validateIntervalCheck(c.config()); err != nil {
+		return err
+	}
+	if err := validateMaxManagerVersion(c.config()); err != nil {
+		return err
+	}
+	if interval := c.config().GetIntervalCheck(); interval != c.config().IntervalCheck {
+		c.logger().Warn("clamping interval check", "interval", c.config().IntervalCheck, "clamped", interval)
+	}
+
+	if !c.config().SkipCompatibilityCheck {
+		if err := c.checkCompatibility(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.ReloadDetailed(withTriggerReason(context.Background(), TriggerReasonStartup))
+	if err != nil {
+		if c.config().StateCacheFile == "" {
+			return err
+		}
+		if errCache := c.loadStateCache(); errCache != nil {
+			c.logger().Warn("failed to load state cache", "path", c.config().StateCacheFile, "error", errCache)
+			return err
+		}
+		c.logger().Warn("failed to load state, using state cache", "path", c.config().StateCacheFile, "error", err)
+		return nil
+	}
+
+	return c.reportLifecycle(context.Background(), agentEventReady)
+}
+
+// Warmup loads the state like Init, without sending any agent status or hit,
+// for callers driving reloads themselves.
+func (c *client) Warmup(ctx context.Context) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	err := c.loadState(ctx)
+	c.markHealth(err)
+	return err
+}
+
+func (c *client) config() *Config {
+	return c.cfg.Load()
+}
+
+func (c *client) logger() *slog.Logger {
+	if c.config().Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	if c.config().LogFields != nil {
+		return slog.New(contextFieldsHandler{Handler: c.config().Logger.Handler(), fields: c.config().LogFields})
+	}
+	return c.config().Logger
+}
+
+// contextFieldsHandler adds to every record the fields Config.LogFields
+// extracts from the context it is logged with.
+type contextFieldsHandler struct {
+	slog.Handler
+	fields func(ctx context.Context) []any
+}
+
+func (h contextFieldsHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields := h.fields(ctx); len(fields) > 0 {
+		record = record.Clone()
+		record.Add(fields...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextFieldsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextFieldsHandler{Handler: h.Handler.WithAttrs(attrs), fields: h.fields}
 }
 
+func (h contextFieldsHandler) WithGroup(name string) slog.Handler {
+	return contextFieldsHandler{Handler: h.Handler.WithGroup(name), fields: h.fields}
+}
+
+// load returns the current state, or an empty one when none was stored, e.g.
+// on a client not built by New.
 func (c *client) load() *State {
-	return c.State.Load().(*State)
+	state, _ := c.State.Load().(*State)
+	if state == nil {
+		return emptyState()
+	}
+	return state
 }
 
 func (c *client) RedirectMatch(host, uri string) (*types.Redirect, string) {
-	return c.load().RedirectMatcher.Match(host, uri)
+	cfg := c.config()
+	if maintenance := cfg.MaintenanceRedirect; maintenance != nil && !c.Healthy() {
+		maintenance = withDefaultStatus(maintenance, cfg.GetDefaultRedirectStatus())
+		return maintenance, maintenance.Target
+	}
+	if cfg.NormalizeHost {
+		host = normalizeHost(host)
+	}
+	state := c.load()
+	if redirect, target := c.matchRedirect(state, host, uri); redirect != nil {
+		return redirect, target
+	}
+	return c.matchRedirectSlash(state, host, uri)
+}
+
+// RedirectMatchStatus returns the resolved target and the HTTP status code to
+// answer with, or ok=false when no redirect matches.
+func (c *client) RedirectMatchStatus(host, uri string) (target string, status int, ok bool) {
+	redirect, target := c.RedirectMatch(host, uri)
+	if redirect == nil {
+		return "", 0, false
+	}
+	return target, redirect.HTTPCode(), true
 }
+
+// withDefaultStatus returns redirect, or a copy of it with status when it has none.
+func withDefaultStatus(redirect *types.Redirect, status types.RedirectStatus) *types.Redirect {
+	if redirect.Status != "" {
+		return redirect
+	}
+	withStatus := *redirect
+	withStatus.Status = status
+	return &withStatus
+}
+
 func (c *client) PageMatch(host, uri string) *types.Page {
-	return c.load().PageMatcher.Match(host, uri)
+	cfg := c.config()
+	if cfg.NormalizeHost {
+		host = normalizeHost(host)
+	}
+	state := c.load()
+	if page := state.PageMatcher.Match(host, c.foldPath(uri)); page != nil {
+		return page
+	}
+	return c.matchPageSlash(state, host, uri)
+}
+
+// PageMatchMethods returns the matched page along with the HTTP methods it may
+// answer, as configured by Config.PageMethods. A nil set means any method.
+func (c *client) PageMatchMethods(host, uri string) (*types.Page, []string) {
+	page := c.PageMatch(host, uri)
+	if page == nil {
+		return nil, nil
+	}
+	return page, c.config().PageMethods
+}
+
+// PageMatchMethod returns the matched page if it answers to method. HEAD is
+// answered wherever GET is, unless Config.DisableImplicitHead is set; the caller
+// writes the headers and omits the body.
+func (c *client) PageMatchMethod(host, uri, method string) *types.Page {
+	page, methods := c.PageMatchMethods(host, uri)
+	if page == nil {
+		return nil
+	}
+	if len(methods) == 0 {
+		return page
+	}
+	implicitHead := !c.config().DisableImplicitHead && strings.EqualFold(method, http.MethodHead)
+	for _, m := range methods {
+		if strings.EqualFold(m, method) || (implicitHead && strings.EqualFold(m, http.MethodGet)) {
+			return page
+		}
+	}
+	return nil
 }
 
 func (c *client) GetStateVersion() int {
 	return c.load().ProjectVersion
 }
 func (c *client) Reload() error {
+	_, err := c.ReloadDetailed(context.Background())
+	return err
+}
+
+// ReloadResult describes the outcome of a reload. Versions and counts are read
+// from the state installed before and after the reload.
+type ReloadResult struct {
+	// Skipped is true when another reload was already running.
+	Skipped        bool
+	VersionChanged bool
+	OldVersion     int
+	NewVersion     int
+	Duration       time.Duration
+	RedirectCount  int
+	PageCount      int
+	// Err is the error of the reload, also returned by ReloadDetailed.
+	Err error
+}
+
+// ReloadDetailed reloads like Reload and reports what happened.
+func (c *client) ReloadDetailed(ctx context.Context) (ReloadResult, error) {
+	if triggerReason(ctx) == "" {
+		ctx = withTriggerReason(ctx, TriggerReasonManual)
+	}
 	if !c.reloadMu.TryLock() {
-		return nil
+		return c.skipReload(), nil
 	}
-	defer c.reloadMu.Unlock()
-	version, err := c.getProjectVersion()
+	result := c.reloadAndUnlock(ctx)
+	return result, result.Err
+}
+
+// ReloadAsync runs ReloadDetailed in a goroutine and delivers its result on the
+// returned channel, which is then closed. When another reload is running, the
+// channel is returned already closed, holding a skipped result.
+func (c *client) ReloadAsync() <-chan ReloadResult {
+	results := make(chan ReloadResult, 1)
+	if !c.reloadMu.TryLock() {
+		results <- c.skipReload()
+		close(results)
+		return results
+	}
+	go func() {
+		defer close(results)
+		results <- c.reloadAndUnlock(withTriggerReason(context.Background(), TriggerReasonManual))
+	}()
+	return results
+}
+
+// reloadAndUnlock reloads for a caller that acquired reloadMu, and releases it.
+func (c *client) reloadAndUnlock(ctx context.Context) ReloadResult {
+	c.followUp.Store(false)
+	defer func() {
+		c.reloadMu.Unlock()
+		if c.followUp.Swap(false) {
+			c.TriggerReload()
+		}
+	}()
+
+	start := c.clock.Now()
+	before := c.load()
+	c.stats.attempted.Add(1)
+	err := c.reloadWithBreaker(ctx)
 	if err != nil {
+		c.stats.failed.Add(1)
+	} else {
+		c.stats.succeeded.Add(1)
+	}
+	c.markHealth(err)
+	after := c.load()
+	return ReloadResult{
+		VersionChanged: after.versionKey() != before.versionKey(),
+		OldVersion:     before.ProjectVersion,
+		NewVersion:     after.ProjectVersion,
+		Duration:       c.clock.Since(start),
+		RedirectCount:  len(after.Redirects),
+		PageCount:      len(after.Pages),
+		Err:            err,
+	}
+}
+
+func (c *client) reloadWithBreaker(ctx context.Context) error {
+	if c.config().CircuitBreakerThreshold <= 0 {
+		return c.reloadLocked(ctx)
+	}
+	if err := c.breaker.allow(c.clock.Now(), c.config().CircuitBreakerCooldown); err != nil {
 		return err
 	}
-	agent := types.Agent{Name: c.cfg.AgentName, Type: c.cfg.AgentType, Version: version}
-	if version != c.load().ProjectVersion {
+	err := c.reloadLocked(ctx)
+	c.breaker.record(err, c.clock.Now(), c.config().CircuitBreakerThreshold)
+	return err
+}
+
+func (c *client) reloadLocked(ctx context.Context) error {
+	version, err := c.fetchVersion(ctx)
+	if err != nil {
+		if c.tolerateVersionError(ctx, err) {
+			return nil
+		}
+		if errors.Is(err, ErrProjectNotFound) && c.config().ClearStateOnProjectMissing {
+			c.State.Store(emptyState())
+		}
+		return err
+	}
+	c.versionTolerations = 0
+	agent := types.Agent{Name: c.config().AgentName, Type: c.config().AgentType, Version: version.Number}
+	versionChanged := version.Key != c.load().versionKey()
+	if versionChanged || c.config().DetectByContentHash {
+		if versionChanged {
+			c.stats.versionChanges.Add(1)
+		}
 		now := c.clock.Now()
-		err = c.loadState()
+		c.progressAt = now
+		err = c.loadState(ctx)
+		c.progressAt = time.Time{}
 		duration := c.clock.Now().Sub(now)
 		agent.LoadDuration = types.NewDuration(duration)
+		if errors.Is(err, errStateUnchanged) {
+			return c.reportHit(ctx, agent)
+		}
+		event := agentEventLoaded
+		if err != nil {
+			event = agentEventLoadFailed
+		}
+		status, errStatus := c.transition(event)
+		if errStatus != nil {
+			return errors.Join(err, errStatus)
+		}
+		agent.Status = status
 		if err != nil {
-			agent.Status = types.AgentStatusError
 			agent.Error = err.Error()
-			_ = c.sendAgentStatus(agent)
+			_ = c.reportChangedStatus(ctx, agent)
 			return err
 		}
-		agent.Status = types.AgentStatusSuccess
-		return c.sendAgentStatus(agent)
+		return c.reportChangedStatus(ctx, agent)
+	}
+	return c.reportHit(ctx, agent)
+}
+
+// reportChangedStatus posts the agent status unless the last post already
+// registered the agent with the same version, status and error, e.g. when
+// retrying a version that failed to load. A hit is sent instead.
+func (c *client) reportChangedStatus(ctx context.Context, agent types.Agent) error {
+	if c.postedFingerprint(agent) == c.lastPosted {
+		return c.reportHit(ctx, agent)
+	}
+	return c.reportStatus(ctx, agent)
+}
+
+func (c *client) agentFingerprint(agent types.Agent) string {
+	return fmt.Sprintf("%s|%s|%d|%s", agent.Name, agent.Type, agent.Version, c.config().AgentVersion)
+}
+
+func (c *client) markReported(agent types.Agent) {
+	c.lastReport = c.agentFingerprint(agent)
+	c.lastReportAt = c.clock.Now()
+}
+
+// postStatus sends the agent status, or queues it when statuses are batched.
+func (c *client) postStatus(ctx context.Context, agent types.Agent) error {
+	if c.statusBatch != nil {
+		return c.statusBatch.enqueue(ctx, c, agent)
+	}
+	return c.sendAgentStatus(ctx, agent)
+}
+
+func (c *client) reportStatus(ctx context.Context, agent types.Agent) error {
+	if err := c.postStatus(ctx, agent); err != nil {
+		return err
+	}
+	c.markReported(agent)
+	c.lastPosted = c.postedFingerprint(agent)
+	return nil
+}
+
+// forgetPosted undoes the dedupe of a queued status whose batch failed, so that
+// the next reload posts it again, unless a newer status was queued meanwhile.
+func (c *client) forgetPosted(agent types.Agent) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	if c.lastPosted == c.postedFingerprint(agent) {
+		c.lastPosted = ""
+	}
+}
+
+func (c *client) postedFingerprint(agent types.Agent) string {
+	return fmt.Sprintf("%s|%s|%s", c.agentFingerprint(agent), agent.Status, agent.Error)
+}
+
+// reportHit sends a liveness hit, unless Config.SuppressUnchangedHits is set and
+// the same agent was already reported within Config.HitInterval.
+func (c *client) reportHit(ctx context.Context, agent types.Agent) error {
+	if c.config().SuppressUnchangedHits && c.lastReport == c.agentFingerprint(agent) &&
+		(c.config().HitInterval <= 0 || c.clock.Since(c.lastReportAt) < c.config().HitInterval) {
+		return nil
+	}
+	if err := c.sendAgentHit(ctx, agent.Name); err != nil {
+		return err
+	}
+	c.stats.hitsSent.Add(1)
+	c.markReported(agent)
+	return nil
+}
+
+// TriggerReload asks the Start loop to reload without waiting for the next tick.
+// It never blocks: triggers sent while one is already pending are coalesced.
+func (c *client) TriggerReload() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// debounceTriggers waits Config.ReloadDebounce after a trigger, so that the
+// triggers sent meanwhile collapse into one reload. It returns false when the
+// loop must stop.
+func (c *client) debounceTriggers(ctx context.Context, closed chan struct{}) bool {
+	if c.config().ReloadDebounce <= 0 {
+		return true
+	}
+	timer := c.clock.NewTimer(c.config().ReloadDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.Chan():
+			return true
+		case <-c.trigger:
+		case <-closed:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// drainTriggers drops the pending trigger, served by the reload about to start.
+func (c *client) drainTriggers() {
+	select {
+	case <-c.trigger:
+	default:
 	}
-	return c.sendAgentHit(agent.Name)
+}
+
+// Pause makes Start skip reloads until Resume is called; the loop keeps ticking.
+func (c *client) Pause() {
+	c.paused.Store(true)
+}
+
+func (c *client) Resume() {
+	c.paused.Store(false)
+}
+
+func (c *client) IsPaused() bool {
+	return c.paused.Load()
 }
 
 func (c *client) Start(ctx context.Context) {
-	ticker := c.clock.NewTimer(c.cfg.IntervalCheck)
+	first := c.config().GetIntervalCheck()
+	startup := c.reloadOnStart()
+	if startup {
+		first = 0
+	}
+	ticker := c.clock.NewTimer(first)
 	defer ticker.Stop()
+	if !startup && first == 0 {
+		c.resetTicker(ticker, 0)
+	}
+	var backoff reloadBackoff
+	closed := c.closedChan()
 	for {
+		triggered := false
 		select {
+		case <-closed:
+			return
 		case <-ticker.Chan():
-			_ = c.Reload()
+		case <-c.trigger:
+			// a Retry-After from the manager also holds back triggered reloads
+			if c.clock.Now().Before(backoff.retryAt) {
+				continue
+			}
+			if !c.debounceTriggers(ctx, closed) {
+				return
+			}
+			triggered = true
 		case <-ctx.Done():
 			return
 		}
+		if c.IsPaused() {
+			c.resetTicker(ticker, c.config().GetIntervalCheck())
+			continue
+		}
+		// a pending trigger is served by this reload, and a trigger skipped by an
+		// in-flight reload is replayed once that reload ends
+		c.drainTriggers()
+		reason := TriggerReasonTimer
+		if triggered {
+			c.followUp.Store(true)
+			reason = TriggerReasonTrigger
+		} else if startup {
+			reason = TriggerReasonStartup
+		}
+		startup = false
+		_, err := c.ReloadDetailed(withTriggerReason(context.Background(), reason))
+		next := backoff.next(c, err)
+		if err != nil && c.config().GetIntervalCheck() == 0 {
+			// no retry is planned either, but triggers wait for the backoff
+			backoff.retryAt = c.clock.Now().Add(next)
+		}
+		c.resetTicker(ticker, next)
+	}
+}
+
+// resetTicker plans the next background reload of Start in d, unless
+// IntervalCheck is zero: only triggers are served then.
+func (c *client) resetTicker(ticker clockwork.Timer, d time.Duration) {
+	if c.config().GetIntervalCheck() == 0 {
+		stopTimer(ticker)
+		return
 	}
+	ticker.Reset(d)
 }
 
-func (c *client) loadState() error {
-	redirectTreeMatcher := types.NewRedirectTreeMatcher()
-	pagesTreeMatcher := types.NewPageTreeMatcher()
-	version, errVersion := c.getProjectVersion()
-	if errVersion != nil {
-		return errVersion
+// stopTimer stops timer, dropping the tick of a zero timer that fired already.
+func stopTimer(timer clockwork.Timer) {
+	timer.Stop()
+	select {
+	case <-timer.Chan():
+	default:
 	}
+}
 
-	redirects, errRedirects := c.getProjectRedirects()
-	if errRedirects != nil {
-		return errRedirects
+// reloadBackoff spaces the reloads of a refresh loop after failures.
+type reloadBackoff struct {
+	failures int
+	// retryAt is when the Retry-After of the last reload, if any, expires, or
+	// with a zero IntervalCheck when the backoff after a failure does.
+	retryAt time.Time
+}
+
+// next records the outcome of a reload of c and returns the delay before the next one.
+func (b *reloadBackoff) next(c *client, err error) time.Duration {
+	switch ClassifyError(err) {
+	case ErrorClassNone:
+		b.failures = 0
+	case ErrorClassCanceled:
+	default:
+		b.failures++
+	}
+	next := max(backoffInterval(c.config().GetIntervalCheck(), c.config().MaxIntervalCheck, b.failures), retryAfter(err))
+	if errors.Is(err, ErrProjectNotFound) {
+		// a deleted project is unlikely to come back soon
+		next = max(next, c.config().MaxIntervalCheck)
 	}
+	b.retryAt = c.clock.Now().Add(retryAfter(err))
+	return next
+}
 
-	for i := range redirects {
-		err := redirectTreeMatcher.Insert(&redirects[i])
-		if err != nil {
-			return err
+// backoffInterval doubles interval for every consecutive failure, up to max.
+// A max lower than interval disables the backoff. After a failure, interval is
+// at least MinAllowedIntervalCheck, so that a zero interval still backs off.
+func backoffInterval(interval, max time.Duration, failures int) time.Duration {
+	if failures > 0 && interval < MinAllowedIntervalCheck {
+		interval = MinAllowedIntervalCheck
+	}
+	if max <= interval {
+		return interval
+	}
+	for i := 0; i < failures; i++ {
+		interval *= 2
+		if interval >= max {
+			return max
 		}
 	}
+	return interval
+}
 
-	pages, errPages := c.getProjectPages()
+// withReloadBudget bounds ctx by Config.MaxReloadDuration, measured on the client clock.
+func (c *client) withReloadBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config().MaxReloadDuration <= 0 {
+		return context.WithCancel(ctx)
+	}
+	budgetCtx, cancel := context.WithCancelCause(ctx)
+	timer := c.clock.AfterFunc(c.config().MaxReloadDuration, func() {
+		cancel(fmt.Errorf("%w (%s)", ErrReloadBudgetExceeded, c.config().MaxReloadDuration))
+	})
+	return budgetCtx, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+func (c *client) loadState(ctx context.Context) error {
+	ctx, cancel := c.withReloadBudget(ctx)
+	defer cancel()
+
+	err := c.fetchState(ctx)
+	if cause := context.Cause(ctx); errors.Is(cause, ErrReloadBudgetExceeded) {
+		return cause
+	}
+	return err
+}
+
+func (c *client) fetchState(ctx context.Context) error {
+	combined, errCombined := c.fetchCombinedState(ctx)
+	if errCombined != nil {
+		return errCombined
+	}
+	var version ParsedVersion
+	if combined != nil {
+		version = combined.version
+	} else {
+		var errVersion error
+		if version, errVersion = c.fetchVersion(ctx); errVersion != nil {
+			return errVersion
+		}
+	}
+
+	var redirects []types.Redirect
+	if combined != nil {
+		if c.config().GetLoadRedirects() {
+			redirects = combined.Redirects
+		}
+	} else if c.config().GetLoadRedirects() {
+		var errRedirects error
+		if redirects, errRedirects = c.getProjectRedirects(ctx); errRedirects != nil {
+			return errRedirects
+		}
+	}
+
+	var cached stateCache
+	if c.config().StateCacheFile != "" {
+		// transforms may modify the fetched rules in place
+		cached = stateCache{Version: version.Number, VersionKey: version.Key, Redirects: slices.Clone(redirects)}
+	}
+	redirectMatcher, loadedRedirects, errBuild := c.buildRedirects(ctx, redirects)
+	if errBuild != nil {
+		return errBuild
+	}
+
+	previous := c.load()
+	var pageMatcher types.PageTreeMatcher
+	var loadedPages []types.Page
+	var pages []types.Page
+	var errPages error
+	if combined != nil {
+		if c.config().GetLoadPages() {
+			pages = combined.Pages
+		}
+	} else if c.config().GetLoadPages() {
+		pages, errPages = c.getProjectPages(ctx)
+	}
 	if errPages != nil {
-		return errPages
+		if !c.config().PagesOptional {
+			return errPages
+		}
+		c.logger().WarnContext(ctx, "failed to fetch pages, keeping previous pages", "error", errPages)
+		pageMatcher, loadedPages = previous.PageMatcher, previous.Pages
+		if pageMatcher == nil {
+			pageMatcher, loadedPages = c.buildPages(nil)
+		}
+	} else {
+		cached.Pages = slices.Clone(pages)
+		pageMatcher, loadedPages = c.buildPages(pages)
 	}
-	for i := range pages {
-		pagesTreeMatcher.Insert(&pages[i])
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	state := c.newState(version, redirectMatcher, pageMatcher, loadedRedirects, loadedPages)
+	if c.config().DetectByContentHash && state.versionKey() == previous.versionKey() && state.ContentHash == previous.ContentHash {
+		return errStateUnchanged
+	}
+	if err := c.verifyState(state); err != nil {
+		return err
+	}
+	c.installState(previous, state)
+	if c.config().StateCacheFile != "" && errPages == nil {
+		if err := c.saveStateCache(cached); err != nil {
+			c.logger().WarnContext(ctx, "failed to write state cache", "path", c.config().StateCacheFile, "error", err)
+		}
+	}
+	return nil
+}
+
+// LoadFromData installs a state built from in-memory rules, without any request to the manager.
+func (c *client) LoadFromData(version int, redirects []types.Redirect, pages []types.Page) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	return c.installData(ParsedVersion{Key: strconv.Itoa(version), Number: version}, redirects, pages)
+}
+
+// installData builds and installs a state from rules that did not come from the
+// manager. The caller holds reloadMu.
+func (c *client) installData(version ParsedVersion, redirects []types.Redirect, pages []types.Page) error {
+	redirectMatcher, loadedRedirects, err := c.buildRedirects(context.Background(), slices.Clone(redirects))
+	if err != nil {
+		return err
+	}
+	pageMatcher, loadedPages := c.buildPages(slices.Clone(pages))
+	state := c.newState(version, redirectMatcher, pageMatcher, loadedRedirects, loadedPages)
+	if err := c.verifyState(state); err != nil {
+		return err
+	}
+	c.installState(c.load(), state)
+	return nil
+}
+
+func (c *client) newState(version ParsedVersion, redirectMatcher types.RedirectTreeMatcher, pageMatcher types.PageTreeMatcher, redirects []types.Redirect, pages []types.Page) *State {
+	return &State{
+		foldedKeys:      c.config().CaseInsensitivePaths,
+		decodedKeys:     c.config().DecodePathForMatch,
+		ProjectVersion:  version.Number,
+		VersionKey:      version.Key,
+		RedirectMatcher: redirectMatcher,
+		PageMatcher:     pageMatcher,
+		Redirects:       redirects,
+		Pages:           pages,
+		ContentHash:     ContentHash(redirects, pages),
+	}
+}
+
+// verifyState runs Config.VerifyState on a state about to be installed.
+func (c *client) verifyState(state *State) error {
+	if c.config().VerifyState == nil {
+		return nil
+	}
+	if err := c.config().VerifyState(state); err != nil {
+		return fmt.Errorf("%w: %w", ErrStateVerification, err)
 	}
-	state := &State{ProjectVersion: version, RedirectMatcher: redirectTreeMatcher, PageMatcher: pagesTreeMatcher}
-	c.State.Store(state)
 	return nil
 }
 
-func (c *client) getProjectVersion() (int, error) {
-	req, err := NewRequest(c.cfg.Http, http.MethodGet, c.cfg.GetUrlApiVersion(), nil)
+func (c *client) installState(previous, state *State) {
+	c.State.Store(state)
+	c.markReady()
+	if c.config().OnReload != nil {
+		c.config().OnReload(DiffState(previous, state))
+	}
+}
+
+func (c *client) buildRedirects(ctx context.Context, redirects []types.Redirect) (types.RedirectTreeMatcher, []types.Redirect, error) {
+	redirects, duplicates := dedupeRedirects(redirects)
+	if c.config().WarnDuplicates {
+		for _, duplicate := range duplicates {
+			c.logger().WarnContext(ctx, "dropping duplicate redirect", "error", duplicate)
+		}
+	}
+	loaded := make([]types.Redirect, 0, len(redirects))
+	for i := range redirects {
+		redirect := &redirects[i]
+		if c.config().RedirectTransform != nil {
+			var keep bool
+			if redirect, keep = c.config().RedirectTransform(redirect); !keep || redirect == nil {
+				continue
+			}
+		}
+		redirect = withDefaultStatus(redirect, c.config().GetDefaultRedirectStatus())
+		if c.config().DetectRedirectLoops && isRedirectLoop(redirect) {
+			c.logger().WarnContext(ctx, "skipping redirect", "error", RuleError{Index: i, Redirect: *redirect, Err: ErrRedirectLoop})
+			continue
+		}
+		loaded = append(loaded, *redirect)
+	}
+	loaded, err := c.limitRedirects(ctx, loaded)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := loaded
+	if c.config().CaseInsensitivePaths || c.config().DecodePathForMatch {
+		keys = make([]types.Redirect, 0, len(loaded))
+		for _, redirect := range loaded {
+			if c.config().DecodePathForMatch {
+				redirect = *decodeRedirect(redirect)
+			}
+			if c.config().CaseInsensitivePaths {
+				redirect = *foldRedirect(redirect)
+			}
+			keys = append(keys, redirect)
+		}
+	}
+	if c.config().ReuseMatchers {
+		previous, found := c.reusableState().RedirectMatcher.(*redirectIndex)
+		if !found {
+			previous = newRedirectIndex()
+		}
+		if index, ok, err := updateRedirectIndex(previous, keys); ok {
+			if err != nil {
+				return nil, nil, err
+			}
+			return index, loaded, nil
+		}
+	}
+	if c.config().ParallelInsert {
+		if index, ok, err := buildRedirectIndexParallel(keys, runtime.GOMAXPROCS(0)); ok {
+			if err != nil {
+				return nil, nil, err
+			}
+			return index, loaded, nil
+		}
+	}
+	matcher, err := BuildRedirectMatcher(keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return matcher, loaded, nil
+}
+
+// reusableState returns the installed state when its matchers were built from
+// keys folded and decoded like the current config asks, an empty state otherwise.
+// Config.ReuseMatchers applies a reload to copies of those matchers: they are
+// never modified once installed.
+func (c *client) reusableState() *State {
+	previous := c.load()
+	if previous.foldedKeys != c.config().CaseInsensitivePaths || previous.decodedKeys != c.config().DecodePathForMatch {
+		return &State{}
+	}
+	return previous
+}
+
+func (c *client) buildPages(pages []types.Page) (types.PageTreeMatcher, []types.Page) {
+	loaded := make([]types.Page, 0, len(pages))
+	for i := range pages {
+		page := &pages[i]
+		if c.config().PageTransform != nil {
+			var keep bool
+			if page, keep = c.config().PageTransform(page); !keep || page == nil {
+				continue
+			}
+		}
+		loaded = append(loaded, *page)
+	}
+	keys := loaded
+	if c.config().CaseInsensitivePaths || c.config().DecodePathForMatch {
+		keys = make([]types.Page, 0, len(loaded))
+		for _, page := range loaded {
+			if c.config().DecodePathForMatch {
+				page = *decodePage(page)
+			}
+			if c.config().CaseInsensitivePaths {
+				page = *foldPage(page)
+			}
+			keys = append(keys, page)
+		}
+	}
+	if c.config().ReuseMatchers {
+		previous, found := c.reusableState().PageMatcher.(*pageIndex)
+		if !found {
+			previous = newPageIndex()
+		}
+		if index, ok := updatePageIndex(previous, keys); ok {
+			return index, loaded
+		}
+	}
+	return BuildPageMatcher(keys), loaded
+}
+
+func (c *client) metrics() Metrics {
+	if c.config().Metrics == nil {
+		return noopMetrics{}
+	}
+	return c.config().Metrics
+}
+
+func (c *client) do(endpoint Endpoint, req *http.Request) (*http.Response, error) {
+	resp, err := c.doOnce(endpoint, req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	retry := c.reauthorizedRequest(req)
+	if retry == nil {
+		return resp, err
+	}
+	closeBody(resp)
+	return c.doOnce(endpoint, retry)
+}
+
+// reauthorizedRequest returns a copy of req rejected with a 401 carrying the
+// token re-read from HTTPConfig.TokenFile, or nil when the token did not change
+// or the body cannot be sent again.
+func (c *client) reauthorizedRequest(req *http.Request) *http.Request {
+	httpCfg := c.config().Http
+	if httpCfg.TokenFile == "" || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return nil
+	}
+	c.tokenFile.invalidate()
+	authorization := httpCfg.authorizationValue(c.token())
+	if authorization == req.Header.Get(httpCfg.HeaderAuthorizationName) {
+		return nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil
+		}
+		retry.Body = body
+	}
+	retry.Header.Set(httpCfg.HeaderAuthorizationName, authorization)
+	c.logger().DebugContext(req.Context(), "token rejected, retrying with the token file re-read", "url", req.URL.String())
+	return retry
+}
+
+func (c *client) doOnce(endpoint Endpoint, req *http.Request) (*http.Response, error) {
+	if c.config().Http.OnRequest != nil {
+		c.config().Http.OnRequest(inspectableRequest(req))
+	}
+	req, tracer := c.traceRequest(req)
+	start := c.clock.Now()
+	resp, err := c.limitedDo(req)
+	c.metrics().ObserveRequest(endpoint, c.clock.Since(start), err)
+	c.observeTrace(req.Context(), endpoint, tracer)
+	if c.config().Http.OnResponse != nil {
+		c.config().Http.OnResponse(c.inspectableResponse(resp), err)
+	}
+	return resp, err
+}
+
+func newRequestSlots(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+	return make(chan struct{}, limit)
+}
+
+// limitedDo sends req once a request slot is free, or fails with the request
+// context error if it is done first.
+func (c *client) limitedDo(req *http.Request) (*http.Response, error) {
+	httpClient := c.config().Http.Client
+	if c.requestSlots == nil {
+		return httpClient.Do(req)
+	}
+	select {
+	case c.requestSlots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-c.requestSlots }()
+	return httpClient.Do(req)
+}
+
+// inspectableRequest gives OnRequest a copy whose body can be read without
+// consuming the one sent.
+func inspectableRequest(req *http.Request) *http.Request {
+	if req.GetBody == nil {
+		return req
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return req
+	}
+	inspected := req.Clone(req.Context())
+	inspected.Body = body
+	return inspected
+}
+
+// inspectableResponse buffers the start of the body, up to the response limit,
+// so that OnResponse and the client both read it in full.
+func (c *client) inspectableResponse(resp *http.Response) *http.Response {
+	if resp == nil || resp.Body == nil {
+		return resp
+	}
+	buffered, _ := io.ReadAll(io.LimitReader(resp.Body, c.config().Http.GetMaxResponseBytes()+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buffered), resp.Body), resp.Body}
+	inspected := *resp
+	inspected.Body = io.NopCloser(bytes.NewReader(buffered))
+	return &inspected
+}
+
+func (c *client) limitBody(resp *http.Response) io.Reader {
+	return newMaxBytesReader(resp.Body, c.config().Http.GetMaxResponseBytes())
+}
+
+func (c *client) getProjectVersion(ctx context.Context) (int, error) {
+	version, err := c.fetchVersion(ctx)
+	return version.Number, err
+}
+
+func (c *client) fetchVersion(ctx context.Context) (ParsedVersion, error) {
+	req, err := c.newRequest(ctx, EndpointVersion, http.MethodGet, c.config().GetUrlApiVersion(), nil)
 	if err != nil {
-		return 0, err
+		return ParsedVersion{}, err
 	}
-	resp, errReq := c.httpClient.Do(req)
+	resp, errReq := c.do(EndpointVersion, req)
 	if errReq != nil {
-		return 0, errReq
+		return ParsedVersion{}, errReq
 	}
-	defer func() { _ = resp.Body.Close() }()
+	defer closeBody(resp)
 
-	body, errReadBody := io.ReadAll(resp.Body)
+	body, errReadBody := io.ReadAll(c.limitBody(resp))
 	if errReadBody != nil {
-		return 0, errReadBody
+		return ParsedVersion{}, errReadBody
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("unexpected status code for %s: %s (%d) %s", c.cfg.GetUrlApiVersion(), resp.Status, resp.StatusCode, body)
+	if !c.config().Http.isSuccess(resp.StatusCode) {
+		apiErr := c.apiError(c.config().GetUrlApiVersion(), resp, body)
+		if resp.StatusCode == http.StatusNotFound {
+			return ParsedVersion{}, fmt.Errorf("%w: %w", ErrProjectNotFound, apiErr)
+		}
+		return ParsedVersion{}, apiErr
 	}
 
-	version, errCastInt := strconv.Atoi(strings.TrimSpace(string(body)))
-	if errCastInt != nil {
-		return 0, errCastInt
+	rawVersion := strings.TrimSpace(string(body))
+	if rawVersion == "" {
+		return ParsedVersion{}, fmt.Errorf("%w returned by %s", ErrEmptyVersion, c.config().GetUrlApiVersion())
 	}
 
+	version, err := c.parseVersion(rawVersion)
+	if err != nil {
+		return ParsedVersion{}, versionParseError{err}
+	}
 	return version, nil
 }
 
-func (c *client) getProjectRedirects() ([]types.Redirect, error) {
+// projectState is the body of the combined state endpoint.
+type projectState struct {
+	Version   string
+	Redirects []types.Redirect
+	Pages     []types.Page
+
+	version ParsedVersion
+}
+
+// fetchCombinedState fetches the state endpoint when Config.UseCombinedStateEndpoint
+// is set. It returns nil without error when the separate endpoints must be used,
+// remembering a manager without the endpoint. The caller holds reloadMu.
+func (c *client) fetchCombinedState(ctx context.Context) (*projectState, error) {
+	if !c.config().UseCombinedStateEndpoint || c.stateUnsupported {
+		return nil, nil
+	}
+	state, err := c.getProjectState(ctx)
+	if !isEndpointMissing(err) {
+		return state, err
+	}
+	c.stateUnsupported = true
+	c.logger().InfoContext(ctx, "combined state endpoint unsupported, using the separate endpoints", "error", err)
+	return nil, nil
+}
+
+func (c *client) getProjectState(ctx context.Context) (*projectState, error) {
+	req, err := c.newRequest(ctx, EndpointState, http.MethodGet, c.config().GetUrlApiState(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", c.config().Http.AcceptHeader())
+	resp, errReq := c.do(EndpointState, req)
+	if errReq != nil {
+		return nil, errReq
+	}
+
+	state := &projectState{}
+	if err := c.handleResponse(resp, c.config().GetUrlApiState(), state); err != nil {
+		return nil, err
+	}
+	rawVersion := strings.TrimSpace(state.Version)
+	if rawVersion == "" {
+		return nil, fmt.Errorf("%w returned by %s", ErrEmptyVersion, c.config().GetUrlApiState())
+	}
+	if state.version, err = c.parseVersion(rawVersion); err != nil {
+		return nil, versionParseError{err}
+	}
+	if state.Redirects == nil {
+		state.Redirects = make([]types.Redirect, 0)
+	}
+	if state.Pages == nil {
+		state.Pages = make([]types.Page, 0)
+	}
+	return state, nil
+}
+
+// isEndpointMissing reports whether the manager answered with 404 or 405, i.e.
+// it predates the endpoint called.
+func isEndpointMissing(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed)
+}
+
+// listURL appends the extra query to a list endpoint; pagination parameters always win.
+// A non-empty cursor replaces the offset.
+func listURL(endpoint string, extra url.Values, limit, offset int, cursor string) string {
+	query := url.Values{}
+	for key, values := range extra {
+		query[key] = append([]string(nil), values...)
+	}
+	query.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		query.Del("offset")
+		query.Set("cursor", cursor)
+	} else {
+		query.Del("cursor")
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	return fmt.Sprintf("%s?%s", endpoint, query.Encode())
+}
+
+// redirectListPage is types.RedirectList plus the cursor returned by cursor-paginated managers.
+type redirectListPage struct {
+	Items      []types.Redirect
+	Total      int
+	Limit      int
+	Offset     int
+	NextCursor string
+}
+
+// pageListPage is types.PageList plus the cursor returned by cursor-paginated managers.
+type pageListPage struct {
+	Items      []types.Page
+	Total      int
+	Limit      int
+	Offset     int
+	NextCursor string
+}
+
+// FetchRedirects fetches the redirects of the project as the manager returns
+// them, before transforms, deduplication and case folding, without installing
+// them, e.g. to seed another cache. It waits for an in-flight reload.
+func (c *client) FetchRedirects(ctx context.Context) ([]types.Redirect, error) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	return c.getProjectRedirects(ctx)
+}
+
+// FetchPages is FetchRedirects for pages.
+func (c *client) FetchPages(ctx context.Context) ([]types.Page, error) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	return c.getProjectPages(ctx)
+}
+
+// FetchRedirect fetches the redirect of the project with this source, e.g. to
+// check a rule exists, from the manager item endpoint or, when the manager
+// answers it with 404 or 405, from the list: the first redirect with the source.
+// Only the list waits for an in-flight reload. It returns ErrRedirectNotFound
+// when the project has none.
+func (c *client) FetchRedirect(ctx context.Context, source string) (*types.Redirect, error) {
+	redirect := &types.Redirect{}
+	switch err := c.getProjectItem(ctx, EndpointRedirect, c.config().GetUrlApiRedirect(source), redirect); {
+	case err == nil:
+		return redirect, nil
+	case !isEndpointMissing(err):
+		return nil, err
+	}
+	redirects, err := c.FetchRedirects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range redirects {
+		if redirects[i].Source == source {
+			return &redirects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrRedirectNotFound, source)
+}
+
+// FetchPage is FetchRedirect for the page with this path.
+func (c *client) FetchPage(ctx context.Context, path string) (*types.Page, error) {
+	page := &types.Page{}
+	switch err := c.getProjectItem(ctx, EndpointPage, c.config().GetUrlApiPage(path), page); {
+	case err == nil:
+		return page, nil
+	case !isEndpointMissing(err):
+		return nil, err
+	}
+	pages, err := c.FetchPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pages {
+		if pages[i].Path == path {
+			return &pages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrPageNotFound, path)
+}
+
+// getProjectItem fetches a single rule from an item endpoint into v.
+func (c *client) getProjectItem(ctx context.Context, endpoint Endpoint, url string, v any) error {
+	req, err := c.newRequest(ctx, endpoint, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", c.config().Http.AcceptHeader())
+	resp, errReq := c.do(endpoint, req)
+	if errReq != nil {
+		return errReq
+	}
+	return c.handleResponse(resp, url, v)
+}
+
+func (c *client) getProjectRedirects(ctx context.Context) ([]types.Redirect, error) {
 	redirects := make([]types.Redirect, 0)
 	offset := 0
 	limit := 100
+	cursor := ""
 	for {
-		redirectList := types.RedirectList{}
-		url := fmt.Sprintf("%s?limit=%d&offset=%d", c.cfg.GetUrlApiRedirects(), limit, offset)
-		req, err := NewRequest(c.cfg.Http, http.MethodGet, url, nil)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		redirectList := redirectListPage{}
+		requestUrl := listURL(c.config().GetUrlApiRedirects(), c.config().RedirectQuery, limit, offset, cursor)
+		req, err := c.newRequest(ctx, EndpointRedirects, http.MethodGet, requestUrl, nil)
 		if err != nil {
 			return nil, err
 		}
-		resp, errReq := c.httpClient.Do(req)
+		req.Header.Set("Accept", c.config().Http.AcceptHeader())
+		resp, errReq := c.do(EndpointRedirects, req)
 		if errReq != nil {
 			return nil, errReq
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("unexpected status code for %s: %s (%d) %s", c.cfg.GetUrlApiRedirects(), resp.Status, resp.StatusCode, body)
-		}
-
-		err = json.NewDecoder(resp.Body).Decode(&redirectList)
-		if err != nil {
+		if err := c.handleResponse(resp, c.config().GetUrlApiRedirects(), &redirectList); err != nil {
 			return nil, err
 		}
-		_ = resp.Body.Close()
 		redirects = append(redirects, redirectList.Items...)
+		c.reportProgress(ctx, "redirects", len(redirects), redirectList.Total)
+		if redirectList.NextCursor != "" && redirectList.NextCursor != cursor {
+			cursor = redirectList.NextCursor
+			continue
+		}
+		if cursor != "" {
+			break
+		}
 		offset += limit
 		if offset >= redirectList.Total {
 			break
@@ -208,33 +1397,39 @@ func (c *client) getProjectRedirects() ([]types.Redirect, error) {
 	return redirects, nil
 }
 
-func (c *client) getProjectPages() ([]types.Page, error) {
+func (c *client) getProjectPages(ctx context.Context) ([]types.Page, error) {
 	pages := make([]types.Page, 0)
 	offset := 0
 	limit := 100
+	cursor := ""
 	for {
-		pageList := types.PageList{}
-		url := fmt.Sprintf("%s?limit=%d&offset=%d", c.cfg.GetUrlApiPages(), limit, offset)
-		req, err := NewRequest(c.cfg.Http, http.MethodGet, url, nil)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pageList := pageListPage{}
+		requestUrl := listURL(c.config().GetUrlApiPages(), c.config().PageQuery, limit, offset, cursor)
+		req, err := c.newRequest(ctx, EndpointPages, http.MethodGet, requestUrl, nil)
 		if err != nil {
 			return nil, err
 		}
-		resp, errReq := c.httpClient.Do(req)
+		req.Header.Set("Accept", c.config().Http.AcceptHeader())
+		resp, errReq := c.do(EndpointPages, req)
 		if errReq != nil {
 			return nil, errReq
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("unexpected status code for %s: %s (%d) %s", c.cfg.GetUrlApiPages(), resp.Status, resp.StatusCode, body)
-		}
-
-		err = json.NewDecoder(resp.Body).Decode(&pageList)
-		if err != nil {
+		if err := c.handleResponse(resp, c.config().GetUrlApiPages(), &pageList); err != nil {
 			return nil, err
 		}
-		_ = resp.Body.Close()
 		pages = append(pages, pageList.Items...)
+		c.reportProgress(ctx, "pages", len(pages), pageList.Total)
+		if pageList.NextCursor != "" && pageList.NextCursor != cursor {
+			cursor = pageList.NextCursor
+			continue
+		}
+		if cursor != "" {
+			break
+		}
 		offset += limit
 		if offset >= pageList.Total {
 			break
@@ -244,48 +1439,83 @@ func (c *client) getProjectPages() ([]types.Page, error) {
 	return pages, nil
 }
 
-func (c *client) sendAgentStatus(agent types.Agent) error {
+// agentStatusPayload extends types.Agent with fields the manager may not know yet.
+type agentStatusPayload struct {
+	types.Agent
+	AgentVersion  string        `json:"agent_version,omitempty"`
+	TriggerReason TriggerReason `json:"trigger_reason,omitempty"`
+}
+
+func validateAgentVersion(version string) error {
+	if version != "" && !validAgentVersionRegex.MatchString(version) {
+		return fmt.Errorf("invalid agent version %q: only up to 100 alphanumeric characters, dots, underscores, plus and hyphens are allowed", version)
+	}
+	return nil
+}
+
+// sendAgentStatus posts the agent status once for each of Config.GetAgentTypes.
+func (c *client) sendAgentStatus(ctx context.Context, agent types.Agent) error {
+	for _, typ := range c.config().GetAgentTypes() {
+		agent.Type = typ
+		if err := c.postAgentStatus(ctx, agent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *client) postAgentStatus(ctx context.Context, agent types.Agent) error {
 	if err := types.ValidateAgent(agent); err != nil {
 		return err
 	}
+	if err := validateAgentVersion(c.config().AgentVersion); err != nil {
+		return err
+	}
 
-	jsonAgent, errMarshal := json.Marshal(agent)
+	jsonAgent, errMarshal := json.Marshal(agentStatusPayload{Agent: agent, AgentVersion: c.config().AgentVersion, TriggerReason: triggerReason(ctx)})
 	if errMarshal != nil {
 		return errMarshal
 	}
 
 	body := bytes.NewReader(jsonAgent)
-	req, err := NewRequest(c.cfg.Http, http.MethodPost, c.cfg.GetUrlApiAgents(), body)
+	req, err := c.newRequest(ctx, EndpointAgentStatus, http.MethodPost, c.config().GetUrlApiAgents(), body)
 	if err != nil {
 		return err
 	}
 	req.Header.Add("Content-Type", "application/json")
-	resp, errReq := c.httpClient.Do(req)
+	resp, errReq := c.do(EndpointAgentStatus, req)
 	if errReq != nil {
 		return errReq
 	}
+	return c.handleResponse(resp, c.config().GetUrlApiAgents(), nil)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyResp, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code for %s: %s (%d) %s", c.cfg.GetUrlApiAgents(), resp.Status, resp.StatusCode, bodyResp)
-	}
-	return nil
+// agentHitPayload is the body of the agent hit with Config.HitIncludesBody.
+type agentHitPayload struct {
+	Version   int       `json:"version"`
+	Timestamp time.Time `json:"ts"`
 }
 
-func (c *client) sendAgentHit(name string) error {
-	req, err := NewRequest(c.cfg.Http, http.MethodPatch, c.cfg.GetUrlApiAgentsHit(name), nil)
+func (c *client) sendAgentHit(ctx context.Context, name string) error {
+	var body io.Reader
+	if c.config().HitIncludesBody {
+		jsonHit, errMarshal := json.Marshal(agentHitPayload{Version: c.load().ProjectVersion, Timestamp: c.clock.Now().UTC()})
+		if errMarshal != nil {
+			return errMarshal
+		}
+		body = bytes.NewReader(jsonHit)
+	}
+	req, err := c.newRequest(ctx, EndpointAgentHit, http.MethodPatch, c.config().GetUrlApiAgentsHit(name), body)
 	if err != nil {
 		return err
 	}
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
 
-	resp, errReq := c.httpClient.Do(req)
+	resp, errReq := c.do(EndpointAgentHit, req)
 	if errReq != nil {
 		return errReq
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code for %s: %s (%d) %s", c.cfg.GetUrlApiAgentsHit(name), resp.Status, resp.StatusCode, body)
-	}
-	return nil
+	return c.handleResponse(resp, c.config().GetUrlApiAgentsHit(name), nil)
 }
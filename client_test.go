@@ -5,14 +5,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/flectolab/flecto-manager/common/types"
 	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // mockHTTPClient is a manual mock for HTTPClient interface
@@ -41,6 +47,9 @@ func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	}
 	resp := m.responses[m.callIndex]
 	m.callIndex++
+	if resp.resp != nil {
+		resp.resp.Request = req
+	}
 	return resp.resp, resp.err
 }
 
@@ -61,16 +70,19 @@ func newTestClient() (*client, *mockHTTPClient, clockwork.FakeClock) {
 		Http: &HTTPConfig{
 			Client:                  mockHTTP,
 			HeaderAuthorizationName: "Authorization",
+			AuthScheme:              "Bearer",
 			TokenJWT:                "test-token",
 		},
-		IntervalCheck: 5 * time.Minute,
+		IntervalCheck:          5 * time.Minute,
+		SkipCompatibilityCheck: true,
+		SkipReloadOnStart:      true,
 	}
 
 	c := &client{
-		cfg:        cfg,
-		httpClient: mockHTTP,
-		clock:      fakeClock,
+		clock:   fakeClock,
+		trigger: make(chan struct{}, 1),
 	}
+	c.cfg.Store(cfg)
 	c.State.Store(&State{})
 
 	return c, mockHTTP, fakeClock
@@ -111,6 +123,14 @@ func makePagesResponse(pages []types.Page, total int) *http.Response {
 	}
 }
 
+func makeStateResponse(version string, redirects []types.Redirect, pages []types.Page) *http.Response {
+	body, _ := json.Marshal(projectState{Version: version, Redirects: redirects, Pages: pages})
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBuffer(body)),
+	}
+}
+
 func makeErrorResponse(statusCode int) *http.Response {
 	return &http.Response{
 		StatusCode: statusCode,
@@ -126,6 +146,41 @@ func makeAgentResponse() *http.Response {
 	}
 }
 
+// testLoad describes the responses of a reload scripted by expectLoad.
+type testLoad struct {
+	version   string
+	redirects []types.Redirect
+	// redirectsTotal, when above len(redirects), makes the redirects list span
+	// several pages of 100.
+	redirectsTotal int
+	// progressHits answers the progress hits sent after the second redirects
+	// page and after the pages.
+	progressHits bool
+	// noAgent leaves out the agent status, e.g. when statuses are batched.
+	noAgent bool
+}
+
+// expectLoad scripts a reload: the version check, the version fetched by the
+// load, the redirects and pages lists, then the agent status.
+func expectLoad(mockHTTP *mockHTTPClient, load testLoad) {
+	mockHTTP.expect(makeVersionResponse(load.version), nil)
+	mockHTTP.expect(makeVersionResponse(load.version), nil)
+	total := max(load.redirectsTotal, len(load.redirects))
+	for offset := 0; offset == 0 || offset < total; offset += 100 {
+		mockHTTP.expect(makeRedirectsResponse(load.redirects, total), nil)
+		if load.progressHits && offset == 100 {
+			mockHTTP.expect(makeAgentResponse(), nil)
+		}
+	}
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	if load.progressHits {
+		mockHTTP.expect(makeAgentResponse(), nil)
+	}
+	if !load.noAgent {
+		mockHTTP.expect(makeAgentResponse(), nil)
+	}
+}
+
 func TestNew(t *testing.T) {
 	cfg := NewDefaultConfig()
 	cfg.ManagerUrl = "http://localhost:8080"
@@ -138,6 +193,44 @@ func TestNew(t *testing.T) {
 	assert.Implements(t, (*Client)(nil), c)
 }
 
+func TestNew_WithClock(t *testing.T) {
+	mockHTTP := newMockHTTPClient()
+	fakeClock := clockwork.NewFakeClock()
+	cfg := NewDefaultConfig()
+	cfg.ManagerUrl = "http://localhost:8080"
+	cfg.NamespaceCode = "ns"
+	cfg.ProjectCode = "proj"
+	cfg.Http.Client = mockHTTP
+	cfg.SkipReloadOnStart = true
+
+	c := New(cfg, WithClock(fakeClock))
+	assert.Same(t, fakeClock, c.(*client).clock)
+
+	mockHTTP.expect(makeVersionResponse("0"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(cfg.IntervalCheck)
+	fakeClock.BlockUntil(1)
+	cancel()
+	<-done
+
+	assert.Len(t, mockHTTP.calls, 2)
+}
+
+func TestNew_WithClockNil(t *testing.T) {
+	c := New(NewDefaultConfig(), WithClock(nil))
+
+	assert.NotNil(t, c.(*client).clock)
+}
+
 func TestClient_Init_Success(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
 
@@ -185,16 +278,17 @@ func TestClient_Init_InvalidAgentType(t *testing.T) {
 		Http: &HTTPConfig{
 			Client:                  mockHTTP,
 			HeaderAuthorizationName: "Authorization",
+			AuthScheme:              "Bearer",
 			TokenJWT:                "test-token",
 		},
-		IntervalCheck: 5 * time.Minute,
+		IntervalCheck:          5 * time.Minute,
+		SkipCompatibilityCheck: true,
 	}
 
 	c := &client{
-		cfg:        cfg,
-		httpClient: mockHTTP,
-		clock:      fakeClock,
+		clock: fakeClock,
 	}
+	c.cfg.Store(cfg)
 	c.State.Store(&State{})
 
 	err := c.Init()
@@ -246,6 +340,67 @@ func Test_client_RedirectMatch(t *testing.T) {
 	assert.Equal(t, target, redirects[0].Target)
 }
 
+func Test_client_RedirectMatchStatus_Default(t *testing.T) {
+	c, _, _ := newTestClient()
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/unset", Target: "/moved"},
+		{Type: types.RedirectTypeBasic, Source: "/found", Target: "/elsewhere", Status: types.RedirectStatusFound},
+		{Type: types.RedirectTypeBasic, Source: "/permanent", Target: "/kept", Status: types.RedirectStatusPermanent},
+	}
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+
+	_, status, ok := c.RedirectMatchStatus("example.com", "/unset")
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusMovedPermanently, status)
+	redirect, _ := c.RedirectMatch("example.com", "/unset")
+	assert.Equal(t, http.StatusMovedPermanently, redirect.HTTPCode())
+
+	c.config().DefaultRedirectStatus = types.RedirectStatusTemporary
+	assert.NoError(t, c.LoadFromData(2, redirects, nil))
+	_, status, _ = c.RedirectMatchStatus("example.com", "/unset")
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	redirect, _ = c.RedirectMatch("example.com", "/unset")
+	assert.Equal(t, types.RedirectStatusTemporary, redirect.Status)
+	assert.Equal(t, http.StatusTemporaryRedirect, redirect.HTTPCode())
+	assert.Empty(t, redirects[0].Status)
+
+	_, status, _ = c.RedirectMatchStatus("example.com", "/found")
+	assert.Equal(t, http.StatusFound, status)
+	redirect, _ = c.RedirectMatch("example.com", "/permanent")
+	assert.Equal(t, http.StatusPermanentRedirect, redirect.HTTPCode())
+}
+
+func Test_client_RedirectMatchStatus(t *testing.T) {
+	c, _, _ := newTestClient()
+	tree := types.NewRedirectTreeMatcher()
+	_ = tree.Insert(&types.Redirect{Type: types.RedirectTypeBasic, Source: "/permanent", Target: "/moved", Status: types.RedirectStatusMovedPermanent})
+	_ = tree.Insert(&types.Redirect{Type: types.RedirectTypeBasic, Source: "/found", Target: "/elsewhere", Status: types.RedirectStatusFound})
+	_ = tree.Insert(&types.Redirect{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1", Status: types.RedirectStatusPermanent})
+	c.State.Store(&State{RedirectMatcher: tree})
+
+	tests := []struct {
+		name       string
+		uri        string
+		wantTarget string
+		wantStatus int
+		wantOk     bool
+	}{
+		{name: "moved permanently", uri: "/permanent", wantTarget: "/moved", wantStatus: http.StatusMovedPermanently, wantOk: true},
+		{name: "found", uri: "/found", wantTarget: "/elsewhere", wantStatus: http.StatusFound, wantOk: true},
+		{name: "permanent redirect with regex target", uri: "/blog/post", wantTarget: "/news/post", wantStatus: http.StatusPermanentRedirect, wantOk: true},
+		{name: "miss", uri: "/missing", wantTarget: "", wantStatus: 0, wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, status, ok := c.RedirectMatchStatus("example.com", tt.uri)
+			assert.Equal(t, tt.wantTarget, target)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
+
 func Test_client_PageMatch(t *testing.T) {
 	c, _, _ := newTestClient()
 	pages := []*types.Page{
@@ -261,6 +416,60 @@ func Test_client_PageMatch(t *testing.T) {
 	assert.Equal(t, page, pages[0])
 }
 
+func Test_client_PageMatchMethods(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().PageMethods = []string{http.MethodGet}
+	page := &types.Page{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain}
+	tree := types.NewPageTreeMatcher()
+	tree.Insert(page)
+	c.State.Store(&State{PageMatcher: tree})
+
+	got, methods := c.PageMatchMethods("example.com", "/robots.txt")
+	assert.Equal(t, page, got)
+	assert.Equal(t, []string{http.MethodGet}, methods)
+
+	got, methods = c.PageMatchMethods("example.com", "/missing")
+	assert.Nil(t, got)
+	assert.Nil(t, methods)
+}
+
+func Test_client_PageMatchMethod(t *testing.T) {
+	page := &types.Page{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain}
+	tests := []struct {
+		name        string
+		pageMethods []string
+		disableHead bool
+		uri         string
+		method      string
+		want        *types.Page
+	}{
+		{name: "allowed method", pageMethods: []string{http.MethodGet}, uri: "/robots.txt", method: http.MethodGet, want: page},
+		{name: "allowed method case insensitive", pageMethods: []string{http.MethodGet}, uri: "/robots.txt", method: "get", want: page},
+		{name: "disallowed method", pageMethods: []string{http.MethodGet}, uri: "/robots.txt", method: http.MethodPost, want: nil},
+		{name: "any method when unset", pageMethods: nil, uri: "/robots.txt", method: http.MethodDelete, want: page},
+		{name: "unknown path", pageMethods: []string{http.MethodGet}, uri: "/missing", method: http.MethodGet, want: nil},
+		{name: "head implied by get", pageMethods: []string{http.MethodGet}, uri: "/robots.txt", method: http.MethodHead, want: page},
+		{name: "head implied case insensitive", pageMethods: []string{"get"}, uri: "/robots.txt", method: "head", want: page},
+		{name: "head explicitly allowed", pageMethods: []string{http.MethodHead}, uri: "/robots.txt", method: http.MethodHead, want: page},
+		{name: "head not implied without get", pageMethods: []string{http.MethodPost}, uri: "/robots.txt", method: http.MethodHead, want: nil},
+		{name: "head disabled", pageMethods: []string{http.MethodGet}, disableHead: true, uri: "/robots.txt", method: http.MethodHead, want: nil},
+		{name: "head disabled but listed", pageMethods: []string{http.MethodGet, http.MethodHead}, disableHead: true, uri: "/robots.txt", method: http.MethodHead, want: page},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _ := newTestClient()
+			c.config().PageMethods = tt.pageMethods
+			c.config().DisableImplicitHead = tt.disableHead
+			tree := types.NewPageTreeMatcher()
+			tree.Insert(page)
+			c.State.Store(&State{PageMatcher: tree})
+
+			assert.Equal(t, tt.want, c.PageMatchMethod("example.com", tt.uri, tt.method))
+		})
+	}
+}
+
 func TestClient_getProjectVersion_Success(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -290,7 +499,7 @@ func TestClient_getProjectVersion_Success(t *testing.T) {
 
 			mockHTTP.expect(makeVersionResponse(tt.response), nil)
 
-			version, err := c.getProjectVersion()
+			version, err := c.getProjectVersion(context.Background())
 
 			assert.NoError(t, err)
 			assert.Equal(t, tt.wantVersion, version)
@@ -303,7 +512,7 @@ func TestClient_getProjectVersion_HTTPError(t *testing.T) {
 
 	mockHTTP.expect(nil, errors.New("network error"))
 
-	version, err := c.getProjectVersion()
+	version, err := c.getProjectVersion(context.Background())
 
 	assert.Error(t, err)
 	assert.Equal(t, 0, version)
@@ -325,7 +534,7 @@ func TestClient_getProjectVersion_Non200Status(t *testing.T) {
 
 			mockHTTP.expect(makeErrorResponse(tt.statusCode), nil)
 
-			version, err := c.getProjectVersion()
+			version, err := c.getProjectVersion(context.Background())
 
 			assert.Error(t, err)
 			assert.Equal(t, 0, version)
@@ -334,17 +543,125 @@ func TestClient_getProjectVersion_Non200Status(t *testing.T) {
 	}
 }
 
+func TestClient_getProjectVersion_ProjectNotFound(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeErrorResponse(http.StatusNotFound), nil)
+
+	_, err := c.getProjectVersion(context.Background())
+
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestClient_Reload_ProjectNotFound(t *testing.T) {
+	tests := []struct {
+		name      string
+		clear     bool
+		wantMatch bool
+	}{
+		{name: "keeps state", clear: false, wantMatch: true},
+		{name: "clears state", clear: true, wantMatch: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, _ := newTestClient()
+			c.config().ClearStateOnProjectMissing = tt.clear
+			assert.NoError(t, c.LoadFromData(3, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}, nil))
+
+			mockHTTP.expect(makeErrorResponse(http.StatusNotFound), nil)
+
+			err := c.Reload()
+
+			assert.ErrorIs(t, err, ErrProjectNotFound)
+			assert.Len(t, mockHTTP.calls, 1)
+			redirect, _ := c.RedirectMatch("example.com", "/old")
+			assert.Equal(t, tt.wantMatch, redirect != nil)
+			assert.Equal(t, tt.wantMatch, c.GetStateVersion() == 3)
+		})
+	}
+}
+
+func TestClient_Start_ProjectNotFoundSlowsDown(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().MaxIntervalCheck = time.Hour
+
+	mockHTTP.expect(makeErrorResponse(http.StatusNotFound), nil)
+	mockHTTP.expect(makeErrorResponse(http.StatusNotFound), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	assert.Eventually(t, func() bool { return c.Stats().Attempted == 1 }, time.Second, 10*time.Millisecond)
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(59 * time.Minute)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, uint64(1), c.Stats().Attempted)
+
+	fakeClock.Advance(time.Minute)
+	assert.Eventually(t, func() bool { return c.Stats().Attempted == 2 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
 func TestClient_getProjectVersion_InvalidResponse(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
 
 	mockHTTP.expect(makeVersionResponse("not-a-number"), nil)
 
-	version, err := c.getProjectVersion()
+	version, err := c.getProjectVersion(context.Background())
 
 	assert.Error(t, err)
 	assert.Equal(t, 0, version)
 }
 
+func TestClient_getProjectVersion_EmptyBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{name: "empty string", response: ""},
+		{name: "whitespace only", response: "  \n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, _ := newTestClient()
+
+			mockHTTP.expect(makeVersionResponse(tt.response), nil)
+
+			version, err := c.getProjectVersion(context.Background())
+
+			assert.ErrorIs(t, err, ErrEmptyVersion)
+			assert.Equal(t, 0, version)
+			assert.Contains(t, err.Error(), c.config().GetUrlApiVersion())
+		})
+	}
+}
+
+func TestClient_getProjectVersion_BodyTooLarge(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.MaxResponseBytes = 4
+
+	mockHTTP.expect(makeVersionResponse("123456"), nil)
+
+	version, err := c.getProjectVersion(context.Background())
+
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Equal(t, 0, version)
+}
+
 func TestClient_getProjectVersion_ReadBodyError(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
 
@@ -355,7 +672,7 @@ func TestClient_getProjectVersion_ReadBodyError(t *testing.T) {
 
 	mockHTTP.expect(resp, nil)
 
-	version, err := c.getProjectVersion()
+	version, err := c.getProjectVersion(context.Background())
 
 	assert.Error(t, err)
 	assert.Equal(t, 0, version)
@@ -374,16 +691,16 @@ func TestClient_getProjectVersion_NewRequestError(t *testing.T) {
 			HeaderAuthorizationName: "Authorization",
 			TokenJWT:                "token",
 		},
-		IntervalCheck: 5 * time.Minute,
+		IntervalCheck:          5 * time.Minute,
+		SkipCompatibilityCheck: true,
 	}
 
 	c := &client{
-		cfg:        cfg,
-		httpClient: mockHTTP,
-		clock:      fakeClock,
+		clock: fakeClock,
 	}
+	c.cfg.Store(cfg)
 
-	version, err := c.getProjectVersion()
+	version, err := c.getProjectVersion(context.Background())
 
 	assert.Error(t, err)
 	assert.Equal(t, 0, version)
@@ -405,7 +722,7 @@ func TestClient_getProjectRedirects_Success(t *testing.T) {
 
 	mockHTTP.expect(makeRedirectsResponse(redirects, 2), nil)
 
-	result, err := c.getProjectRedirects()
+	result, err := c.getProjectRedirects(context.Background())
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
@@ -446,18 +763,125 @@ func TestClient_getProjectRedirects_Pagination(t *testing.T) {
 	mockHTTP.expect(resp1, nil)
 	mockHTTP.expect(resp2, nil)
 
-	result, err := c.getProjectRedirects()
+	result, err := c.getProjectRedirects(context.Background())
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 101)
 }
 
+func TestClient_getProjectRedirects_BodyTooLarge(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.MaxResponseBytes = 16
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old1", Target: "/new1", Status: types.RedirectStatusMovedPermanent},
+	}
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+
+	result, err := c.getProjectRedirects(context.Background())
+
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Nil(t, result)
+}
+
+func Test_listURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra url.Values
+		want  string
+	}{
+		{name: "no extra query", extra: nil, want: "http://localhost/redirects?limit=100&offset=200"},
+		{name: "extra query", extra: url.Values{"host": {"example.com"}, "tag": {"a", "b"}}, want: "http://localhost/redirects?host=example.com&limit=100&offset=200&tag=a&tag=b"},
+		{name: "encodes values", extra: url.Values{"tag": {"a&b c"}}, want: "http://localhost/redirects?limit=100&offset=200&tag=a%26b+c"},
+		{name: "does not clobber pagination", extra: url.Values{"limit": {"5"}, "offset": {"7"}}, want: "http://localhost/redirects?limit=100&offset=200"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, listURL("http://localhost/redirects", tt.extra, 100, 200, ""))
+		})
+	}
+}
+
+func Test_listURL_Cursor(t *testing.T) {
+	got := listURL("http://localhost/redirects", url.Values{"offset": {"7"}, "cursor": {"x"}}, 100, 200, "abc")
+
+	assert.Equal(t, "http://localhost/redirects?cursor=abc&limit=100", got)
+}
+
+func makeCursorResponse(body any) *http.Response {
+	b, _ := json.Marshal(body)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBuffer(b)),
+	}
+}
+
+func TestClient_getProjectRedirects_CursorPagination(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeCursorResponse(map[string]any{
+		"items":      []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"}},
+		"total":      3,
+		"nextCursor": "c2",
+	}), nil)
+	mockHTTP.expect(makeCursorResponse(map[string]any{
+		"items":      []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/b", Target: "/2"}},
+		"total":      3,
+		"nextCursor": "c3",
+	}), nil)
+	mockHTTP.expect(makeCursorResponse(map[string]any{
+		"items": []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/c", Target: "/3"}},
+		"total": 3,
+	}), nil)
+
+	result, err := c.getProjectRedirects(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+	assert.Len(t, mockHTTP.calls, 3)
+	assert.Equal(t, "limit=100&offset=0", mockHTTP.calls[0].URL.RawQuery)
+	assert.Equal(t, "cursor=c2&limit=100", mockHTTP.calls[1].URL.RawQuery)
+	assert.Equal(t, "cursor=c3&limit=100", mockHTTP.calls[2].URL.RawQuery)
+}
+
+func TestClient_getProjectRedirects_CursorRepeatedStops(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	page := map[string]any{
+		"items":      []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"}},
+		"total":      1000,
+		"nextCursor": "same",
+	}
+	mockHTTP.expect(makeCursorResponse(page), nil)
+	mockHTTP.expect(makeCursorResponse(page), nil)
+
+	result, err := c.getProjectRedirects(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Len(t, mockHTTP.calls, 2)
+}
+
+func TestClient_getProjectRedirects_Query(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().RedirectQuery = url.Values{"host": {"example.com"}}
+
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{}, 0), nil)
+
+	_, err := c.getProjectRedirects(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "host=example.com&limit=100&offset=0", mockHTTP.calls[0].URL.RawQuery)
+	assert.Empty(t, c.config().RedirectQuery.Get("limit"))
+}
+
 func TestClient_getProjectRedirects_HTTPError(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
 
 	mockHTTP.expect(nil, errors.New("connection failed"))
 
-	result, err := c.getProjectRedirects()
+	result, err := c.getProjectRedirects(context.Background())
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -468,7 +892,7 @@ func TestClient_getProjectRedirects_Non200Status(t *testing.T) {
 
 	mockHTTP.expect(makeErrorResponse(http.StatusForbidden), nil)
 
-	result, err := c.getProjectRedirects()
+	result, err := c.getProjectRedirects(context.Background())
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -485,7 +909,7 @@ func TestClient_getProjectRedirects_InvalidJSON(t *testing.T) {
 
 	mockHTTP.expect(resp, nil)
 
-	result, err := c.getProjectRedirects()
+	result, err := c.getProjectRedirects(context.Background())
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -504,16 +928,16 @@ func TestClient_getProjectRedirects_NewRequestError(t *testing.T) {
 			HeaderAuthorizationName: "Authorization",
 			TokenJWT:                "token",
 		},
-		IntervalCheck: 5 * time.Minute,
+		IntervalCheck:          5 * time.Minute,
+		SkipCompatibilityCheck: true,
 	}
 
 	c := &client{
-		cfg:        cfg,
-		httpClient: mockHTTP,
-		clock:      fakeClock,
+		clock: fakeClock,
 	}
+	c.cfg.Store(cfg)
 
-	result, err := c.getProjectRedirects()
+	result, err := c.getProjectRedirects(context.Background())
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
@@ -529,7 +953,7 @@ func TestClient_getProjectPages_Success(t *testing.T) {
 
 	mockHTTP.expect(makePagesResponse(pages, 2), nil)
 
-	result, err := c.getProjectPages()
+	result, err := c.getProjectPages(context.Background())
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
@@ -570,108 +994,336 @@ func TestClient_getProjectPages_Pagination(t *testing.T) {
 	mockHTTP.expect(resp1, nil)
 	mockHTTP.expect(resp2, nil)
 
-	result, err := c.getProjectPages()
+	result, err := c.getProjectPages(context.Background())
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 101)
 }
 
-func TestClient_getProjectPages_HTTPError(t *testing.T) {
+func TestClient_FetchRedirects_Pagination(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
+	c.config().RedirectTransform = func(r *types.Redirect) (*types.Redirect, bool) { return nil, false }
 
-	mockHTTP.expect(nil, errors.New("connection failed"))
+	page1 := make([]types.Redirect, 100)
+	for i := range page1 {
+		page1[i] = types.Redirect{Type: types.RedirectTypeBasic, Source: fmt.Sprintf("/page1/%d", i), Target: "/target1"}
+	}
+	page2 := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/page1/0", Target: "/duplicate"}}
+	mockHTTP.expect(makeRedirectsResponse(page1, 101), nil)
+	mockHTTP.expect(makeRedirectsResponse(page2, 101), nil)
 
-	result, err := c.getProjectPages()
+	result, err := c.FetchRedirects(context.Background())
 
-	assert.Error(t, err)
-	assert.Nil(t, result)
+	assert.NoError(t, err)
+	assert.Equal(t, append(page1, page2...), result)
+	assert.Equal(t, "0", mockHTTP.calls[0].URL.Query().Get("offset"))
+	assert.Equal(t, "100", mockHTTP.calls[1].URL.Query().Get("offset"))
+	assert.Equal(t, 0, c.GetStateVersion())
+	assert.Empty(t, c.ListRedirects())
 }
 
-func TestClient_getProjectPages_Non200Status(t *testing.T) {
+func TestClient_FetchRedirects_Error(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
+	mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
 
-	mockHTTP.expect(makeErrorResponse(http.StatusForbidden), nil)
-
-	result, err := c.getProjectPages()
+	result, err := c.FetchRedirects(context.Background())
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "unexpected status code")
 }
 
-func TestClient_getProjectPages_InvalidJSON(t *testing.T) {
+func TestClient_FetchRedirects_Canceled(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
 
-	resp := &http.Response{
-		StatusCode: http.StatusOK,
-		Body:       io.NopCloser(bytes.NewBufferString("invalid json")),
-	}
-
-	mockHTTP.expect(resp, nil)
-
-	result, err := c.getProjectPages()
+	_, err := c.FetchRedirects(ctx)
 
-	assert.Error(t, err)
-	assert.Nil(t, result)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, mockHTTP.calls)
 }
 
-func TestClient_getProjectPages_NewRequestError(t *testing.T) {
-	mockHTTP := newMockHTTPClient()
-	fakeClock := clockwork.NewFakeClock()
-
-	cfg := &Config{
-		ManagerUrl:    "://invalid-url",
-		NamespaceCode: "ns",
-		ProjectCode:   "proj",
-		Http: &HTTPConfig{
-			Client:                  mockHTTP,
-			HeaderAuthorizationName: "Authorization",
-			TokenJWT:                "token",
-		},
-		IntervalCheck: 5 * time.Minute,
-	}
+func TestClient_FetchPages_Pagination(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
 
-	c := &client{
-		cfg:        cfg,
-		httpClient: mockHTTP,
-		clock:      fakeClock,
+	page1 := make([]types.Page, 100)
+	for i := range page1 {
+		page1[i] = types.Page{Type: types.PageTypeBasic, Path: fmt.Sprintf("/page1/%d", i), Content: "content"}
 	}
+	page2 := []types.Page{{Type: types.PageTypeBasic, Path: "/page2", Content: "content2"}}
+	mockHTTP.expect(makePagesResponse(page1, 101), nil)
+	mockHTTP.expect(makePagesResponse(page2, 101), nil)
 
-	result, err := c.getProjectPages()
+	result, err := c.FetchPages(context.Background())
 
-	assert.Error(t, err)
-	assert.Nil(t, result)
+	assert.NoError(t, err)
+	assert.Equal(t, append(page1, page2...), result)
+	assert.Equal(t, "100", mockHTTP.calls[1].URL.Query().Get("offset"))
+	assert.Empty(t, c.ListPages())
 }
 
-func TestClient_loadState_Success(t *testing.T) {
+func TestClient_FetchPages_Error(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
+	mockHTTP.expect(nil, errors.New("network error"))
 
-	redirects := []types.Redirect{
-		{Type: types.RedirectTypeBasic, Source: "/test", Target: "/target", Status: types.RedirectStatusMovedPermanent},
-	}
-	pages := []types.Page{
-		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
-	}
-
-	mockHTTP.expect(makeVersionResponse("5"), nil)
-	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
-	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+	_, err := c.FetchPages(context.Background())
 
-	err := c.loadState()
+	assert.ErrorContains(t, err, "network error")
+}
 
-	assert.NoError(t, err)
-	assert.NotNil(t, c.State.Load())
-	assert.Equal(t, 5, c.State.Load().(*State).ProjectVersion)
-	assert.NotNil(t, c.State.Load().(*State).RedirectMatcher)
-	assert.NotNil(t, c.State.Load().(*State).PageMatcher)
+func makeJSONResponse(v any) *http.Response {
+	body, _ := json.Marshal(v)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBuffer(body)),
+	}
 }
 
-func TestClient_loadState_VersionError(t *testing.T) {
+func TestClient_FetchRedirect(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	want := types.Redirect{Type: types.RedirectTypeBasic, Source: "/old page", Target: "/new"}
+	mockHTTP.expect(makeJSONResponse(want), nil)
+
+	redirect, err := c.FetchRedirect(context.Background(), "/old page")
+
+	require.NoError(t, err)
+	assert.Equal(t, want, *redirect)
+	require.Len(t, mockHTTP.calls, 1)
+	assert.Equal(t, "/old page", mockHTTP.calls[0].URL.Query().Get("source"))
+	assert.True(t, strings.HasSuffix(mockHTTP.calls[0].URL.Path, "/redirect"))
+}
+
+func TestClient_FetchRedirect_FromList(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"},
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+	}
+	tests := []struct {
+		name    string
+		source  string
+		wantErr error
+	}{
+		{name: "hit", source: "/old"},
+		{name: "not found", source: "/missing", wantErr: ErrRedirectNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, _ := newTestClient()
+			mockHTTP.expect(makeErrorResponse(http.StatusNotFound), nil)
+			mockHTTP.expect(makeRedirectsResponse(redirects, 2), nil)
+
+			redirect, err := c.FetchRedirect(context.Background(), tt.source)
+
+			assert.Len(t, mockHTTP.calls, 2)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, redirect)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, redirects[1], *redirect)
+		})
+	}
+}
+
+func TestClient_FetchRedirect_Error(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
+
+	redirect, err := c.FetchRedirect(context.Background(), "/old")
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Nil(t, redirect)
+	assert.Len(t, mockHTTP.calls, 1)
+}
+
+func TestClient_FetchRedirect_DuringReload(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	want := types.Redirect{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}
+	mockHTTP.expect(makeJSONResponse(want), nil)
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	redirect, err := c.FetchRedirect(context.Background(), "/old")
+
+	require.NoError(t, err)
+	assert.Equal(t, want, *redirect)
+}
+
+func TestClient_FetchPage(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	want := types.Page{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *"}
+	mockHTTP.expect(makeJSONResponse(want), nil)
+
+	page, err := c.FetchPage(context.Background(), "/robots.txt")
+
+	require.NoError(t, err)
+	assert.Equal(t, want, *page)
+	assert.Equal(t, "/robots.txt", mockHTTP.calls[0].URL.Query().Get("path"))
+}
+
+func TestClient_FetchPage_FromList(t *testing.T) {
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *"}}
+	c, mockHTTP, _ := newTestClient()
+	mockHTTP.expect(makeErrorResponse(http.StatusMethodNotAllowed), nil)
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+	mockHTTP.expect(makeErrorResponse(http.StatusNotFound), nil)
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+
+	page, err := c.FetchPage(context.Background(), "/robots.txt")
+	require.NoError(t, err)
+	assert.Equal(t, pages[0], *page)
+
+	page, err = c.FetchPage(context.Background(), "/ads.txt")
+	assert.ErrorIs(t, err, ErrPageNotFound)
+	assert.Nil(t, page)
+	assert.Len(t, mockHTTP.calls, 4)
+}
+
+func TestClient_getProjectPages_BodyTooLarge(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.MaxResponseBytes = 16
+
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Nil(t, result)
+}
+
+func TestClient_getProjectPages_Query(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().PageQuery = url.Values{"host": {"example.com"}}
+
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
+
+	_, err := c.getProjectPages(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "host=example.com&limit=100&offset=0", mockHTTP.calls[0].URL.RawQuery)
+}
+
+func TestClient_getProjectPages_CursorPagination(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeCursorResponse(map[string]any{
+		"items":      []types.Page{{Type: types.PageTypeBasic, Path: "/a", Content: "a"}},
+		"total":      2,
+		"nextCursor": "next",
+	}), nil)
+	mockHTTP.expect(makeCursorResponse(map[string]any{
+		"items": []types.Page{{Type: types.PageTypeBasic, Path: "/b", Content: "b"}},
+		"total": 2,
+	}), nil)
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "cursor=next&limit=100", mockHTTP.calls[1].URL.RawQuery)
+}
+
+func TestClient_getProjectPages_HTTPError(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(nil, errors.New("connection failed"))
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestClient_getProjectPages_Non200Status(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeErrorResponse(http.StatusForbidden), nil)
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "unexpected status code")
+}
+
+func TestClient_getProjectPages_InvalidJSON(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString("invalid json")),
+	}
+
+	mockHTTP.expect(resp, nil)
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestClient_getProjectPages_NewRequestError(t *testing.T) {
+	mockHTTP := newMockHTTPClient()
+	fakeClock := clockwork.NewFakeClock()
+
+	cfg := &Config{
+		ManagerUrl:    "://invalid-url",
+		NamespaceCode: "ns",
+		ProjectCode:   "proj",
+		Http: &HTTPConfig{
+			Client:                  mockHTTP,
+			HeaderAuthorizationName: "Authorization",
+			TokenJWT:                "token",
+		},
+		IntervalCheck:          5 * time.Minute,
+		SkipCompatibilityCheck: true,
+	}
+
+	c := &client{
+		clock: fakeClock,
+	}
+	c.cfg.Store(cfg)
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestClient_loadState_Success(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/test", Target: "/target", Status: types.RedirectStatusMovedPermanent},
+	}
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}
+
+	mockHTTP.expect(makeVersionResponse("5"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotNil(t, c.State.Load())
+	assert.Equal(t, 5, c.State.Load().(*State).ProjectVersion)
+	assert.NotNil(t, c.State.Load().(*State).RedirectMatcher)
+	assert.NotNil(t, c.State.Load().(*State).PageMatcher)
+}
+
+func TestClient_loadState_VersionError(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
 
 	mockHTTP.expect(nil, errors.New("version error"))
 
-	err := c.loadState()
+	err := c.loadState(context.Background())
 
 	assert.Error(t, err)
 }
@@ -682,7 +1334,7 @@ func TestClient_loadState_RedirectsError(t *testing.T) {
 	mockHTTP.expect(makeVersionResponse("1"), nil)
 	mockHTTP.expect(nil, errors.New("redirects error"))
 
-	err := c.loadState()
+	err := c.loadState(context.Background())
 
 	assert.Error(t, err)
 }
@@ -697,7 +1349,7 @@ func TestClient_loadState_InvalidRedirectRegex(t *testing.T) {
 	mockHTTP.expect(makeVersionResponse("1"), nil)
 	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
 
-	err := c.loadState()
+	err := c.loadState(context.Background())
 
 	assert.Error(t, err)
 }
@@ -713,136 +1365,1262 @@ func TestClient_loadState_PagesError(t *testing.T) {
 	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
 	mockHTTP.expect(nil, errors.New("pages error"))
 
-	err := c.loadState()
+	err := c.loadState(context.Background())
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "pages error")
 }
 
-func TestClient_Reload_NoVersionChange(t *testing.T) {
+func TestClient_loadState_PagesOptional(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
-	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+	c.config().PagesOptional = true
 
-	mockHTTP.expect(makeVersionResponse("1"), nil)
-	mockHTTP.expect(makeAgentResponse(), nil)
+	var logs bytes.Buffer
+	c.config().Logger = slog.New(slog.NewTextHandler(&logs, nil))
 
-	err := c.Reload()
+	previousPage := &types.Page{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain}
+	previousPages := types.NewPageTreeMatcher()
+	previousPages.Insert(previousPage)
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher(), PageMatcher: previousPages})
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/test", Target: "/target", Status: types.RedirectStatusMovedPermanent},
+	}
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(nil, errors.New("pages error"))
+
+	err := c.loadState(context.Background())
 
 	assert.NoError(t, err)
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
-	assert.Len(t, mockHTTP.calls, 2)
+	assert.Equal(t, 2, c.GetStateVersion())
+	redirect, target := c.RedirectMatch("example.com", "/test")
+	assert.NotNil(t, redirect)
+	assert.Equal(t, "/target", target)
+	assert.Equal(t, previousPage, c.PageMatch("example.com", "/robots.txt"))
+	assert.Contains(t, logs.String(), "pages error")
 }
 
-func TestClient_Reload_VersionChange(t *testing.T) {
+func TestClient_loadState_PagesOptional_NoPreviousPages(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
-	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+	c.config().PagesOptional = true
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{}, 0), nil)
+	mockHTTP.expect(nil, errors.New("pages error"))
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.GetStateVersion())
+	assert.Nil(t, c.PageMatch("example.com", "/robots.txt"))
+}
+
+type traceIDKey struct{}
+
+func TestClient_LogFields(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().PagesOptional = true
+	c.config().WarnDuplicates = true
+	var logs bytes.Buffer
+	c.config().Logger = slog.New(slog.NewTextHandler(&logs, nil)).With("component", "flecto")
+	c.config().LogFields = func(ctx context.Context) []any {
+		if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+			return []any{"trace_id", id}
+		}
+		return nil
+	}
 
 	redirects := []types.Redirect{
-		{Type: types.RedirectTypeBasic, Source: "/new", Target: "/target", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeBasic, Source: "/test", Target: "/a"},
+		{Type: types.RedirectTypeBasic, Source: "/test", Target: "/b"},
 	}
-	pages := []types.Page{
-		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 2), nil)
+	mockHTTP.expect(nil, errors.New("pages error"))
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	_, err := c.ReloadDetailed(context.WithValue(context.Background(), traceIDKey{}, "abc123"))
+
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "dropping duplicate redirect")
+	assert.Contains(t, lines[1], "failed to fetch pages")
+	for _, line := range lines {
+		assert.Contains(t, line, "component=flecto")
+		assert.Contains(t, line, "trace_id=abc123")
 	}
 
-	mockHTTP.expect(makeVersionResponse("2"), nil)
+	logs.Reset()
+	assert.NoError(t, c.LoadFromData(3, redirects, nil))
+	assert.Contains(t, logs.String(), "dropping duplicate redirect")
+	assert.NotContains(t, logs.String(), "trace_id")
+}
+
+func TestClient_loadState_RedirectsOnly(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	loadPages := false
+	c.config().LoadPages = &loadPages
+
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/test", Target: "/target"}}
 	mockHTTP.expect(makeVersionResponse("2"), nil)
 	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, mockHTTP.calls, 2)
+	_, target := c.RedirectMatch("example.com", "/test")
+	assert.Equal(t, "/target", target)
+	assert.Nil(t, c.PageMatch("example.com", "/robots.txt"))
+	assert.Empty(t, c.ListPages())
+}
+
+func TestClient_loadState_PagesOnly(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	loadRedirects := false
+	c.config().LoadRedirects = &loadRedirects
+
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *"}}
+	mockHTTP.expect(makeVersionResponse("2"), nil)
 	mockHTTP.expect(makePagesResponse(pages, 1), nil)
-	mockHTTP.expect(makeAgentResponse(), nil)
 
-	err := c.Reload()
+	err := c.loadState(context.Background())
 
 	assert.NoError(t, err)
-	assert.Equal(t, 2, c.State.Load().(*State).ProjectVersion)
+	assert.Len(t, mockHTTP.calls, 2)
+	assert.True(t, strings.HasSuffix(mockHTTP.calls[1].URL.Path, "/pages"))
+	redirect, _ := c.RedirectMatch("example.com", "/test")
+	assert.Nil(t, redirect)
+	assert.Equal(t, "User-agent: *", c.PageMatch("example.com", "/robots.txt").Content)
+	assert.Empty(t, c.ListRedirects())
 }
 
-func TestClient_Reload_VersionError(t *testing.T) {
+func TestClient_loadState_CombinedStateEndpoint(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
-	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+	c.config().UseCombinedStateEndpoint = true
 
-	mockHTTP.expect(nil, errors.New("network error"))
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/test", Target: "/target"}}
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *"}}
+	mockHTTP.expect(makeStateResponse("3", redirects, pages), nil)
 
-	err := c.Reload()
+	err := c.loadState(context.Background())
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "network error")
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+	assert.NoError(t, err)
+	require.Len(t, mockHTTP.calls, 1)
+	assert.Equal(t, c.config().GetUrlApiState(), mockHTTP.calls[0].URL.String())
+	assert.Equal(t, 3, c.load().ProjectVersion)
+	_, target := c.RedirectMatch("example.com", "/test")
+	assert.Equal(t, "/target", target)
+	assert.Equal(t, "User-agent: *", c.PageMatch("example.com", "/robots.txt").Content)
 }
 
-func TestClient_Reload_LoadStateError(t *testing.T) {
+func TestClient_loadState_CombinedStateEndpointFallback(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusMethodNotAllowed} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			c, mockHTTP, _ := newTestClient()
+			c.config().UseCombinedStateEndpoint = true
+
+			redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/test", Target: "/target"}}
+			mockHTTP.expect(makeErrorResponse(status), nil)
+			mockHTTP.expect(makeVersionResponse("2"), nil)
+			mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+			mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+			err := c.loadState(context.Background())
+
+			assert.NoError(t, err)
+			assert.Len(t, mockHTTP.calls, 4)
+			assert.Equal(t, 2, c.load().ProjectVersion)
+			_, target := c.RedirectMatch("example.com", "/test")
+			assert.Equal(t, "/target", target)
+
+			// the missing endpoint is not asked again
+			mockHTTP.expect(makeVersionResponse("3"), nil)
+			mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+			mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+			assert.NoError(t, c.loadState(context.Background()))
+			assert.Len(t, mockHTTP.calls, 7)
+			assert.Equal(t, c.config().GetUrlApiVersion(), mockHTTP.calls[4].URL.String())
+			assert.Equal(t, 3, c.load().ProjectVersion)
+		})
+	}
+}
+
+func TestClient_loadState_CombinedStateEndpointError(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
-	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+	c.config().UseCombinedStateEndpoint = true
+	previous := c.load()
 
-	mockHTTP.expect(makeVersionResponse("2"), nil)
-	mockHTTP.expect(nil, errors.New("failed to load version"))
-	mockHTTP.expect(makeAgentResponse(), nil)
+	mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
+
+	err := c.loadState(context.Background())
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Len(t, mockHTTP.calls, 1)
+	assert.Same(t, previous, c.load())
+	assert.False(t, c.stateUnsupported)
+}
+
+func TestClient_loadState_CombinedStateEndpointEmptyVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().UseCombinedStateEndpoint = true
+
+	mockHTTP.expect(makeStateResponse(" ", nil, nil), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.ErrorIs(t, err, ErrEmptyVersion)
+}
+
+func TestClient_Reload_VerifyState(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	var verified *State
+	c.config().VerifyState = func(state *State) error {
+		verified = state
+		return nil
+	}
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	assert.NoError(t, c.Reload())
+	assert.Same(t, c.load(), verified)
+	assert.Equal(t, 4, c.GetStateVersion())
+}
+
+func TestClient_Reload_VerifyStateFails(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/checkout", Target: "/cart"}}, nil))
+	previous := c.load()
+	c.config().VerifyState = func(state *State) error {
+		if redirect, _ := state.RedirectMatcher.Match("example.com", "/checkout"); redirect == nil {
+			return errors.New("missing /checkout redirect")
+		}
+		return nil
+	}
+	installed := 0
+	c.config().OnReload = func(StateDiff) { installed++ }
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
 
 	err := c.Reload()
 
-	assert.Error(t, err)
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+	assert.ErrorIs(t, err, ErrStateVerification)
+	assert.EqualError(t, err, "state verification failed: missing /checkout redirect")
+	assert.Same(t, previous, c.load())
+	assert.Zero(t, installed)
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	assert.Len(t, posts, 1)
+	assert.Equal(t, types.AgentStatusError, posts[0].Status)
+	assert.Equal(t, "state verification failed: missing /checkout redirect", posts[0].Error)
+}
+
+func TestClient_LoadFromData_VerifyStateFails(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().VerifyState = func(state *State) error {
+		if len(state.Redirects) == 0 {
+			return errors.New("no redirects")
+		}
+		return nil
+	}
+
+	err := c.LoadFromData(1, nil, nil)
+
+	assert.ErrorIs(t, err, ErrStateVerification)
+	assert.Equal(t, 0, c.GetStateVersion())
+}
+
+// stallingHTTPClient advances the fake clock then blocks the given call until its context is done.
+type stallingHTTPClient struct {
+	next    HTTPClient
+	clock   clockwork.FakeClock
+	stallAt int
+	advance time.Duration
+	calls   int
+}
+
+func (s *stallingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	s.calls++
+	if s.calls == s.stallAt {
+		s.clock.Advance(s.advance)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return s.next.Do(req)
+}
+
+func TestClient_loadState_MaxReloadDurationExceeded(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().MaxReloadDuration = 10 * time.Second
+	c.config().Http.Client = &stallingHTTPClient{next: mockHTTP, clock: fakeClock, stallAt: 3, advance: 11 * time.Second}
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	page1 := make([]types.Redirect, 100)
+	for i := 0; i < 100; i++ {
+		page1[i] = types.Redirect{Type: types.RedirectTypeBasic, Source: "/page1", Target: "/target1"}
+	}
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(page1, 150), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.ErrorIs(t, err, ErrReloadBudgetExceeded)
+	assert.Equal(t, 1, c.GetStateVersion())
+}
+
+func TestClient_loadState_MaxReloadDurationNotExceeded(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().MaxReloadDuration = 10 * time.Second
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{}, 0), nil)
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.GetStateVersion())
+}
+
+func TestClient_loadState_ContextCanceled(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+
+	err := c.loadState(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, c.GetStateVersion())
+}
+
+func TestClient_loadState_RedirectTransform(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().RedirectTransform = func(r *types.Redirect) (*types.Redirect, bool) {
+		if r.Source == "/drop" {
+			return nil, false
+		}
+		rewritten := *r
+		rewritten.Target = strings.Replace(r.Target, "staging.example.com", "www.example.com", 1)
+		return &rewritten, true
+	}
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/keep", Target: "https://staging.example.com/new", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeBasic, Source: "/drop", Target: "/target", Status: types.RedirectStatusMovedPermanent},
+	}
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 2), nil)
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	redirect, target := c.RedirectMatch("example.com", "/keep")
+	assert.NotNil(t, redirect)
+	assert.Equal(t, "https://www.example.com/new", target)
+	redirect, _ = c.RedirectMatch("example.com", "/drop")
+	assert.Nil(t, redirect)
+}
+
+func TestClient_loadState_PageTransform(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().PageTransform = func(p *types.Page) (*types.Page, bool) {
+		if p.Path == "/drop.txt" {
+			return nil, false
+		}
+		rewritten := *p
+		rewritten.Content = strings.ToUpper(p.Content)
+		return &rewritten, true
+	}
+
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "user-agent: *", ContentType: types.PageContentTypeTextPlain},
+		{Type: types.PageTypeBasic, Path: "/drop.txt", Content: "drop", ContentType: types.PageContentTypeTextPlain},
+	}
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{}, 0), nil)
+	mockHTTP.expect(makePagesResponse(pages, 2), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	page := c.PageMatch("example.com", "/robots.txt")
+	assert.NotNil(t, page)
+	assert.Equal(t, "USER-AGENT: *", page.Content)
+	assert.Nil(t, c.PageMatch("example.com", "/drop.txt"))
+}
+
+func TestClient_ReloadDetailed_VersionChanged(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().Http.Client = &slowHTTPClient{next: mockHTTP, clock: fakeClock, latency: 10 * time.Millisecond}
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"},
+		{Type: types.RedirectTypeBasic, Source: "/b", Target: "/2"},
+	}
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "x"}}
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 2), nil)
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	result, err := c.ReloadDetailed(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, ReloadResult{
+		VersionChanged: true,
+		OldVersion:     1,
+		NewVersion:     2,
+		Duration:       50 * time.Millisecond,
+		RedirectCount:  2,
+		PageCount:      1,
+	}, result)
+}
+
+func TestClient_ReloadDetailed_VersionUnchanged(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(3, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"}}, nil))
+
+	mockHTTP.expect(makeVersionResponse("3"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	result, err := c.ReloadDetailed(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, result.VersionChanged)
+	assert.Equal(t, 3, result.OldVersion)
+	assert.Equal(t, 3, result.NewVersion)
+	assert.Equal(t, 1, result.RedirectCount)
+	assert.Equal(t, 0, result.PageCount)
+}
+
+func TestClient_ReloadDetailed_Error(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(nil, errors.New("network error"))
+
+	result, err := c.ReloadDetailed(context.Background())
+
+	assert.Error(t, err)
+	assert.False(t, result.VersionChanged)
+	assert.Equal(t, 1, result.NewVersion)
+}
+
+func TestClient_ReloadDetailed_Skipped(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	result, err := c.ReloadDetailed(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, result.Skipped)
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_ReloadAsync(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"}}
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	results := c.ReloadAsync()
+
+	result, ok := <-results
+	assert.True(t, ok)
+	assert.NoError(t, result.Err)
+	assert.False(t, result.Skipped)
+	assert.True(t, result.VersionChanged)
+	assert.Equal(t, 2, result.NewVersion)
+	assert.Equal(t, 1, result.RedirectCount)
+	_, ok = <-results
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.GetStateVersion())
+	assert.True(t, c.reloadMu.TryLock())
+}
+
+func TestClient_ReloadAsync_Error(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(nil, errors.New("network error"))
+
+	result := <-c.ReloadAsync()
+
+	assert.ErrorContains(t, result.Err, "network error")
+	assert.Equal(t, 1, result.NewVersion)
+}
+
+func TestClient_ReloadAsync_Skipped(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	results := c.ReloadAsync()
+
+	assert.Len(t, results, 1)
+	result, ok := <-results
+	assert.True(t, ok)
+	assert.True(t, result.Skipped)
+	assert.NoError(t, result.Err)
+	_, ok = <-results
+	assert.False(t, ok)
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_Warmup(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}
+	mockHTTP.expect(makeVersionResponse("3"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+	err := c.Warmup(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, c.GetStateVersion())
+	_, target := c.RedirectMatch("example.com", "/old")
+	assert.Equal(t, "/new", target)
+	assert.Len(t, mockHTTP.calls, 3)
+	for _, call := range mockHTTP.calls {
+		assert.Equal(t, http.MethodGet, call.Method)
+		assert.NotContains(t, call.URL.Path, "/agents")
+	}
+	assert.Equal(t, ReloadStats{}, c.Stats())
+}
+
+func TestClient_Warmup_Error(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
+
+	err := c.Warmup(context.Background())
+
+	assert.Error(t, err)
+	assert.Len(t, mockHTTP.calls, 1)
+	assert.False(t, c.StaleSince().IsZero())
+}
+
+func TestClient_LoadFromData(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1", Status: types.RedirectStatusFound},
+	}
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}
+
+	err := c.LoadFromData(7, redirects, pages)
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockHTTP.calls)
+	assert.Equal(t, 7, c.GetStateVersion())
+	_, target := c.RedirectMatch("example.com", "/blog/post")
+	assert.Equal(t, "/news/post", target)
+	assert.NotNil(t, c.PageMatch("example.com", "/robots.txt"))
+
+	// the loaded state does not alias the caller slices
+	redirects[0].Target = "/mutated"
+	_, target = c.RedirectMatch("example.com", "/old")
+	assert.Equal(t, "/new", target)
+}
+
+func TestClient_LoadFromData_InvalidRegex(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeRegex, Source: "[invalid(regex", Target: "/target"},
+	}
+
+	err := c.LoadFromData(2, redirects, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, c.GetStateVersion())
+}
+
+func TestClient_Reload_NoVersionChange(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+	assert.Len(t, mockHTTP.calls, 2)
+}
+
+func TestClient_Reload_VersionChange(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/new", Target: "/target", Status: types.RedirectStatusMovedPermanent},
+	}
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Reload_SuppressUnchangedHits(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().SuppressUnchangedHits = true
+	c.config().HitInterval = 30 * time.Minute
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	// first unchanged reload reports a hit
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Len(t, mockHTTP.calls, 2)
+
+	// second unchanged reload within HitInterval is suppressed
+	fakeClock.Advance(5 * time.Minute)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	assert.NoError(t, c.Reload())
+	assert.Len(t, mockHTTP.calls, 3)
+
+	// once HitInterval elapsed a hit is sent again
+	fakeClock.Advance(30 * time.Minute)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Len(t, mockHTTP.calls, 5)
+	assert.Equal(t, http.MethodPatch, mockHTTP.calls[4].Method)
+}
+
+func TestClient_Reload_SuppressUnchangedHits_ReportsAfterChange(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().SuppressUnchangedHits = true
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	assert.NoError(t, c.Reload())
+	assert.Len(t, mockHTTP.calls, 3)
+
+	// version change always reports a status
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{}, 0), nil)
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Len(t, mockHTTP.calls, 8)
+	assert.Equal(t, http.MethodPost, mockHTTP.calls[7].Method)
+
+	// the new version was just reported, so the next unchanged reload is suppressed
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	assert.NoError(t, c.Reload())
+	assert.Len(t, mockHTTP.calls, 9)
+}
+
+func TestClient_Reload_HitNotSuppressedByDefault(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	for i := 0; i < 2; i++ {
+		mockHTTP.expect(makeVersionResponse("1"), nil)
+		mockHTTP.expect(makeAgentResponse(), nil)
+		assert.NoError(t, c.Reload())
+	}
+
+	assert.Len(t, mockHTTP.calls, 4)
+}
+
+func TestClient_Reload_VersionError(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(nil, errors.New("network error"))
+
+	err := c.Reload()
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "network error")
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Reload_LoadStateError(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(nil, errors.New("failed to load version"))
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Reload_TryLockFails(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	err := c.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Start_ContextCancellation(t *testing.T) {
+	c, _, fakeClock := newTestClient()
+
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Start did not exit after context cancellation")
+	}
+
+	_ = fakeClock
+}
+
+func TestClient_Start_VersionCheckNoChange(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Add multiple responses for version check (may be called multiple times)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.Advance(5 * time.Minute)
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Start did not exit")
+	}
+
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Start_VersionChangeTriggersReload(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/new", Target: "/target", Status: types.RedirectStatusMovedPermanent},
+	}
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Start did not exit")
+	}
+
+	assert.Equal(t, 2, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Start_LoadStateErrorDuringReload(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(nil, errors.New("failed to load redirects"))
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Start did not exit")
+	}
+
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Start_VersionCheckError(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockHTTP.expect(nil, errors.New("network error"))
+
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Start did not exit")
+	}
+
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Start_TryLockFails(t *testing.T) {
+	c, _, fakeClock := newTestClient()
+
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Lock the mutex before starting - TryLock will fail
+	c.reloadMu.Lock()
+
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	time.Sleep(50 * time.Millisecond)
+
+	// Unlock and cancel
+	c.reloadMu.Unlock()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Start did not exit")
+	}
+
+	// Version should remain unchanged since TryLock failed
+	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+}
+
+func TestClient_Start_Paused(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	c.Pause()
+	assert.True(t, c.IsPaused())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		fakeClock.BlockUntil(1)
+		fakeClock.Advance(5 * time.Minute)
+	}
+	fakeClock.BlockUntil(1)
+	assert.Empty(t, mockHTTP.calls)
+
+	c.Resume()
+	assert.False(t, c.IsPaused())
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 2)
+
+	cancel()
+	<-done
+}
+
+func TestClient_Start_ReloadOnStart(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().SkipReloadOnStart = false
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	assert.NoError(t, c.WaitReady(waitCtx))
+	cancel()
+	<-done
+
+	assert.Len(t, mockHTTP.calls, 5)
+	assert.Equal(t, 4, c.GetStateVersion())
+}
+
+func TestClient_Start_ZeroIntervalCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		startup  bool
+		attempts uint64
+		calls    int
+	}{
+		{name: "triggers only", attempts: 1, calls: 5},
+		{name: "startup then triggers", startup: true, attempts: 2, calls: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, fakeClock := newTestClient()
+			c.config().IntervalCheck = 0
+			c.config().SkipReloadOnStart = !tt.startup
+			expectLoad(mockHTTP, testLoad{version: "4"})
+			if tt.startup {
+				mockHTTP.expect(makeVersionResponse("4"), nil)
+				mockHTTP.expect(makeAgentResponse(), nil)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.Start(ctx)
+				close(done)
+			}()
+			if tt.startup {
+				assert.Eventually(t, func() bool { return c.GetStateVersion() == 4 }, time.Second, time.Millisecond)
+			}
+			fakeClock.Advance(time.Hour)
+			c.TriggerReload()
+			assert.Eventually(t, func() bool { return c.Stats().Attempted == tt.attempts }, time.Second, time.Millisecond)
+			fakeClock.Advance(24 * time.Hour)
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+			<-done
+
+			assert.Equal(t, tt.attempts, c.Stats().Attempted)
+			assert.Len(t, mockHTTP.calls, tt.calls)
+		})
+	}
+}
+
+func TestClient_Start_ZeroIntervalCheckFailureHoldsTriggers(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().IntervalCheck = 0
+	mockHTTP.expect(nil, errors.New("connection refused"))
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+	c.TriggerReload()
+	assert.Eventually(t, func() bool { return c.Stats().Failed == 1 }, time.Second, time.Millisecond)
+	c.TriggerReload()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, uint64(1), c.Stats().Attempted)
+
+	fakeClock.Advance(2 * MinAllowedIntervalCheck)
+	c.TriggerReload()
+	assert.Eventually(t, func() bool { return c.GetStateVersion() == 4 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, uint64(2), c.Stats().Attempted)
+}
+
+func TestClient_Start_NoReloadOnStart(t *testing.T) {
+	tests := []struct {
+		name string
+		init bool
+		skip bool
+	}{
+		{name: "after Init", init: true},
+		{name: "SkipReloadOnStart", skip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, fakeClock := newTestClient()
+			c.config().SkipReloadOnStart = tt.skip
+			if tt.init {
+				expectLoad(mockHTTP, testLoad{version: "4"})
+				require.NoError(t, c.Init())
+			}
+			calls := len(mockHTTP.calls)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.Start(ctx)
+				close(done)
+			}()
+
+			fakeClock.BlockUntil(1)
+			cancel()
+			<-done
+
+			assert.Len(t, mockHTTP.calls, calls)
+		})
+	}
 }
 
-func TestClient_Reload_TryLockFails(t *testing.T) {
-	c, _, _ := newTestClient()
+func TestClient_HTTPHooks(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
 	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
 
-	c.reloadMu.Lock()
-	defer c.reloadMu.Unlock()
+	var requests, responses []string
+	var requestBodies, responseBodies []string
+	c.config().Http.OnRequest = func(req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Body != nil {
+			b, _ := io.ReadAll(req.Body)
+			requestBodies = append(requestBodies, string(b))
+		}
+	}
+	c.config().Http.OnResponse = func(resp *http.Response, err error) {
+		assert.NoError(t, err)
+		responses = append(responses, resp.Request.Method+" "+resp.Request.URL.Path)
+		b, _ := io.ReadAll(resp.Body)
+		responseBodies = append(responseBodies, string(b))
+	}
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}, 1), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
 
 	err := c.Reload()
 
 	assert.NoError(t, err)
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
-}
+	base := "/api/namespace/test-ns/project/test-proj"
+	want := []string{
+		"GET " + base + "/version",
+		"GET " + base + "/version",
+		"GET " + base + "/redirects",
+		"GET " + base + "/pages",
+		"POST " + base + "/agents",
+	}
+	assert.Equal(t, want, requests)
+	assert.Equal(t, want, responses)
+	assert.Equal(t, "2", responseBodies[0])
+	assert.Contains(t, responseBodies[2], `"/old"`)
+	// the hooks read the bodies, the client still got them in full
+	assert.Equal(t, 2, c.GetStateVersion())
+	_, target := c.RedirectMatch("example.com", "/old")
+	assert.Equal(t, "/new", target)
+	sent, _ := io.ReadAll(mockHTTP.calls[4].Body)
+	assert.Contains(t, requestBodies[len(requestBodies)-1], `"status":"success"`)
+	assert.Equal(t, requestBodies[len(requestBodies)-1], string(sent))
+}
+
+func TestClient_HTTPHooks_Error(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	var gotErr error
+	var gotResp *http.Response
+	c.config().Http.OnResponse = func(resp *http.Response, err error) {
+		gotResp, gotErr = resp, err
+	}
 
-func TestClient_Start_ContextCancellation(t *testing.T) {
-	c, _, fakeClock := newTestClient()
+	mockHTTP.expect(nil, errors.New("connection refused"))
 
-	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+	_, err := c.getProjectVersion(context.Background())
 
-	ctx, cancel := context.WithCancel(context.Background())
+	assert.Error(t, err)
+	assert.Nil(t, gotResp)
+	assert.EqualError(t, gotErr, "connection refused")
+}
 
-	done := make(chan struct{})
-	go func() {
-		c.Start(ctx)
-		close(done)
-	}()
+func TestClient_HTTPHooks_BodyLimit(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.MaxResponseBytes = 16
+	c.config().Http.OnResponse = func(resp *http.Response, err error) {
+		_, _ = io.ReadAll(resp.Body)
+	}
 
-	cancel()
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old1", Target: "/new1"}}
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
 
-	select {
-	case <-done:
-	case <-time.After(1 * time.Second):
-		t.Fatal("Start did not exit after context cancellation")
+	_, err := c.getProjectRedirects(context.Background())
+
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+}
+
+func Test_backoffInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		max      time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{name: "no failure", interval: 5 * time.Minute, max: time.Hour, failures: 0, want: 5 * time.Minute},
+		{name: "one failure", interval: 5 * time.Minute, max: time.Hour, failures: 1, want: 10 * time.Minute},
+		{name: "three failures", interval: 5 * time.Minute, max: time.Hour, failures: 3, want: 40 * time.Minute},
+		{name: "capped", interval: 5 * time.Minute, max: time.Hour, failures: 10, want: time.Hour},
+		{name: "disabled", interval: 5 * time.Minute, max: 0, failures: 3, want: 5 * time.Minute},
+		{name: "zero interval, no failure", interval: 0, max: time.Hour, failures: 0, want: 0},
+		{name: "zero interval, one failure", interval: 0, max: time.Hour, failures: 1, want: 2 * MinAllowedIntervalCheck},
+		{name: "zero interval, three failures", interval: 0, max: time.Hour, failures: 3, want: 8 * MinAllowedIntervalCheck},
+		{name: "zero interval, disabled", interval: 0, max: 0, failures: 3, want: MinAllowedIntervalCheck},
 	}
 
-	_ = fakeClock
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, backoffInterval(tt.interval, tt.max, tt.failures))
+		})
+	}
 }
 
-func TestClient_Start_VersionCheckNoChange(t *testing.T) {
+func TestClient_Start_Backoff(t *testing.T) {
 	c, mockHTTP, fakeClock := newTestClient()
-
+	c.config().MaxIntervalCheck = 20 * time.Minute
 	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Add multiple responses for version check (may be called multiple times)
-	mockHTTP.expect(makeVersionResponse("1"), nil)
-	mockHTTP.expect(makeAgentResponse(), nil)
+	for i := 0; i < 3; i++ {
+		mockHTTP.expect(nil, errors.New("network error"))
+	}
 	mockHTTP.expect(makeVersionResponse("1"), nil)
 	mockHTTP.expect(makeAgentResponse(), nil)
 	mockHTTP.expect(makeVersionResponse("1"), nil)
 	mockHTTP.expect(makeAgentResponse(), nil)
 
+	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan struct{})
 	go func() {
 		c.Start(ctx)
 		close(done)
 	}()
 
+	// first failure after IntervalCheck, next check in 10m
+	fakeClock.BlockUntil(1)
 	fakeClock.Advance(5 * time.Minute)
-	time.Sleep(50 * time.Millisecond)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 1)
+
+	fakeClock.Advance(9 * time.Minute)
+	assert.Len(t, mockHTTP.calls, 1)
+	// second failure, next check in 20m
+	fakeClock.Advance(1 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 2)
+
+	// third failure, interval capped at MaxIntervalCheck
+	fakeClock.Advance(20 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 3)
+
+	// success resets to IntervalCheck
+	fakeClock.Advance(20 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 5)
+
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 7)
 
 	cancel()
 
@@ -851,11 +2629,19 @@ func TestClient_Start_VersionCheckNoChange(t *testing.T) {
 	case <-time.After(1 * time.Second):
 		t.Fatal("Start did not exit")
 	}
+}
 
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+func TestClient_TriggerReload_Coalesces(t *testing.T) {
+	c, _, _ := newTestClient()
+
+	c.TriggerReload()
+	c.TriggerReload()
+	c.TriggerReload()
+
+	assert.Len(t, c.trigger, 1)
 }
 
-func TestClient_Start_VersionChangeTriggersReload(t *testing.T) {
+func TestClient_Start_TriggerReload(t *testing.T) {
 	c, mockHTTP, fakeClock := newTestClient()
 
 	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
@@ -865,14 +2651,11 @@ func TestClient_Start_VersionChangeTriggersReload(t *testing.T) {
 	redirects := []types.Redirect{
 		{Type: types.RedirectTypeBasic, Source: "/new", Target: "/target", Status: types.RedirectStatusMovedPermanent},
 	}
-	pages := []types.Page{
-		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
-	}
 
 	mockHTTP.expect(makeVersionResponse("2"), nil)
 	mockHTTP.expect(makeVersionResponse("2"), nil)
 	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
-	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
 	mockHTTP.expect(makeAgentResponse(), nil)
 
 	done := make(chan struct{})
@@ -882,8 +2665,8 @@ func TestClient_Start_VersionChangeTriggersReload(t *testing.T) {
 	}()
 
 	fakeClock.BlockUntil(1)
-	fakeClock.Advance(5 * time.Minute)
-	time.Sleep(100 * time.Millisecond)
+	c.TriggerReload()
+	assert.Eventually(t, func() bool { return c.GetStateVersion() == 2 }, time.Second, 10*time.Millisecond)
 
 	cancel()
 
@@ -893,20 +2676,17 @@ func TestClient_Start_VersionChangeTriggersReload(t *testing.T) {
 		t.Fatal("Start did not exit")
 	}
 
-	assert.Equal(t, 2, c.State.Load().(*State).ProjectVersion)
+	assert.Len(t, mockHTTP.calls, 5)
 }
 
-func TestClient_Start_LoadStateErrorDuringReload(t *testing.T) {
+func TestClient_Start_TriggerReloadTryLockFails(t *testing.T) {
 	c, mockHTTP, fakeClock := newTestClient()
 
 	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	mockHTTP.expect(makeVersionResponse("2"), nil)
-	mockHTTP.expect(makeVersionResponse("2"), nil)
-	mockHTTP.expect(nil, errors.New("failed to load redirects"))
-	mockHTTP.expect(makeAgentResponse(), nil)
+	c.reloadMu.Lock()
 
 	done := make(chan struct{})
 	go func() {
@@ -915,9 +2695,10 @@ func TestClient_Start_LoadStateErrorDuringReload(t *testing.T) {
 	}()
 
 	fakeClock.BlockUntil(1)
-	fakeClock.Advance(5 * time.Minute)
-	time.Sleep(100 * time.Millisecond)
+	c.TriggerReload()
+	assert.Eventually(t, func() bool { return len(c.trigger) == 0 }, time.Second, 10*time.Millisecond)
 
+	// Keep the mutex locked until Start exits so the triggered Reload cannot acquire it
 	cancel()
 
 	select {
@@ -925,19 +2706,20 @@ func TestClient_Start_LoadStateErrorDuringReload(t *testing.T) {
 	case <-time.After(1 * time.Second):
 		t.Fatal("Start did not exit")
 	}
+	c.reloadMu.Unlock()
 
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+	assert.Empty(t, mockHTTP.calls)
 }
 
-func TestClient_Start_VersionCheckError(t *testing.T) {
+func TestClient_Start_ReloadDebounce(t *testing.T) {
 	c, mockHTTP, fakeClock := newTestClient()
-
+	c.config().ReloadDebounce = 10 * time.Second
 	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	mockHTTP.expect(nil, errors.New("network error"))
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
 
+	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan struct{})
 	go func() {
 		c.Start(ctx)
@@ -945,52 +2727,80 @@ func TestClient_Start_VersionCheckError(t *testing.T) {
 	}()
 
 	fakeClock.BlockUntil(1)
-	fakeClock.Advance(5 * time.Minute)
-	time.Sleep(100 * time.Millisecond)
+	c.TriggerReload()
+	fakeClock.BlockUntil(2)
+	for i := 0; i < 5; i++ {
+		c.TriggerReload()
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, uint64(0), c.Stats().Attempted)
 
+	fakeClock.Advance(10 * time.Second)
+	assert.Eventually(t, func() bool { return c.Stats().Attempted == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
 	cancel()
+	<-done
 
-	select {
-	case <-done:
-	case <-time.After(1 * time.Second):
-		t.Fatal("Start did not exit")
-	}
+	assert.Equal(t, uint64(1), c.Stats().Attempted)
+	assert.Len(t, mockHTTP.calls, 2)
+}
 
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+// gatedHTTPClient blocks the first request until gate is closed.
+type gatedHTTPClient struct {
+	next    HTTPClient
+	entered chan struct{}
+	gate    chan struct{}
+	once    sync.Once
 }
 
-func TestClient_Start_TryLockFails(t *testing.T) {
-	c, _, fakeClock := newTestClient()
+func (g *gatedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	g.once.Do(func() {
+		close(g.entered)
+		<-g.gate
+	})
+	return g.next.Do(req)
+}
 
+func TestClient_Start_TriggerDuringInFlightReload(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
 	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+	gated := &gatedHTTPClient{next: mockHTTP, entered: make(chan struct{}), gate: make(chan struct{})}
+	c.config().Http.Client = gated
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Lock the mutex before starting - TryLock will fail
-	c.reloadMu.Lock()
+	for i := 0; i < 3; i++ {
+		mockHTTP.expect(makeVersionResponse("1"), nil)
+		mockHTTP.expect(makeAgentResponse(), nil)
+	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan struct{})
 	go func() {
 		c.Start(ctx)
 		close(done)
 	}()
-
 	fakeClock.BlockUntil(1)
-	fakeClock.Advance(5 * time.Minute)
-	time.Sleep(50 * time.Millisecond)
 
-	// Unlock and cancel
-	c.reloadMu.Unlock()
-	cancel()
+	inFlight := make(chan error)
+	go func() { inFlight <- c.Reload() }()
+	<-gated.entered
 
-	select {
-	case <-done:
-	case <-time.After(1 * time.Second):
-		t.Fatal("Start did not exit")
-	}
+	c.TriggerReload()
+	assert.Eventually(t, func() bool { return len(c.trigger) == 0 }, time.Second, time.Millisecond)
+	c.TriggerReload()
+	c.TriggerReload()
 
-	// Version should remain unchanged since TryLock failed
-	assert.Equal(t, 1, c.State.Load().(*State).ProjectVersion)
+	close(gated.gate)
+	assert.NoError(t, <-inFlight)
+	assert.Eventually(t, func() bool { return c.Stats().Attempted >= 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	// the in-flight reload, then one follow-up, two at most
+	attempted := c.Stats().Attempted
+	assert.GreaterOrEqual(t, attempted, uint64(2))
+	assert.LessOrEqual(t, attempted, uint64(3))
+	assert.Len(t, mockHTTP.calls, int(attempted)*2)
 }
 
 func TestClient_sendAgentStatus_Success(t *testing.T) {
@@ -1005,7 +2815,7 @@ func TestClient_sendAgentStatus_Success(t *testing.T) {
 
 	mockHTTP.expect(makeAgentResponse(), nil)
 
-	err := c.sendAgentStatus(agent)
+	err := c.sendAgentStatus(context.Background(), agent)
 
 	assert.NoError(t, err)
 	assert.Len(t, mockHTTP.calls, 1)
@@ -1025,7 +2835,7 @@ func TestClient_sendAgentStatus_HTTPError(t *testing.T) {
 
 	mockHTTP.expect(nil, errors.New("network error"))
 
-	err := c.sendAgentStatus(agent)
+	err := c.sendAgentStatus(context.Background(), agent)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "network error")
@@ -1054,7 +2864,7 @@ func TestClient_sendAgentStatus_Non200Status(t *testing.T) {
 
 			mockHTTP.expect(makeErrorResponse(tt.statusCode), nil)
 
-			err := c.sendAgentStatus(agent)
+			err := c.sendAgentStatus(context.Background(), agent)
 
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "unexpected status code")
@@ -1072,9 +2882,104 @@ func TestClient_sendAgentStatus_ValidationError(t *testing.T) {
 		Status:  types.AgentStatusSuccess,
 	}
 
-	err := c.sendAgentStatus(agent)
+	err := c.sendAgentStatus(context.Background(), agent)
+
+	assert.Error(t, err)
+}
+
+func TestClient_sendAgentStatus_AgentVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().AgentVersion = "1.4.2"
+
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	agent := types.Agent{Name: "test-node", Type: types.AgentTypeDefault, Version: 1, Status: types.AgentStatusSuccess}
+	err := c.sendAgentStatus(context.Background(), agent)
+
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(mockHTTP.calls[0].Body)
+	var payload map[string]any
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "1.4.2", payload["agent_version"])
+	assert.Equal(t, "test-node", payload["name"])
+	assert.Equal(t, "success", payload["status"])
+}
+
+func TestClient_sendAgentStatus_NoAgentVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	agent := types.Agent{Name: "test-node", Type: types.AgentTypeDefault, Version: 1, Status: types.AgentStatusSuccess}
+	err := c.sendAgentStatus(context.Background(), agent)
+
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(mockHTTP.calls[0].Body)
+	assert.NotContains(t, string(body), "agent_version")
+}
+
+func TestClient_sendAgentStatus_InvalidAgentVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().AgentVersion = "1.0 beta"
+
+	agent := types.Agent{Name: "test-node", Type: types.AgentTypeDefault, Version: 1, Status: types.AgentStatusSuccess}
+	err := c.sendAgentStatus(context.Background(), agent)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid agent version")
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_Init_InvalidAgentVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().AgentVersion = "v1\n"
+
+	err := c.Init()
 
 	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid agent version")
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_Init_IntervalCheckOutOfRange(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().IntervalCheck = 100 * time.Millisecond
+
+	err := c.Init()
+
+	assert.ErrorContains(t, err, "interval check 100ms out of range")
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_Init_ClampIntervalCheck(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().IntervalCheck = 100 * time.Millisecond
+	c.config().ClampIntervalCheck = true
+	var logs bytes.Buffer
+	c.config().Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	assert.NoError(t, c.Init())
+	assert.Contains(t, logs.String(), "clamping interval check")
+
+	mockHTTP.expect(makeVersionResponse("4"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+	// the loop waits MinAllowedIntervalCheck, not 100ms
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(100 * time.Millisecond)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 5)
+	fakeClock.Advance(MinAllowedIntervalCheck - 100*time.Millisecond)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 7)
+	cancel()
+	<-done
 }
 
 func TestClient_sendAgentStatus_NewRequestError(t *testing.T) {
@@ -1092,14 +2997,14 @@ func TestClient_sendAgentStatus_NewRequestError(t *testing.T) {
 			HeaderAuthorizationName: "Authorization",
 			TokenJWT:                "token",
 		},
-		IntervalCheck: 5 * time.Minute,
+		IntervalCheck:          5 * time.Minute,
+		SkipCompatibilityCheck: true,
 	}
 
 	c := &client{
-		cfg:        cfg,
-		httpClient: mockHTTP,
-		clock:      fakeClock,
+		clock: fakeClock,
 	}
+	c.cfg.Store(cfg)
 
 	agent := types.Agent{
 		Name:    "test-node",
@@ -1108,7 +3013,7 @@ func TestClient_sendAgentStatus_NewRequestError(t *testing.T) {
 		Status:  types.AgentStatusSuccess,
 	}
 
-	err := c.sendAgentStatus(agent)
+	err := c.sendAgentStatus(context.Background(), agent)
 
 	assert.Error(t, err)
 }
@@ -1118,7 +3023,7 @@ func TestClient_sendAgentHit_Success(t *testing.T) {
 
 	mockHTTP.expect(makeAgentResponse(), nil)
 
-	err := c.sendAgentHit("test-node")
+	err := c.sendAgentHit(context.Background(), "test-node")
 
 	assert.NoError(t, err)
 	assert.Len(t, mockHTTP.calls, 1)
@@ -1126,12 +3031,40 @@ func TestClient_sendAgentHit_Success(t *testing.T) {
 	assert.Contains(t, mockHTTP.calls[0].URL.String(), "/agents/test-node/hit")
 }
 
+func TestClient_sendAgentHit_EmptyBody(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.sendAgentHit(context.Background(), "test-node"))
+
+	assert.Nil(t, mockHTTP.calls[0].Body)
+	assert.Empty(t, mockHTTP.calls[0].Header.Get("Content-Type"))
+}
+
+func TestClient_sendAgentHit_HitIncludesBody(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().HitIncludesBody = true
+	require.NoError(t, c.LoadFromData(7, nil, nil))
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.sendAgentHit(context.Background(), "test-node"))
+
+	hit := mockHTTP.calls[0]
+	assert.Equal(t, http.MethodPatch, hit.Method)
+	assert.Equal(t, "application/json", hit.Header.Get("Content-Type"))
+	body, err := io.ReadAll(hit.Body)
+	require.NoError(t, err)
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, map[string]any{"version": float64(7), "ts": fakeClock.Now().UTC().Format(time.RFC3339Nano)}, payload)
+}
+
 func TestClient_sendAgentHit_HTTPError(t *testing.T) {
 	c, mockHTTP, _ := newTestClient()
 
 	mockHTTP.expect(nil, errors.New("network error"))
 
-	err := c.sendAgentHit("test-node")
+	err := c.sendAgentHit(context.Background(), "test-node")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "network error")
@@ -1154,7 +3087,7 @@ func TestClient_sendAgentHit_Non200Status(t *testing.T) {
 
 			mockHTTP.expect(makeErrorResponse(tt.statusCode), nil)
 
-			err := c.sendAgentHit("test-node")
+			err := c.sendAgentHit(context.Background(), "test-node")
 
 			assert.Error(t, err)
 			assert.Contains(t, err.Error(), "unexpected status code")
@@ -1162,6 +3095,45 @@ func TestClient_sendAgentHit_Non200Status(t *testing.T) {
 	}
 }
 
+func TestClient_sendAgentHit_SuccessStatusCodes(t *testing.T) {
+	t.Run("204 accepted when configured", func(t *testing.T) {
+		c, mockHTTP, _ := newTestClient()
+		c.config().Http.SuccessStatusCodes = []int{http.StatusOK, http.StatusNoContent}
+
+		mockHTTP.expect(makeErrorResponse(http.StatusNoContent), nil)
+
+		assert.NoError(t, c.sendAgentHit(context.Background(), "test-node"))
+	})
+
+	t.Run("204 rejected by default", func(t *testing.T) {
+		c, mockHTTP, _ := newTestClient()
+
+		mockHTTP.expect(makeErrorResponse(http.StatusNoContent), nil)
+
+		err := c.sendAgentHit(context.Background(), "test-node")
+		assert.ErrorContains(t, err, "unexpected status code")
+	})
+
+	t.Run("200 rejected when not listed", func(t *testing.T) {
+		c, mockHTTP, _ := newTestClient()
+		c.config().Http.SuccessStatusCodes = []int{http.StatusNoContent}
+
+		mockHTTP.expect(makeAgentResponse(), nil)
+
+		assert.Error(t, c.sendAgentHit(context.Background(), "test-node"))
+	})
+}
+
+func TestClient_sendAgentStatus_SuccessStatusCodes(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.SuccessStatusCodes = []int{http.StatusOK, http.StatusCreated, http.StatusAccepted}
+
+	mockHTTP.expect(makeErrorResponse(http.StatusCreated), nil)
+
+	agent := types.Agent{Name: "test-node", Type: types.AgentTypeDefault, Version: 1, Status: types.AgentStatusSuccess}
+	assert.NoError(t, c.sendAgentStatus(context.Background(), agent))
+}
+
 func TestClient_sendAgentHit_NewRequestError(t *testing.T) {
 	mockHTTP := newMockHTTPClient()
 	fakeClock := clockwork.NewFakeClock()
@@ -1177,16 +3149,140 @@ func TestClient_sendAgentHit_NewRequestError(t *testing.T) {
 			HeaderAuthorizationName: "Authorization",
 			TokenJWT:                "token",
 		},
-		IntervalCheck: 5 * time.Minute,
+		IntervalCheck:          5 * time.Minute,
+		SkipCompatibilityCheck: true,
 	}
 
 	c := &client{
-		cfg:        cfg,
-		httpClient: mockHTTP,
-		clock:      fakeClock,
+		clock: fakeClock,
 	}
+	c.cfg.Store(cfg)
 
-	err := c.sendAgentHit("test-node")
+	err := c.sendAgentHit(context.Background(), "test-node")
 
 	assert.Error(t, err)
 }
+
+// blockingHTTPClient holds every request until released, recording how many
+// were in flight at once.
+type blockingHTTPClient struct {
+	entered chan struct{}
+	release chan struct{}
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (b *blockingHTTPClient) Do(*http.Request) (*http.Response, error) {
+	b.mu.Lock()
+	b.inFlight++
+	b.maxInFlight = max(b.maxInFlight, b.inFlight)
+	b.mu.Unlock()
+	b.entered <- struct{}{}
+	<-b.release
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+	return makeVersionResponse("1"), nil
+}
+
+func TestClient_MaxConcurrentRequests(t *testing.T) {
+	c, _, _ := newTestClient()
+	blocking := &blockingHTTPClient{entered: make(chan struct{}), release: make(chan struct{})}
+	c.config().Http.Client = blocking
+	c.requestSlots = newRequestSlots(2)
+
+	const requests = 5
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, c.config().GetUrlApiVersion(), nil)
+			resp, err := c.do(EndpointVersion, req)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+
+	<-blocking.entered
+	<-blocking.entered
+	select {
+	case <-blocking.entered:
+		t.Fatal("a third request was sent while two were in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := 2; i < requests; i++ {
+		blocking.release <- struct{}{}
+		<-blocking.entered
+	}
+	blocking.release <- struct{}{}
+	blocking.release <- struct{}{}
+	wg.Wait()
+
+	assert.Equal(t, 2, blocking.maxInFlight)
+}
+
+func TestClient_MaxConcurrentRequests_ContextDone(t *testing.T) {
+	c, _, _ := newTestClient()
+	blocking := &blockingHTTPClient{entered: make(chan struct{}), release: make(chan struct{})}
+	c.config().Http.Client = blocking
+	c.requestSlots = newRequestSlots(1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req, _ := http.NewRequest(http.MethodGet, c.config().GetUrlApiVersion(), nil)
+		_, _ = c.do(EndpointVersion, req)
+	}()
+	<-blocking.entered
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, c.config().GetUrlApiVersion(), nil)
+	_, err := c.do(EndpointVersion, req)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	blocking.release <- struct{}{}
+	<-done
+}
+
+func TestNew_MaxConcurrentRequests(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.Nil(t, New(cfg).(*client).requestSlots)
+
+	cfg.MaxConcurrentRequests = 3
+	assert.Equal(t, 3, cap(New(cfg).(*client).requestSlots))
+}
+
+func TestClient_Reload_URLRewriter(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	var endpoints []Endpoint
+	c.config().Http.URLRewriter = func(endpoint Endpoint, url string) string {
+		endpoints = append(endpoints, endpoint)
+		if endpoint == EndpointVersion {
+			return strings.Replace(url, "localhost:8080", "canary:8080", 1)
+		}
+		return url + "&rewritten=" + string(endpoint)
+	}
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 0), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.Reload())
+	assert.NoError(t, c.Reload())
+
+	assert.Equal(t, []Endpoint{EndpointVersion, EndpointVersion, EndpointRedirects, EndpointPages, EndpointAgentStatus, EndpointVersion, EndpointAgentHit}, endpoints)
+	assert.Len(t, mockHTTP.calls, 7)
+	assert.Equal(t, "http://canary:8080/api/namespace/test-ns/project/test-proj/version", mockHTTP.calls[0].URL.String())
+	assert.Equal(t, "redirects", mockHTTP.calls[2].URL.Query().Get("rewritten"))
+	assert.Equal(t, "pages", mockHTTP.calls[3].URL.Query().Get("rewritten"))
+	assert.Equal(t, c.config().GetUrlApiAgents()+"&rewritten=agent_status", mockHTTP.calls[4].URL.String())
+	assert.Equal(t, c.config().GetUrlApiAgentsHit("test-node")+"&rewritten=agent_hit", mockHTTP.calls[6].URL.String())
+}
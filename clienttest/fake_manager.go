@@ -0,0 +1,190 @@
+// Package clienttest provides an in-memory Flecto Manager for testing code
+// built on top of the go-client package.
+package clienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	client "github.com/flectolab/go-client"
+)
+
+// FakeManager implements client.HTTPClient and serves the configured state.
+// It is safe for concurrent use.
+type FakeManager struct {
+	mu             sync.Mutex
+	managerVersion string
+	version        int
+	redirects      []types.Redirect
+	pages          []types.Page
+	failures       map[client.Endpoint]int
+	requests       []*http.Request
+	statuses       []types.Agent
+	hits           []string
+}
+
+var _ client.HTTPClient = (*FakeManager)(nil)
+
+func NewFakeManager() *FakeManager {
+	return &FakeManager{managerVersion: client.MinManagerVersion, version: 1, failures: make(map[client.Endpoint]int)}
+}
+
+// SetManagerVersion sets the version served by the info endpoint, checked by Init.
+func (f *FakeManager) SetManagerVersion(version string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.managerVersion = version
+}
+
+func (f *FakeManager) SetVersion(version int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version = version
+}
+
+func (f *FakeManager) SetRedirects(redirects []types.Redirect) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.redirects = append([]types.Redirect(nil), redirects...)
+}
+
+func (f *FakeManager) SetPages(pages []types.Page) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pages = append([]types.Page(nil), pages...)
+}
+
+// SetFailure makes every request to endpoint answer statusCode. A zero status code clears the failure.
+func (f *FakeManager) SetFailure(endpoint client.Endpoint, statusCode int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if statusCode == 0 {
+		delete(f.failures, endpoint)
+		return
+	}
+	f.failures[endpoint] = statusCode
+}
+
+// Requests returns every request received, in order.
+func (f *FakeManager) Requests() []*http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*http.Request(nil), f.requests...)
+}
+
+// AgentStatuses returns the agent statuses posted by the client, in order.
+func (f *FakeManager) AgentStatuses() []types.Agent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]types.Agent(nil), f.statuses...)
+}
+
+// AgentHits returns the agent names that sent a hit, in order.
+func (f *FakeManager) AgentHits() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.hits...)
+}
+
+func (f *FakeManager) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+
+	endpoint, agentName, ok := route(req)
+	if !ok {
+		return response(http.StatusNotFound, []byte("not found")), nil
+	}
+	if statusCode, failing := f.failures[endpoint]; failing {
+		return response(statusCode, []byte(http.StatusText(statusCode))), nil
+	}
+
+	switch endpoint {
+	case client.EndpointManagerInfo:
+		return jsonResponse(map[string]string{"version": f.managerVersion})
+	case client.EndpointVersion:
+		return response(http.StatusOK, []byte(strconv.Itoa(f.version))), nil
+	case client.EndpointRedirects:
+		items, limit, offset := paginate(req, f.redirects)
+		return jsonResponse(types.RedirectList{Items: items, Total: len(f.redirects), Limit: limit, Offset: offset})
+	case client.EndpointPages:
+		items, limit, offset := paginate(req, f.pages)
+		return jsonResponse(types.PageList{Items: items, Total: len(f.pages), Limit: limit, Offset: offset})
+	case client.EndpointAgentStatus:
+		var agent types.Agent
+		if req.Body == nil {
+			return response(http.StatusBadRequest, []byte("missing body")), nil
+		}
+		if err := json.NewDecoder(req.Body).Decode(&agent); err != nil {
+			return response(http.StatusBadRequest, []byte(err.Error())), nil
+		}
+		f.statuses = append(f.statuses, agent)
+		return response(http.StatusOK, nil), nil
+	default:
+		f.hits = append(f.hits, agentName)
+		return response(http.StatusOK, nil), nil
+	}
+}
+
+func route(req *http.Request) (client.Endpoint, string, bool) {
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case req.Method == http.MethodGet && strings.HasSuffix(path, "/info"):
+		return client.EndpointManagerInfo, "", true
+	case req.Method == http.MethodGet && strings.HasSuffix(path, "/version"):
+		return client.EndpointVersion, "", true
+	case req.Method == http.MethodGet && strings.HasSuffix(path, "/redirects"):
+		return client.EndpointRedirects, "", true
+	case req.Method == http.MethodGet && strings.HasSuffix(path, "/pages"):
+		return client.EndpointPages, "", true
+	case req.Method == http.MethodPost && strings.HasSuffix(path, "/agents"):
+		return client.EndpointAgentStatus, "", true
+	case req.Method == http.MethodPatch && strings.HasSuffix(path, "/hit"):
+		parts := strings.Split(path, "/")
+		if len(parts) < 3 || parts[len(parts)-3] != "agents" {
+			return "", "", false
+		}
+		return client.EndpointAgentHit, parts[len(parts)-2], true
+	}
+	return "", "", false
+}
+
+func paginate[T any](req *http.Request, items []T) ([]T, int, int) {
+	limit, errLimit := strconv.Atoi(req.URL.Query().Get("limit"))
+	if errLimit != nil || limit <= 0 {
+		limit = types.DefaultLimit
+	}
+	offset, errOffset := strconv.Atoi(req.URL.Query().Get("offset"))
+	if errOffset != nil || offset < 0 {
+		offset = types.DefaultOffset
+	}
+	if offset >= len(items) {
+		return []T{}, limit, offset
+	}
+	end := min(offset+limit, len(items))
+	return append([]T(nil), items[offset:end]...), limit, offset
+}
+
+func jsonResponse(v any) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("clienttest: %w", err)
+	}
+	return response(http.StatusOK, body), nil
+}
+
+func response(statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
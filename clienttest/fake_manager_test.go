@@ -0,0 +1,124 @@
+package clienttest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	client "github.com/flectolab/go-client"
+	"github.com/stretchr/testify/assert"
+)
+
+func newConfig(manager *FakeManager) *client.Config {
+	cfg := client.NewDefaultConfig()
+	cfg.ManagerUrl = "http://manager.test"
+	cfg.NamespaceCode = "ns"
+	cfg.ProjectCode = "proj"
+	cfg.AgentName = "test-agent"
+	cfg.AgentType = types.AgentTypeDefault
+	cfg.Logger = nil
+	cfg.Http.Client = manager
+	return cfg
+}
+
+func TestFakeManager_Init(t *testing.T) {
+	manager := NewFakeManager()
+	manager.SetVersion(3)
+	manager.SetRedirects([]types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+	})
+	manager.SetPages([]types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	})
+
+	c := client.New(newConfig(manager))
+
+	assert.NoError(t, c.Init())
+	assert.Equal(t, 3, c.GetStateVersion())
+	redirect, target := c.RedirectMatch("example.com", "/old")
+	assert.NotNil(t, redirect)
+	assert.Equal(t, "/new", target)
+	assert.NotNil(t, c.PageMatch("example.com", "/robots.txt"))
+
+	statuses := manager.AgentStatuses()
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, "test-agent", statuses[0].Name)
+	assert.Equal(t, types.AgentStatusSuccess, statuses[0].Status)
+	assert.Equal(t, 3, statuses[0].Version)
+}
+
+func TestFakeManager_Pagination(t *testing.T) {
+	manager := NewFakeManager()
+	redirects := make([]types.Redirect, 250)
+	for i := range redirects {
+		redirects[i] = types.Redirect{Type: types.RedirectTypeBasic, Source: fmt.Sprintf("/old-%d", i), Target: fmt.Sprintf("/new-%d", i)}
+	}
+	manager.SetRedirects(redirects)
+
+	c := client.New(newConfig(manager))
+
+	assert.NoError(t, c.Init())
+	_, target := c.RedirectMatch("example.com", "/old-249")
+	assert.Equal(t, "/new-249", target)
+
+	redirectRequests := 0
+	for _, req := range manager.Requests() {
+		if req.URL.Path == "/api/namespace/ns/project/proj/redirects" {
+			redirectRequests++
+		}
+	}
+	assert.Equal(t, 3, redirectRequests)
+}
+
+func TestFakeManager_AgentHits(t *testing.T) {
+	manager := NewFakeManager()
+	c := client.New(newConfig(manager))
+
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.Reload())
+
+	assert.Len(t, manager.AgentStatuses(), 1)
+	assert.Equal(t, []string{"test-agent"}, manager.AgentHits())
+}
+
+func TestFakeManager_SetFailure(t *testing.T) {
+	manager := NewFakeManager()
+	manager.SetFailure(client.EndpointRedirects, http.StatusInternalServerError)
+	c := client.New(newConfig(manager))
+
+	err := c.Init()
+
+	var apiErr *client.APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	statuses := manager.AgentStatuses()
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, types.AgentStatusError, statuses[0].Status)
+
+	manager.SetFailure(client.EndpointRedirects, 0)
+	assert.NoError(t, c.Reload())
+}
+
+func TestFakeManager_UnknownRoute(t *testing.T) {
+	manager := NewFakeManager()
+	req, _ := http.NewRequest(http.MethodGet, "http://manager.test/unknown", nil)
+
+	resp, err := manager.Do(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestFakeManager_IncompatibleManager(t *testing.T) {
+	manager := NewFakeManager()
+	manager.SetManagerVersion("2.0.0")
+	cfg := newConfig(manager)
+	cfg.MaxManagerVersion = "2.0.0"
+	c := client.New(cfg)
+
+	err := c.Init()
+
+	assert.ErrorIs(t, err, client.ErrIncompatibleManager)
+	assert.Empty(t, manager.AgentStatuses())
+}
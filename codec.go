@@ -0,0 +1,70 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// Codec decodes list responses in a format other than JSON, e.g. msgpack.
+// The client advertises it through the Accept header and still decodes JSON
+// when the manager ignores the header.
+type Codec interface {
+	ContentType() string
+	Decode(r io.Reader, v any) error
+}
+
+const (
+	AcceptFormatJSON    = "json"
+	AcceptFormatMsgpack = "msgpack"
+)
+
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (JSONCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+func (c *HTTPConfig) getCodec() Codec {
+	if c.Codec != nil {
+		return c.Codec
+	}
+	if c.AcceptFormat == AcceptFormatMsgpack {
+		return MsgpackCodec{}
+	}
+	return JSONCodec{}
+}
+
+func validateAcceptFormat(format string) error {
+	switch format {
+	case "", AcceptFormatJSON, AcceptFormatMsgpack:
+		return nil
+	default:
+		return fmt.Errorf("invalid accept format: %s", format)
+	}
+}
+
+// AcceptHeader returns the Accept header value sent on list requests.
+func (c *HTTPConfig) AcceptHeader() string {
+	contentType := c.getCodec().ContentType()
+	if contentType == (JSONCodec{}).ContentType() {
+		return contentType
+	}
+	return contentType + ", application/json;q=0.9"
+}
+
+// ResponseCodec selects the codec matching the response Content-Type, falling back to JSON.
+func (c *HTTPConfig) ResponseCodec(resp *http.Response) Codec {
+	codec := c.getCodec()
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err == nil && mediaType == codec.ContentType() {
+		return codec
+	}
+	return JSONCodec{}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// gobCodec stands in for a compact binary codec such as msgpack.
+type gobCodec struct{}
+
+func (gobCodec) ContentType() string {
+	return "application/x-gob"
+}
+
+func (gobCodec) Decode(r io.Reader, v any) error {
+	return gob.NewDecoder(r).Decode(v)
+}
+
+func makeGobResponse(v any) *http.Response {
+	var body bytes.Buffer
+	_ = gob.NewEncoder(&body).Encode(v)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/x-gob"}},
+		Body:       io.NopCloser(&body),
+	}
+}
+
+func TestHTTPConfig_AcceptHeader(t *testing.T) {
+	assert.Equal(t, "application/json", (&HTTPConfig{}).AcceptHeader())
+	assert.Equal(t, "application/x-gob, application/json;q=0.9", (&HTTPConfig{Codec: gobCodec{}}).AcceptHeader())
+	assert.Equal(t, "application/msgpack, application/json;q=0.9", (&HTTPConfig{AcceptFormat: AcceptFormatMsgpack}).AcceptHeader())
+	assert.Equal(t, "application/x-gob, application/json;q=0.9", (&HTTPConfig{Codec: gobCodec{}, AcceptFormat: AcceptFormatMsgpack}).AcceptHeader())
+}
+
+func TestHTTPConfig_ResponseCodec(t *testing.T) {
+	tests := []struct {
+		name        string
+		codec       Codec
+		contentType string
+		want        Codec
+	}{
+		{name: "default codec", codec: nil, contentType: "application/json", want: JSONCodec{}},
+		{name: "custom codec matches", codec: gobCodec{}, contentType: "application/x-gob", want: gobCodec{}},
+		{name: "custom codec with params", codec: gobCodec{}, contentType: "application/x-gob; charset=binary", want: gobCodec{}},
+		{name: "server ignored accept", codec: gobCodec{}, contentType: "application/json", want: JSONCodec{}},
+		{name: "missing content type", codec: gobCodec{}, contentType: "", want: JSONCodec{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &HTTPConfig{Codec: tt.codec}
+			resp := &http.Response{Header: http.Header{"Content-Type": []string{tt.contentType}}}
+			assert.Equal(t, tt.want, cfg.ResponseCodec(resp))
+		})
+	}
+}
+
+func TestClient_getProjectRedirects_CustomCodec(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.Codec = gobCodec{}
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+	}
+	mockHTTP.expect(makeGobResponse(types.RedirectList{Items: redirects, Total: 1}), nil)
+
+	result, err := c.getProjectRedirects(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, redirects, result)
+	assert.Equal(t, "application/x-gob, application/json;q=0.9", mockHTTP.calls[0].Header.Get("Accept"))
+}
+
+func TestClient_getProjectPages_CustomCodecJSONFallback(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.Codec = gobCodec{}
+
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, pages, result)
+}
+
+func largeRedirectList(n int) types.RedirectList {
+	items := make([]types.Redirect, n)
+	for i := range items {
+		items[i] = types.Redirect{Type: types.RedirectTypeBasic, Source: fmt.Sprintf("/old/%d", i), Target: fmt.Sprintf("/new/%d", i), Status: types.RedirectStatusMovedPermanent}
+	}
+	return types.RedirectList{Items: items, Total: n, Limit: n}
+}
+
+func BenchmarkDecodeRedirects_JSON(b *testing.B) {
+	body, _ := json.Marshal(largeRedirectList(10000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var list types.RedirectList
+		_ = JSONCodec{}.Decode(bytes.NewReader(body), &list)
+	}
+}
+
+func BenchmarkDecodeRedirects_Msgpack(b *testing.B) {
+	body := marshalMsgpack(largeRedirectList(10000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var list types.RedirectList
+		_ = MsgpackCodec{}.Decode(bytes.NewReader(body), &list)
+	}
+}
+
+func BenchmarkDecodeRedirects_Gob(b *testing.B) {
+	var body bytes.Buffer
+	_ = gob.NewEncoder(&body).Encode(largeRedirectList(10000))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var list types.RedirectList
+		_ = gobCodec{}.Decode(bytes.NewReader(body.Bytes()), &list)
+	}
+}
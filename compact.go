@@ -0,0 +1,155 @@
+package client
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// compactGroup identifies the BASIC or BASIC_HOST rules that CompactRedirects
+// can merge: same type and status, and a source and target made of these
+// prefixes followed by the same rest of the path.
+type compactGroup struct {
+	Type         types.RedirectType
+	Status       types.RedirectStatus
+	SourcePrefix string
+	TargetPrefix string
+}
+
+// CompactRedirects returns redirects with the BASIC and BASIC_HOST rules mapping
+// a common source prefix to a common target prefix, keeping the rest of the path,
+// merged into one REGEX or REGEX_HOST rule: /old/a -> /new/a and /old/b/c ->
+// /new/b/c become ^/old/(.*)$ -> /new/$1. It is meant for operators to preview
+// in tooling and is never applied by the client. As when loading a state, the
+// last redirect of a (type, source) pair wins.
+//
+// A merged rule also redirects the paths under its prefix that had no rule. A
+// group is left alone when a regex rule, or another merged rule, could match
+// under its prefix, or, for host rules, when a rule without host has the path of
+// one of its members: the merged rule would not win the same matches.
+func CompactRedirects(redirects []types.Redirect) []types.Redirect {
+	redirects, _ = dedupeRedirects(redirects)
+	groups := make(map[compactGroup][]int)
+	var order []compactGroup
+	for i := range redirects {
+		group, ok := compactGroupOf(&redirects[i])
+		if !ok {
+			continue
+		}
+		if _, found := groups[group]; !found {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], i)
+	}
+
+	merged := make(map[int]types.Redirect)
+	dropped := make(map[int]bool)
+	var mergedPaths []string
+	for _, group := range order {
+		members := groups[group]
+		path := compactPath(group)
+		if len(members) < 2 || slices.ContainsFunc(mergedPaths, func(p string) bool { return prefixesOverlap(p, path) }) ||
+			compactConflicts(group, members, redirects) {
+			continue
+		}
+		mergedPaths = append(mergedPaths, path)
+		merged[members[0]] = group.redirect()
+		for _, i := range members[1:] {
+			dropped[i] = true
+		}
+	}
+
+	compacted := make([]types.Redirect, 0, len(redirects))
+	for i, r := range redirects {
+		if dropped[i] {
+			continue
+		}
+		if m, found := merged[i]; found {
+			r = m
+		}
+		compacted = append(compacted, r)
+	}
+	return compacted
+}
+
+// compactGroupOf splits r at the first slash of its source after which source
+// and target end alike, the target prefix also ending with a slash.
+func compactGroupOf(r *types.Redirect) (compactGroup, bool) {
+	if r.Type != types.RedirectTypeBasic && r.Type != types.RedirectTypeBasicHost {
+		return compactGroup{}, false
+	}
+	for i := strings.IndexByte(r.Source, '/'); i >= 0 && i < len(r.Source)-1; {
+		rest := r.Source[i+1:]
+		targetPrefix, found := strings.CutSuffix(r.Target, rest)
+		if found && strings.HasSuffix(targetPrefix, "/") && !strings.Contains(targetPrefix, "$") {
+			group := compactGroup{Type: r.Type, Status: r.Status, SourcePrefix: r.Source[:i+1], TargetPrefix: targetPrefix}
+			// a rule redirecting to its own path is a loop, not worth merging
+			return group, compactPath(group) != targetPrefix
+		}
+		next := strings.IndexByte(rest, '/')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return compactGroup{}, false
+}
+
+func (g compactGroup) redirect() types.Redirect {
+	r := types.Redirect{Type: types.RedirectTypeRegex, Status: g.Status, Target: g.TargetPrefix + "$1"}
+	if g.Type == types.RedirectTypeBasicHost {
+		r.Type = types.RedirectTypeRegexHost
+	}
+	r.Source = "^" + regexp.QuoteMeta(g.SourcePrefix) + "(.*)$"
+	return r
+}
+
+// compactPath returns the path prefix of the group, without its host.
+func compactPath(g compactGroup) string {
+	if g.Type != types.RedirectTypeBasicHost {
+		return g.SourcePrefix
+	}
+	_, path, _ := strings.Cut(g.SourcePrefix, "/")
+	return "/" + path
+}
+
+func compactConflicts(group compactGroup, members []int, redirects []types.Redirect) bool {
+	path := compactPath(group)
+	for _, r := range redirects {
+		switch r.Type {
+		case types.RedirectTypeRegex:
+			if prefixesOverlap(regexLiteralPrefix(r.Source), path) {
+				return true
+			}
+		case types.RedirectTypeRegexHost:
+			// the host of a regex may not be a literal: only compare the paths
+			_, rest, found := strings.Cut(regexLiteralPrefix(r.Source), "/")
+			if !found || prefixesOverlap("/"+rest, path) {
+				return true
+			}
+		}
+	}
+	if group.Type != types.RedirectTypeBasicHost {
+		return false
+	}
+	// a rule without host is matched before the merged REGEX_HOST rule
+	basic := make(map[string]bool)
+	for _, r := range redirects {
+		if r.Type == types.RedirectTypeBasic {
+			basic[r.Source] = true
+		}
+	}
+	for _, i := range members {
+		_, memberPath, _ := strings.Cut(redirects[i].Source, "/")
+		if basic["/"+memberPath] {
+			return true
+		}
+	}
+	return false
+}
+
+func prefixesOverlap(a, b string) bool {
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
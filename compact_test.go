@@ -0,0 +1,149 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactRedirects(t *testing.T) {
+	tests := []struct {
+		name      string
+		redirects []types.Redirect
+		want      []types.Redirect
+	}{
+		{
+			name: "common prefix",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/keep", Target: "/kept"},
+				{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a"},
+				{Type: types.RedirectTypeBasic, Source: "/old/b/c", Target: "/new/b/c"},
+				{Type: types.RedirectTypeBasic, Source: "/old/d.html", Target: "https://example.org/new/d.html"},
+			},
+			want: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/keep", Target: "/kept"},
+				{Type: types.RedirectTypeRegex, Source: "^/old/(.*)$", Target: "/new/$1"},
+				{Type: types.RedirectTypeBasic, Source: "/old/d.html", Target: "https://example.org/new/d.html"},
+			},
+		},
+		{
+			name: "host rules",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasicHost, Source: "example.com/old/a", Target: "/new/a", Status: types.RedirectStatusFound},
+				{Type: types.RedirectTypeBasicHost, Source: "example.com/old/b", Target: "/new/b", Status: types.RedirectStatusFound},
+			},
+			want: []types.Redirect{
+				{Type: types.RedirectTypeRegexHost, Source: `^example\.com/old/(.*)$`, Target: "/new/$1", Status: types.RedirectStatusFound},
+			},
+		},
+		{
+			name: "different statuses",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a", Status: types.RedirectStatusFound},
+				{Type: types.RedirectTypeBasic, Source: "/old/b", Target: "/new/b"},
+			},
+		},
+		{
+			name: "different target prefixes",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a"},
+				{Type: types.RedirectTypeBasic, Source: "/old/b", Target: "/other/b"},
+			},
+		},
+		{
+			name: "regex under the prefix",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a"},
+				{Type: types.RedirectTypeBasic, Source: "/old/b", Target: "/new/b"},
+				{Type: types.RedirectTypeRegex, Source: "^/old/x/(.*)$", Target: "/x/$1"},
+			},
+		},
+		{
+			name: "host regex under the prefix",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a"},
+				{Type: types.RedirectTypeBasic, Source: "/old/b", Target: "/new/b"},
+				{Type: types.RedirectTypeRegexHost, Source: `^example\.com/(.*)$`, Target: "/$1"},
+			},
+		},
+		{
+			name: "host rule shadowed by a rule without host",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasicHost, Source: "example.com/old/a", Target: "/new/a"},
+				{Type: types.RedirectTypeBasicHost, Source: "example.com/old/b", Target: "/new/b"},
+				{Type: types.RedirectTypeBasic, Source: "/old/b", Target: "/elsewhere"},
+			},
+		},
+		{
+			name: "nested prefixes",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a"},
+				{Type: types.RedirectTypeBasic, Source: "/old/b", Target: "/new/b"},
+				{Type: types.RedirectTypeBasic, Source: "/old/sub/a", Target: "/sub/a"},
+				{Type: types.RedirectTypeBasic, Source: "/old/sub/b", Target: "/sub/b"},
+			},
+			want: []types.Redirect{
+				{Type: types.RedirectTypeRegex, Source: "^/old/(.*)$", Target: "/new/$1"},
+				{Type: types.RedirectTypeBasic, Source: "/old/sub/a", Target: "/sub/a"},
+				{Type: types.RedirectTypeBasic, Source: "/old/sub/b", Target: "/sub/b"},
+			},
+		},
+		{
+			name: "loops",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/a", Target: "/a"},
+				{Type: types.RedirectTypeBasic, Source: "/b", Target: "/b"},
+			},
+		},
+		{
+			name: "single rule",
+			redirects: []types.Redirect{
+				{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == nil {
+				want = tt.redirects
+			}
+
+			got := CompactRedirects(tt.redirects)
+
+			assert.Equal(t, want, got)
+			before, err := BuildRedirectMatcher(tt.redirects)
+			require.NoError(t, err)
+			after, err := BuildRedirectMatcher(got)
+			require.NoError(t, err)
+			// the compacted rules answer the sources as the original ones
+			for _, r := range tt.redirects {
+				host, uri := "example.com", r.Source
+				switch r.Type {
+				case types.RedirectTypeBasicHost:
+					var path string
+					host, path, _ = strings.Cut(r.Source, "/")
+					uri = "/" + path
+				case types.RedirectTypeRegex, types.RedirectTypeRegexHost:
+					continue
+				}
+				_, wantTarget := before.Match(host, uri)
+				_, gotTarget := after.Match(host, uri)
+				assert.Equal(t, wantTarget, gotTarget, r.Source)
+			}
+		})
+	}
+}
+
+func TestCompactRedirects_Duplicates(t *testing.T) {
+	got := CompactRedirects([]types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/elsewhere"},
+		{Type: types.RedirectTypeBasic, Source: "/old/a", Target: "/new/a"},
+		{Type: types.RedirectTypeBasic, Source: "/old/b", Target: "/new/b"},
+	})
+
+	assert.Equal(t, []types.Redirect{{Type: types.RedirectTypeRegex, Source: "^/old/(.*)$", Target: "/new/$1"}}, got)
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MinManagerVersion is the oldest manager version this client works with. Newer
+// versions are accepted unless Config.MaxManagerVersion bounds them.
+const MinManagerVersion = "0.0.1"
+
+// ErrIncompatibleManager is returned by Init when the manager version is out of
+// the accepted range.
+var ErrIncompatibleManager = errors.New("incompatible manager")
+
+// managerInfo is the document returned by the manager info endpoint.
+type managerInfo struct {
+	Version string `json:"version"`
+}
+
+// parseManagerVersion parses a "v1.2.3" version, ignoring any pre-release or
+// build suffix. Missing minor and patch numbers are zero.
+func parseManagerVersion(raw string) ([3]int, error) {
+	var parsed [3]int
+	version := strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if version == "" || len(parts) > 3 {
+		return parsed, fmt.Errorf("invalid manager version %q", raw)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, fmt.Errorf("invalid manager version %q", raw)
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
+
+func compareManagerVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// validateMaxManagerVersion checks Config.MaxManagerVersion, empty or a version
+// above MinManagerVersion.
+func validateMaxManagerVersion(cfg *Config) error {
+	if cfg.MaxManagerVersion == "" {
+		return nil
+	}
+	maxVersion, err := parseManagerVersion(cfg.MaxManagerVersion)
+	if err != nil {
+		return fmt.Errorf("MaxManagerVersion: %w", err)
+	}
+	minVersion, _ := parseManagerVersion(MinManagerVersion)
+	if compareManagerVersions(maxVersion, minVersion) <= 0 {
+		return fmt.Errorf("MaxManagerVersion %s must be above %s", cfg.MaxManagerVersion, MinManagerVersion)
+	}
+	return nil
+}
+
+// checkManagerVersion fails with ErrIncompatibleManager when version is below
+// MinManagerVersion or, unless it is empty, not below maxVersion.
+func checkManagerVersion(version, maxVersion string) error {
+	parsed, err := parseManagerVersion(version)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrIncompatibleManager, err)
+	}
+	minVersion, _ := parseManagerVersion(MinManagerVersion)
+	if compareManagerVersions(parsed, minVersion) < 0 {
+		return fmt.Errorf("%w: version %s, supported from %s", ErrIncompatibleManager, version, MinManagerVersion)
+	}
+	if maxVersion == "" {
+		return nil
+	}
+	if upper, _ := parseManagerVersion(maxVersion); compareManagerVersions(parsed, upper) >= 0 {
+		return fmt.Errorf("%w: version %s, supported from %s to below %s", ErrIncompatibleManager, version, MinManagerVersion, maxVersion)
+	}
+	return nil
+}
+
+// checkCompatibility fetches the manager version and checks it is supported.
+// Managers answering 404 or 405 have no info endpoint and are let through.
+func (c *client) checkCompatibility(ctx context.Context) error {
+	url := c.config().GetUrlApiInfo()
+	req, err := c.newRequest(ctx, EndpointManagerInfo, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, errReq := c.do(EndpointManagerInfo, req)
+	if errReq != nil {
+		return errReq
+	}
+	defer closeBody(resp)
+
+	body, errReadBody := io.ReadAll(c.limitBody(resp))
+	if errReadBody != nil {
+		return errReadBody
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		c.logger().DebugContext(ctx, "manager has no info endpoint, skipping the compatibility check", "status", resp.StatusCode)
+		return nil
+	}
+	if !c.config().Http.isSuccess(resp.StatusCode) {
+		return c.apiError(url, resp, body)
+	}
+
+	var info managerInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return fmt.Errorf("%w: decoding %s: %w", ErrIncompatibleManager, url, err)
+	}
+	return checkManagerVersion(info.Version, c.config().MaxManagerVersion)
+}
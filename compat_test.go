@@ -0,0 +1,172 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeInfoResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}
+}
+
+func TestCheckManagerVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		maxVersion string
+		wantErr    bool
+	}{
+		{version: "0.0.1"},
+		{version: "0.3.1"},
+		{version: "v0.9.12"},
+		{version: "0.4.0-rc.1"},
+		{version: "0.5"},
+		{version: "1.0.0"},
+		{version: "2.1.0"},
+		{version: "1.9.3", maxVersion: "2.0.0"},
+		{version: "2.0.0", maxVersion: "2.0.0", wantErr: true},
+		{version: "2.1.0", maxVersion: "2", wantErr: true},
+		{version: "0.0.0", wantErr: true},
+		{version: "", wantErr: true},
+		{version: "latest", wantErr: true},
+		{version: "0.1.2.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" below "+tt.maxVersion, func(t *testing.T) {
+			err := checkManagerVersion(tt.version, tt.maxVersion)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrIncompatibleManager)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateMaxManagerVersion(t *testing.T) {
+	tests := []struct {
+		maxVersion string
+		wantErr    string
+	}{
+		{maxVersion: ""},
+		{maxVersion: "2.0.0"},
+		{maxVersion: "v1"},
+		{maxVersion: "next", wantErr: "invalid manager version"},
+		{maxVersion: "0.0.1", wantErr: "must be above"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.maxVersion, func(t *testing.T) {
+			err := validateMaxManagerVersion(&Config{MaxManagerVersion: tt.maxVersion})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_Init_CompatibleManager(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().SkipCompatibilityCheck = false
+
+	mockHTTP.expect(makeInfoResponse(`{"version":"0.3.1"}`), nil)
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	assert.NoError(t, c.Init())
+	assert.Len(t, mockHTTP.calls, 6)
+	assert.Equal(t, "http://localhost:8080/api/info", mockHTTP.calls[0].URL.String())
+	assert.Equal(t, "Bearer test-token", mockHTTP.calls[0].Header.Get("Authorization"))
+	assert.Equal(t, 4, c.GetStateVersion())
+}
+
+func TestClient_Init_NewerManager(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().SkipCompatibilityCheck = false
+
+	mockHTTP.expect(makeInfoResponse(`{"version":"1.2.0"}`), nil)
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	assert.NoError(t, c.Init())
+	assert.Equal(t, 4, c.GetStateVersion())
+}
+
+func TestClient_Init_IncompatibleManager(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().SkipCompatibilityCheck = false
+	c.config().MaxManagerVersion = "1.0.0"
+
+	mockHTTP.expect(makeInfoResponse(`{"version":"1.2.0"}`), nil)
+
+	err := c.Init()
+
+	assert.ErrorIs(t, err, ErrIncompatibleManager)
+	assert.Contains(t, err.Error(), "1.2.0")
+	assert.Len(t, mockHTTP.calls, 1)
+	assert.Equal(t, 0, c.GetStateVersion())
+}
+
+func TestClient_Init_InvalidMaxManagerVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().SkipCompatibilityCheck = false
+	c.config().MaxManagerVersion = "next"
+
+	assert.ErrorContains(t, c.Init(), "MaxManagerVersion")
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_Init_UnparsableManagerInfo(t *testing.T) {
+	for _, body := range []string{`<html>Bad Gateway</html>`, `{"version":"latest"}`, `{}`} {
+		t.Run(body, func(t *testing.T) {
+			c, mockHTTP, _ := newTestClient()
+			c.config().SkipCompatibilityCheck = false
+
+			mockHTTP.expect(makeInfoResponse(body), nil)
+
+			assert.ErrorIs(t, c.Init(), ErrIncompatibleManager)
+			assert.Len(t, mockHTTP.calls, 1)
+		})
+	}
+}
+
+func TestClient_Init_ManagerWithoutInfoEndpoint(t *testing.T) {
+	for _, statusCode := range []int{http.StatusNotFound, http.StatusMethodNotAllowed} {
+		t.Run(http.StatusText(statusCode), func(t *testing.T) {
+			c, mockHTTP, _ := newTestClient()
+			c.config().SkipCompatibilityCheck = false
+
+			mockHTTP.expect(makeErrorResponse(statusCode), nil)
+			expectLoad(mockHTTP, testLoad{version: "4"})
+
+			assert.NoError(t, c.Init())
+			assert.Len(t, mockHTTP.calls, 6)
+		})
+	}
+}
+
+func TestClient_Init_ManagerInfoError(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().SkipCompatibilityCheck = false
+
+	mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
+
+	err := c.Init()
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Len(t, mockHTTP.calls, 1)
+}
+
+func TestClient_Init_SkipCompatibilityCheck(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	assert.NoError(t, c.Init())
+	assert.Len(t, mockHTTP.calls, 5)
+	assert.Equal(t, c.config().GetUrlApiVersion(), mockHTTP.calls[0].URL.String())
+}
@@ -1,9 +1,14 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/flectolab/flecto-manager/common/types"
@@ -13,19 +18,303 @@ type HTTPConfig struct {
 	Client                  HTTPClient
 	HeaderAuthorizationName string
 	TokenJWT                string
+	// TokenFile, when set, replaces TokenJWT by the content of the file, e.g. for
+	// rotated Kubernetes service-account tokens. A client re-reads it when its
+	// modification time changes, checked at most every TokenFileRefresh (zero means
+	// DefaultTokenFileRefresh); NewRequest reads it on every call.
+	TokenFile        string
+	TokenFileRefresh time.Duration
+	// SigningKey, when set, signs every request with an HMAC-SHA256 sent in the
+	// X-Signature and X-Timestamp headers (see README). The authorization header is
+	// then only sent if TokenJWT or TokenFile is set too.
+	SigningKey []byte
+	// AuthScheme prefixes the token in the authorization header. Empty means
+	// DefaultAuthScheme, AuthSchemeNone sends the raw token.
+	AuthScheme string
+	// Codec decodes redirects and pages lists. Nil means the codec of AcceptFormat.
+	Codec Codec
+	// AcceptFormat selects the bundled codec used when Codec is nil: AcceptFormatJSON
+	// (the default when empty) or AcceptFormatMsgpack. JSON responses are still
+	// decoded when the manager ignores the Accept header.
+	AcceptFormat string
+	// MaxResponseBytes caps the size of a response body. Zero means DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+	// CorrelationHeader, when set, carries the correlation ID found in the request context.
+	CorrelationHeader string
+	// CorrelationContextKey is the context key holding the correlation ID (a string).
+	// Nil means the key used by WithCorrelationID.
+	CorrelationContextKey any
+	// OnRequest and OnResponse are called around every manager request, e.g. to dump
+	// them while debugging. They receive copies whose bodies may be read freely.
+	OnRequest  func(*http.Request)
+	OnResponse func(*http.Response, error)
+	// EnableTrace times the DNS, connect, TLS and first byte phases of every request
+	// and hands them to Metrics if it implements TraceMetrics, or logs them at debug level.
+	EnableTrace bool
+	// URLRewriter, when set, returns the URL actually requested for each manager
+	// request, given its endpoint and the URL built from the config, e.g. to route
+	// some endpoints to a canary manager.
+	URLRewriter func(endpoint Endpoint, url string) string
+	// SuccessStatusCodes lists the response status codes accepted from the manager.
+	// Empty means only 200.
+	SuccessStatusCodes []int
+}
+
+func (c *HTTPConfig) authorizationValue(token string) string {
+	switch c.AuthScheme {
+	case AuthSchemeNone:
+		return token
+	case "":
+		return fmt.Sprintf("%s %s", DefaultAuthScheme, token)
+	default:
+		return fmt.Sprintf("%s %s", c.AuthScheme, token)
+	}
+}
+
+func (c *HTTPConfig) isSuccess(statusCode int) bool {
+	if len(c.SuccessStatusCodes) == 0 {
+		return statusCode == http.StatusOK
+	}
+	return slices.Contains(c.SuccessStatusCodes, statusCode)
+}
+
+func (c *HTTPConfig) GetMaxResponseBytes() int64 {
+	if c.MaxResponseBytes <= 0 {
+		return DefaultMaxResponseBytes
+	}
+	return c.MaxResponseBytes
 }
 
 type Config struct {
-	ManagerUrl    string
+	ManagerUrl string
+	// ReadManagerUrl serves the version, redirects and pages endpoints. Defaults to ManagerUrl.
+	ReadManagerUrl string
+	// ApiPathPrefix is the path the manager API is mounted on. Empty means "/api", "/" means the root.
+	ApiPathPrefix string
 	NamespaceCode string
 	ProjectCode   string
 
 	AgentName string
 	AgentType types.AgentType
+	// ExtraAgentTypes registers the agent under these types too, e.g. a binary
+	// acting as both a default and a traefik agent: every status is posted once
+	// per type, all sharing the same state.
+	ExtraAgentTypes []types.AgentType
+	// AgentVersion is the version of the agent binary, reported with every status.
+	AgentVersion string
 
 	Http *HTTPConfig
 
+	// VersionParser parses the raw version returned by the manager. Nil means ParseIntVersion.
+	VersionParser func(raw string) (ParsedVersion, error)
+
 	IntervalCheck time.Duration
+	// ClampIntervalCheck brings an IntervalCheck outside of MinAllowedIntervalCheck
+	// and MaxAllowedIntervalCheck back within them, with a warning from Init, instead of
+	// failing Init and Reconfigure.
+	ClampIntervalCheck bool
+	// SkipReloadOnStart makes Start wait a first IntervalCheck before reloading
+	// even when no state has been loaded yet, e.g. by Init.
+	SkipReloadOnStart bool
+	// ReloadDebounce delays triggered reloads by this window, collapsing the
+	// triggers sent meanwhile into one reload. Zero reloads at once.
+	ReloadDebounce time.Duration
+	// TolerateVersionParseErrors treats an invalid or empty version, e.g. an HTML
+	// error page served by a proxy, as no change: the current state is kept and
+	// nothing is reported, for up to MaxVersionParseTolerations consecutive checks
+	// (zero means DefaultMaxVersionParseTolerations).
+	TolerateVersionParseErrors bool
+	MaxVersionParseTolerations int
+	// MaxIntervalCheck caps the interval growth after consecutive reload failures. Zero disables the backoff.
+	MaxIntervalCheck time.Duration
+	// CircuitBreakerThreshold consecutive reload failures open the circuit breaker:
+	// reloads then fail fast with ErrCircuitOpen for CircuitBreakerCooldown before a
+	// single probe is let through. Zero disables the breaker.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+	// MaxReloadDuration bounds the whole state fetch, pagination included. Zero means no limit.
+	MaxReloadDuration time.Duration
+	// MaxConcurrentRequests bounds the manager requests awaiting a response at once,
+	// across all the projects of a MultiClient. Zero means no limit. It is read by
+	// New and NewMulti only.
+	MaxConcurrentRequests int
+	// MaxStaleness is how long reloads may keep failing before Healthy reports false.
+	// Zero disables the check.
+	MaxStaleness time.Duration
+	// ClearStateOnProjectMissing drops the loaded rules when the version endpoint
+	// answers 404 (ErrProjectNotFound). The last state is kept otherwise.
+	ClearStateOnProjectMissing bool
+	// DefaultRedirectStatus is set on redirects fetched without a status, and on a
+	// MaintenanceRedirect without one. Empty means RedirectStatusMovedPermanent.
+	DefaultRedirectStatus types.RedirectStatus
+	// MaintenanceRedirect, when set, is returned by every redirect match while the
+	// client is unhealthy.
+	MaintenanceRedirect *types.Redirect
+	// MaintenancePage, when set, is what MaintenanceResponse returns while the
+	// client is unhealthy, for proxies preferring an explicit page to stale rules.
+	MaintenancePage *types.Page
+
+	// SuppressUnchangedHits skips the agent hit when nothing changed since the last
+	// report and that report is younger than HitInterval (zero means no expiry).
+	SuppressUnchangedHits bool
+	HitInterval           time.Duration
+	// HitIncludesBody sends the agent hit with a JSON body holding the installed
+	// version and the time of the hit, {"version":N,"ts":"..."}, instead of none.
+	HitIncludesBody bool
+	// DetectByContentHash fetches the rules on every check, even when the version did not
+	// move, and installs them when their ContentHash differs from the current state.
+	DetectByContentHash bool
+	// ReuseMatchers applies a reload to a copy of the redirect and page matchers of
+	// the current state, inserting and deleting only the rules that were added,
+	// changed or removed, and keeping the compiled regex of a rule whose target
+	// changed. A matcher whose rules did not change is kept as is. Rule sets with
+	// several rules for one type and source are still rebuilt in full.
+	ReuseMatchers bool
+	// ParallelInsert compiles the regex rules of a rebuilt redirect matcher on
+	// GOMAXPROCS goroutines. The matcher answers exactly like a serial build.
+	// Rule sets with several rules for one type and source are built serially.
+	ParallelInsert bool
+	// ReportReloadProgress sends agent hits while a reload paginates through the
+	// rules, at most every ReloadProgressInterval (zero means
+	// DefaultReloadProgressInterval), so that long loads do not look hung.
+	ReportReloadProgress   bool
+	ReloadProgressInterval time.Duration
+	// StateCacheFile, when set, is where the rules fetched from the manager are
+	// written after every load. Init installs them from that file when its first
+	// load fails, e.g. when starting while the manager is down.
+	StateCacheFile string
+	// StateCacheCompress gzip-compresses StateCacheFile. Reads detect gzip by its
+	// magic bytes, so files written with either setting load.
+	StateCacheCompress bool
+	// SkipCompatibilityCheck stops Init from checking that the manager version is
+	// at least MinManagerVersion and below MaxManagerVersion.
+	SkipCompatibilityCheck bool
+	// MaxManagerVersion, e.g. "2.0.0", makes Init reject managers of this version
+	// or newer. Empty accepts any version from MinManagerVersion.
+	MaxManagerVersion string
+	// ReportLifecycle posts the agent status once Init has loaded the state, unless
+	// its reload already did, and an error status with AgentStoppedError on Close.
+	ReportLifecycle bool
+	// AgentStatusBatchWindow, in a MultiClient, coalesces the agent statuses of all
+	// projects over this window and posts them in one request to the batch endpoint.
+	// Statuses fall back to individual posts if the manager lacks that endpoint.
+	// Zero posts every status on its own.
+	AgentStatusBatchWindow time.Duration
+
+	// RedirectQuery and PageQuery are extra query parameters sent to the list
+	// endpoints, e.g. to let the manager filter by host.
+	RedirectQuery url.Values
+	PageQuery     url.Values
+
+	// RedirectTransform and PageTransform are called on every fetched rule before
+	// it is inserted. Returning false drops the rule; the returned value is inserted otherwise.
+	RedirectTransform func(*types.Redirect) (*types.Redirect, bool)
+	PageTransform     func(*types.Page) (*types.Page, bool)
+	// DetectRedirectLoops skips, with a warning, redirects whose target is matched by
+	// their own source: a relative target, or an absolute one on the host of a host
+	// rule. Regex targets using capture groups are not checked.
+	DetectRedirectLoops bool
+	// MaxTotalRedirects and MaxRedirectsPerHost, when positive, bound the redirects
+	// loaded, protecting the agent memory from a runaway project. Host-specific rules
+	// count against their host, the others against the host "". A load exceeding a
+	// limit fails with ErrRedirectLimit and keeps the current state, unless
+	// TruncateRedirects drops the redirects beyond the limits, in fetch order.
+	MaxTotalRedirects   int
+	MaxRedirectsPerHost int
+	TruncateRedirects   bool
+	// MaxRedirectChainDepth is the number of redirects ResolveRedirectChain follows
+	// (zero means DefaultMaxRedirectChainDepth).
+	MaxRedirectChainDepth int
+	// WarnDuplicates logs the redirects dropped because a later one has the same type and source.
+	WarnDuplicates bool
+
+	// CaseInsensitivePaths matches redirect sources and page paths regardless of
+	// case. Targets, contents and regex captures keep their case.
+	CaseInsensitivePaths bool
+	// DecodePathForMatch percent-decodes request paths and exact redirect sources
+	// and page paths before matching, so that "/caf%C3%A9" and "/café" are the
+	// same path. Escapes of "/", "?", "#", "%", space and control characters are
+	// kept and paths with an invalid escape are matched as is. Regex sources run against the decoded
+	// path, and their captures come from it.
+	DecodePathForMatch bool
+	// TrailingSlashMode tells whether "/about" and "/about/" are distinct paths
+	// (TrailingSlashStrict, the default when empty), the same one
+	// (TrailingSlashIgnore), or whether RedirectMatch answers a 301 to the form a
+	// rule exists for (TrailingSlashRedirect).
+	TrailingSlashMode TrailingSlashMode
+	// NormalizeHost strips the port, the IPv6 brackets and the case of the host
+	// given to the match methods, as found in a Host header behind a proxy.
+	NormalizeHost bool
+
+	// PageCacheControl is the Cache-Control header returned by PageMatchResponse. Empty sends none.
+	PageCacheControl string
+
+	// PageMethods lists the HTTP methods static pages answer to. Empty means any method.
+	PageMethods []string
+	// DisableImplicitHead stops PageMatchMethod from answering HEAD for pages served on GET.
+	DisableImplicitHead bool
+
+	// PagesOptional keeps the previous pages when fetching them fails, instead of aborting the reload.
+	PagesOptional bool
+	// LoadRedirects and LoadPages set to false stop reloads from fetching the
+	// redirects or the pages, for agents that serve only one of them: that
+	// matcher stays empty. Nil means true.
+	LoadRedirects *bool
+	LoadPages     *bool
+	// UseCombinedStateEndpoint fetches the version, redirects and pages of a load
+	// in one request to the state endpoint, falling back to the separate endpoints
+	// when the manager does not have it (404 or 405).
+	UseCombinedStateEndpoint bool
+
+	// VerifyState checks a state before it is installed, e.g. that a critical
+	// redirect is present. On error the current state is kept and the load fails
+	// with ErrStateVerification, reported in the agent status.
+	VerifyState func(state *State) error
+	// OnReload is called after a new state has been installed.
+	OnReload func(diff StateDiff)
+
+	Logger *slog.Logger
+	// LogFields extracts fields, as key-value pairs, from the context a line is
+	// logged with, e.g. a trace ID, added to every line logged while serving that
+	// context: the reloads and fetches it is given to.
+	LogFields func(ctx context.Context) []any
+	Metrics   Metrics
+}
+
+// Bounds of a non-zero Config.IntervalCheck, protecting the manager from agents
+// polling in a tight loop.
+const (
+	MinAllowedIntervalCheck = time.Second
+	MaxAllowedIntervalCheck = 24 * time.Hour
+)
+
+// validateAgentTypes rejects an invalid AgentType or ExtraAgentTypes entry.
+func validateAgentTypes(cfg *Config) error {
+	for _, typ := range cfg.GetAgentTypes() {
+		if !typ.IsValid() {
+			return fmt.Errorf("invalid agent type: %s", typ)
+		}
+	}
+	return nil
+}
+
+// GetAgentTypes returns AgentType followed by the ExtraAgentTypes, without duplicates.
+func (c *Config) GetAgentTypes() []types.AgentType {
+	agentTypes := []types.AgentType{c.AgentType}
+	for _, typ := range c.ExtraAgentTypes {
+		if !slices.Contains(agentTypes, typ) {
+			agentTypes = append(agentTypes, typ)
+		}
+	}
+	return agentTypes
+}
+
+// validateIntervalCheck rejects an IntervalCheck out of bounds, unless ClampIntervalCheck is set.
+func validateIntervalCheck(cfg *Config) error {
+	if cfg.ClampIntervalCheck || cfg.GetIntervalCheck() == cfg.IntervalCheck {
+		return nil
+	}
+	return fmt.Errorf("interval check %s out of range [%s, %s]", cfg.IntervalCheck, MinAllowedIntervalCheck, MaxAllowedIntervalCheck)
 }
 
 func NewDefaultConfig() *Config {
@@ -34,35 +323,166 @@ func NewDefaultConfig() *Config {
 		Http: &HTTPConfig{
 			Client:                  http.DefaultClient,
 			HeaderAuthorizationName: "Authorization",
+			AuthScheme:              DefaultAuthScheme,
+			MaxResponseBytes:        DefaultMaxResponseBytes,
 		},
+		ApiPathPrefix: "/api",
 		AgentName:     name,
 		IntervalCheck: 5 * time.Minute,
+		PageMethods:   []string{http.MethodGet},
+		Logger:        slog.Default(),
 	}
 }
 
+// ParseManagerURL returns a default config for a project URL copied from the
+// manager, such as https://mgr.flecto.io/namespace/prod/project/site, or from its
+// API: a trailing /api segment before the namespace is stripped since GetUrlApi
+// adds it back. Path segments after the project code are ignored. A URL without
+// the namespace and project only sets ManagerUrl, and one with only part of them
+// is rejected.
+func ParseManagerURL(raw string) (*Config, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid manager url %q: missing scheme or host", raw)
+	}
+
+	cfg := NewDefaultConfig()
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	base := segments
+	if i := slices.Index(segments, "namespace"); i >= 0 {
+		if len(segments) < i+4 || segments[i+1] == "" || segments[i+2] != "project" || segments[i+3] == "" {
+			return nil, fmt.Errorf("invalid manager url %q: expected /namespace/<code>/project/<code>", raw)
+		}
+		cfg.NamespaceCode = segments[i+1]
+		cfg.ProjectCode = segments[i+3]
+		base = segments[:i]
+	} else if slices.Contains(segments, "project") {
+		return nil, fmt.Errorf("invalid manager url %q: expected /namespace/<code>/project/<code>", raw)
+	}
+	if len(base) > 0 && base[len(base)-1] == "api" {
+		base = base[:len(base)-1]
+	}
+	cfg.ManagerUrl = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	if path := strings.Join(base, "/"); path != "" {
+		cfg.ManagerUrl += "/" + path
+	}
+	return cfg, nil
+}
+
+func (c *Config) GetReadManagerUrl() string {
+	if c.ReadManagerUrl == "" {
+		return c.ManagerUrl
+	}
+	return c.ReadManagerUrl
+}
+
+// GetIntervalCheck returns IntervalCheck within MinAllowedIntervalCheck and
+// MaxAllowedIntervalCheck. Zero, meaning no background reloads are planned, is kept.
+func (c *Config) GetIntervalCheck() time.Duration {
+	if c.IntervalCheck == 0 {
+		return 0
+	}
+	return min(max(c.IntervalCheck, MinAllowedIntervalCheck), MaxAllowedIntervalCheck)
+}
+
+func (c *Config) GetLoadRedirects() bool {
+	return c.LoadRedirects == nil || *c.LoadRedirects
+}
+
+func (c *Config) GetLoadPages() bool {
+	return c.LoadPages == nil || *c.LoadPages
+}
+
+func (c *Config) GetMaxRedirectChainDepth() int {
+	if c.MaxRedirectChainDepth <= 0 {
+		return DefaultMaxRedirectChainDepth
+	}
+	return c.MaxRedirectChainDepth
+}
+
+func (c *Config) GetMaxVersionParseTolerations() int {
+	if c.MaxVersionParseTolerations <= 0 {
+		return DefaultMaxVersionParseTolerations
+	}
+	return c.MaxVersionParseTolerations
+}
+
+func (c *Config) GetDefaultRedirectStatus() types.RedirectStatus {
+	if c.DefaultRedirectStatus == "" {
+		return types.RedirectStatusMovedPermanent
+	}
+	return c.DefaultRedirectStatus
+}
+
 func (c *Config) GetUrlApi() string {
-	return fmt.Sprintf("%s/api", c.ManagerUrl)
+	return c.urlApi(c.ManagerUrl)
 }
 
 func (c *Config) GetUrlApiProject() string {
-	return fmt.Sprintf("%s/namespace/%s/project/%s", c.GetUrlApi(), c.NamespaceCode, c.ProjectCode)
+	return c.urlApiProject(c.ManagerUrl)
+}
+
+func (c *Config) GetUrlApiReadProject() string {
+	return c.urlApiProject(c.GetReadManagerUrl())
+}
+
+func (c *Config) GetApiPathPrefix() string {
+	if c.ApiPathPrefix == "" {
+		return "/api"
+	}
+	prefix := strings.Trim(c.ApiPathPrefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+func (c *Config) urlApi(managerUrl string) string {
+	return fmt.Sprintf("%s%s", managerUrl, c.GetApiPathPrefix())
+}
+
+func (c *Config) urlApiProject(managerUrl string) string {
+	return fmt.Sprintf("%s/namespace/%s/project/%s", c.urlApi(managerUrl), c.NamespaceCode, c.ProjectCode)
 }
 
 func (c *Config) GetUrlApiVersion() string {
-	return fmt.Sprintf("%s/version", c.GetUrlApiProject())
+	return fmt.Sprintf("%s/version", c.GetUrlApiReadProject())
 }
 
 func (c *Config) GetUrlApiRedirects() string {
-	return fmt.Sprintf("%s/redirects", c.GetUrlApiProject())
+	return fmt.Sprintf("%s/redirects", c.GetUrlApiReadProject())
 }
 
 func (c *Config) GetUrlApiPages() string {
-	return fmt.Sprintf("%s/pages", c.GetUrlApiProject())
+	return fmt.Sprintf("%s/pages", c.GetUrlApiReadProject())
+}
+
+func (c *Config) GetUrlApiRedirect(source string) string {
+	return fmt.Sprintf("%s/redirect?source=%s", c.GetUrlApiReadProject(), url.QueryEscape(source))
+}
+
+func (c *Config) GetUrlApiPage(path string) string {
+	return fmt.Sprintf("%s/page?path=%s", c.GetUrlApiReadProject(), url.QueryEscape(path))
+}
+
+func (c *Config) GetUrlApiState() string {
+	return fmt.Sprintf("%s/state", c.GetUrlApiReadProject())
 }
 func (c *Config) GetUrlApiAgents() string {
 	return fmt.Sprintf("%s/agents", c.GetUrlApiProject())
 }
 
+func (c *Config) GetUrlApiInfo() string {
+	return fmt.Sprintf("%s/info", c.GetUrlApi())
+}
+
+func (c *Config) GetUrlApiAgentsBatch() string {
+	return fmt.Sprintf("%s/agents/batch", c.GetUrlApi())
+}
+
 func (c *Config) GetUrlApiAgentsHit(name string) string {
 	return fmt.Sprintf("%s/%s/hit", c.GetUrlApiAgents(), name)
 }
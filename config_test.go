@@ -1,9 +1,11 @@
 package client
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/flectolab/flecto-manager/common/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,7 +16,12 @@ func TestNewDefaultConfig(t *testing.T) {
 	assert.NotNil(t, cfg.Http)
 	assert.NotNil(t, cfg.Http.Client)
 	assert.Equal(t, "Authorization", cfg.Http.HeaderAuthorizationName)
+	assert.Equal(t, "Bearer", cfg.Http.AuthScheme)
+	assert.Equal(t, DefaultMaxResponseBytes, cfg.Http.MaxResponseBytes)
 	assert.Equal(t, 5*time.Minute, cfg.IntervalCheck)
+	assert.Equal(t, "/api", cfg.ApiPathPrefix)
+	assert.Equal(t, []string{http.MethodGet}, cfg.PageMethods)
+	assert.NotNil(t, cfg.Logger)
 	assert.Empty(t, cfg.ManagerUrl)
 	assert.Empty(t, cfg.NamespaceCode)
 	assert.Empty(t, cfg.ProjectCode)
@@ -299,4 +306,224 @@ func TestConfig_GetUrlApiAgentsHit(t *testing.T) {
 			assert.Equal(t, tt.want, got)
 		})
 	}
-}
\ No newline at end of file
+}
+func TestConfig_GetReadManagerUrl(t *testing.T) {
+	tests := []struct {
+		name           string
+		managerUrl     string
+		readManagerUrl string
+		want           string
+	}{
+		{
+			name:           "defaults to manager url",
+			managerUrl:     "http://primary:8080",
+			readManagerUrl: "",
+			want:           "http://primary:8080",
+		},
+		{
+			name:           "read replica url",
+			managerUrl:     "http://primary:8080",
+			readManagerUrl: "http://replica:8080",
+			want:           "http://replica:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ManagerUrl: tt.managerUrl, ReadManagerUrl: tt.readManagerUrl}
+			assert.Equal(t, tt.want, cfg.GetReadManagerUrl())
+		})
+	}
+}
+
+func TestConfig_ReadManagerUrl_Routing(t *testing.T) {
+	cfg := &Config{
+		ManagerUrl:     "http://primary:8080",
+		ReadManagerUrl: "http://replica:8080",
+		NamespaceCode:  "ns1",
+		ProjectCode:    "proj1",
+	}
+
+	assert.Equal(t, "http://replica:8080/api/namespace/ns1/project/proj1/version", cfg.GetUrlApiVersion())
+	assert.Equal(t, "http://replica:8080/api/namespace/ns1/project/proj1/redirects", cfg.GetUrlApiRedirects())
+	assert.Equal(t, "http://replica:8080/api/namespace/ns1/project/proj1/pages", cfg.GetUrlApiPages())
+	assert.Equal(t, "http://primary:8080/api/namespace/ns1/project/proj1/agents", cfg.GetUrlApiAgents())
+	assert.Equal(t, "http://primary:8080/api/namespace/ns1/project/proj1/agents/my-agent/hit", cfg.GetUrlApiAgentsHit("my-agent"))
+}
+
+func TestHTTPConfig_GetMaxResponseBytes(t *testing.T) {
+	assert.Equal(t, DefaultMaxResponseBytes, (&HTTPConfig{}).GetMaxResponseBytes())
+	assert.Equal(t, int64(1024), (&HTTPConfig{MaxResponseBytes: 1024}).GetMaxResponseBytes())
+}
+
+func TestConfig_GetApiPathPrefix(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiPathPrefix string
+		want          string
+	}{
+		{name: "default", apiPathPrefix: "", want: "/api"},
+		{name: "without slashes", apiPathPrefix: "flecto/api", want: "/flecto/api"},
+		{name: "with slashes", apiPathPrefix: "/flecto/api/", want: "/flecto/api"},
+		{name: "root", apiPathPrefix: "/", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ApiPathPrefix: tt.apiPathPrefix}
+			assert.Equal(t, tt.want, cfg.GetApiPathPrefix())
+		})
+	}
+}
+
+func TestConfig_ApiPathPrefix_UrlBuilders(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiPathPrefix string
+		wantBase      string
+	}{
+		{name: "default prefix", apiPathPrefix: "", wantBase: "http://localhost:8080/api"},
+		{name: "bare prefix", apiPathPrefix: "flecto", wantBase: "http://localhost:8080/flecto"},
+		{name: "slashed prefix", apiPathPrefix: "/flecto/", wantBase: "http://localhost:8080/flecto"},
+		{name: "nested prefix", apiPathPrefix: "/flecto/api", wantBase: "http://localhost:8080/flecto/api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ManagerUrl:    "http://localhost:8080",
+				ApiPathPrefix: tt.apiPathPrefix,
+				NamespaceCode: "ns1",
+				ProjectCode:   "proj1",
+			}
+			project := tt.wantBase + "/namespace/ns1/project/proj1"
+
+			assert.Equal(t, tt.wantBase, cfg.GetUrlApi())
+			assert.Equal(t, project, cfg.GetUrlApiProject())
+			assert.Equal(t, project+"/version", cfg.GetUrlApiVersion())
+			assert.Equal(t, project+"/redirects", cfg.GetUrlApiRedirects())
+			assert.Equal(t, project+"/pages", cfg.GetUrlApiPages())
+			assert.Equal(t, project+"/agents", cfg.GetUrlApiAgents())
+			assert.Equal(t, project+"/agents/my-agent/hit", cfg.GetUrlApiAgentsHit("my-agent"))
+		})
+	}
+}
+
+func TestHTTPConfig_isSuccess(t *testing.T) {
+	strict := &HTTPConfig{}
+	assert.True(t, strict.isSuccess(http.StatusOK))
+	assert.False(t, strict.isSuccess(http.StatusNoContent))
+
+	relaxed := &HTTPConfig{SuccessStatusCodes: []int{http.StatusOK, http.StatusNoContent}}
+	assert.True(t, relaxed.isSuccess(http.StatusNoContent))
+	assert.False(t, relaxed.isSuccess(http.StatusCreated))
+}
+
+func TestConfig_GetDefaultRedirectStatus(t *testing.T) {
+	cfg := &Config{}
+	assert.Equal(t, types.RedirectStatusMovedPermanent, cfg.GetDefaultRedirectStatus())
+
+	cfg.DefaultRedirectStatus = types.RedirectStatusFound
+	assert.Equal(t, types.RedirectStatusFound, cfg.GetDefaultRedirectStatus())
+}
+
+func TestConfig_GetLoadRedirectsAndPages(t *testing.T) {
+	cfg := &Config{}
+	assert.True(t, cfg.GetLoadRedirects())
+	assert.True(t, cfg.GetLoadPages())
+
+	disabled, enabled := false, true
+	cfg.LoadRedirects, cfg.LoadPages = &disabled, &enabled
+	assert.False(t, cfg.GetLoadRedirects())
+	assert.True(t, cfg.GetLoadPages())
+}
+
+func TestConfig_GetIntervalCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{name: "zero", interval: 0, want: 0},
+		{name: "too small", interval: 100 * time.Millisecond, want: MinAllowedIntervalCheck},
+		{name: "in range", interval: time.Minute, want: time.Minute},
+		{name: "lower bound", interval: MinAllowedIntervalCheck, want: MinAllowedIntervalCheck},
+		{name: "too large", interval: 48 * time.Hour, want: MaxAllowedIntervalCheck},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&Config{IntervalCheck: tt.interval}).GetIntervalCheck())
+		})
+	}
+}
+
+func TestValidateIntervalCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		clamp    bool
+		wantErr  string
+	}{
+		{name: "zero", interval: 0},
+		{name: "in range", interval: 5 * time.Minute},
+		{name: "too small", interval: 100 * time.Millisecond, wantErr: "interval check 100ms out of range [1s, 24h0m0s]"},
+		{name: "too large", interval: 48 * time.Hour, wantErr: "interval check 48h0m0s out of range [1s, 24h0m0s]"},
+		{name: "too small clamped", interval: 100 * time.Millisecond, clamp: true},
+		{name: "too large clamped", interval: 48 * time.Hour, clamp: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIntervalCheck(&Config{IntervalCheck: tt.interval, ClampIntervalCheck: tt.clamp})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseManagerURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantUrl  string
+		wantNs   string
+		wantProj string
+		wantErr  bool
+	}{
+		{name: "full url", raw: "https://mgr.flecto.io/namespace/prod/project/site", wantUrl: "https://mgr.flecto.io", wantNs: "prod", wantProj: "site"},
+		{name: "trailing slash", raw: "https://mgr.flecto.io/namespace/prod/project/site/", wantUrl: "https://mgr.flecto.io", wantNs: "prod", wantProj: "site"},
+		{name: "ui sub page and query", raw: "https://mgr.flecto.io/namespace/prod/project/site/redirects?page=2", wantUrl: "https://mgr.flecto.io", wantNs: "prod", wantProj: "site"},
+		{name: "base path and port", raw: "http://localhost:8080/flecto/namespace/ns/project/p", wantUrl: "http://localhost:8080/flecto", wantNs: "ns", wantProj: "p"},
+		{name: "bare url", raw: "https://mgr.flecto.io", wantUrl: "https://mgr.flecto.io"},
+		{name: "bare url with slash", raw: " https://mgr.flecto.io/ ", wantUrl: "https://mgr.flecto.io"},
+		{name: "api url", raw: "https://mgr.flecto.io/api/namespace/prod/project/site/version", wantUrl: "https://mgr.flecto.io", wantNs: "prod", wantProj: "site"},
+		{name: "api url with base path", raw: "http://localhost:8080/flecto/api/namespace/ns/project/p", wantUrl: "http://localhost:8080/flecto", wantNs: "ns", wantProj: "p"},
+		{name: "bare api url", raw: "https://mgr.flecto.io/api/", wantUrl: "https://mgr.flecto.io"},
+		{name: "missing project", raw: "https://mgr.flecto.io/namespace/prod", wantErr: true},
+		{name: "namespace only", raw: "https://mgr.flecto.io/namespace", wantErr: true},
+		{name: "missing project code", raw: "https://mgr.flecto.io/namespace/prod/project", wantErr: true},
+		{name: "missing namespace", raw: "https://mgr.flecto.io/project/site", wantErr: true},
+		{name: "api url missing namespace", raw: "https://mgr.flecto.io/api/project/site", wantErr: true},
+		{name: "empty namespace", raw: "https://mgr.flecto.io/namespace//project/site", wantErr: true},
+		{name: "missing host", raw: "/namespace/prod/project/site", wantErr: true},
+		{name: "unparsable", raw: "http://[::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseManagerURL(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, cfg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantUrl, cfg.ManagerUrl)
+			assert.Equal(t, tt.wantNs, cfg.NamespaceCode)
+			assert.Equal(t, tt.wantProj, cfg.ProjectCode)
+			assert.NotNil(t, cfg.Http)
+			assert.Equal(t, tt.wantUrl+"/api", cfg.GetUrlApi())
+		})
+	}
+}
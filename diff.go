@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// StateDiff summarizes what changed between two states.
+type StateDiff struct {
+	OldVersion       int
+	NewVersion       int
+	AddedRedirects   []string
+	RemovedRedirects []string
+	AddedPages       []string
+	RemovedPages     []string
+}
+
+func (d StateDiff) IsEmpty() bool {
+	return d.OldVersion == d.NewVersion &&
+		len(d.AddedRedirects) == 0 && len(d.RemovedRedirects) == 0 &&
+		len(d.AddedPages) == 0 && len(d.RemovedPages) == 0
+}
+
+func (d StateDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version %d -> %d", d.OldVersion, d.NewVersion)
+	fmt.Fprintf(&b, ", redirects +%d -%d", len(d.AddedRedirects), len(d.RemovedRedirects))
+	fmt.Fprintf(&b, ", pages +%d -%d", len(d.AddedPages), len(d.RemovedPages))
+	return b.String()
+}
+
+// DiffState compares the redirect sources and page paths of two states.
+// A nil state is treated as empty.
+func DiffState(old, new *State) StateDiff {
+	if old == nil {
+		old = &State{}
+	}
+	if new == nil {
+		new = &State{}
+	}
+
+	diff := StateDiff{OldVersion: old.ProjectVersion, NewVersion: new.ProjectVersion}
+	diff.AddedRedirects, diff.RemovedRedirects = diffKeys(redirectSources(old.Redirects), redirectSources(new.Redirects))
+	diff.AddedPages, diff.RemovedPages = diffKeys(pagePaths(old.Pages), pagePaths(new.Pages))
+	return diff
+}
+
+func redirectSources(redirects []types.Redirect) map[string]struct{} {
+	keys := make(map[string]struct{}, len(redirects))
+	for i := range redirects {
+		keys[redirects[i].Source] = struct{}{}
+	}
+	return keys
+}
+
+func pagePaths(pages []types.Page) map[string]struct{} {
+	keys := make(map[string]struct{}, len(pages))
+	for i := range pages {
+		keys[pages[i].Path] = struct{}{}
+	}
+	return keys
+}
+
+func diffKeys(old, new map[string]struct{}) (added, removed []string) {
+	for key := range new {
+		if _, found := old[key]; !found {
+			added = append(added, key)
+		}
+	}
+	for key := range old {
+		if _, found := new[key]; !found {
+			removed = append(removed, key)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffState(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *State
+		new  *State
+		want StateDiff
+	}{
+		{
+			name: "additions",
+			old:  &State{ProjectVersion: 1},
+			new: &State{
+				ProjectVersion: 2,
+				Redirects:      []types.Redirect{{Source: "/b"}, {Source: "/a"}},
+				Pages:          []types.Page{{Path: "/robots.txt"}},
+			},
+			want: StateDiff{OldVersion: 1, NewVersion: 2, AddedRedirects: []string{"/a", "/b"}, AddedPages: []string{"/robots.txt"}},
+		},
+		{
+			name: "removals",
+			old: &State{
+				ProjectVersion: 2,
+				Redirects:      []types.Redirect{{Source: "/a"}, {Source: "/b"}},
+				Pages:          []types.Page{{Path: "/robots.txt"}},
+			},
+			new: &State{
+				ProjectVersion: 3,
+				Redirects:      []types.Redirect{{Source: "/a"}},
+			},
+			want: StateDiff{OldVersion: 2, NewVersion: 3, RemovedRedirects: []string{"/b"}, RemovedPages: []string{"/robots.txt"}},
+		},
+		{
+			name: "no-op",
+			old:  &State{ProjectVersion: 2, Redirects: []types.Redirect{{Source: "/a"}}},
+			new:  &State{ProjectVersion: 2, Redirects: []types.Redirect{{Source: "/a"}}},
+			want: StateDiff{OldVersion: 2, NewVersion: 2},
+		},
+		{
+			name: "nil old state",
+			old:  nil,
+			new:  &State{ProjectVersion: 1, Redirects: []types.Redirect{{Source: "/a"}}},
+			want: StateDiff{OldVersion: 0, NewVersion: 1, AddedRedirects: []string{"/a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DiffState(tt.old, tt.new))
+		})
+	}
+}
+
+func TestStateDiff_IsEmpty(t *testing.T) {
+	assert.True(t, StateDiff{OldVersion: 1, NewVersion: 1}.IsEmpty())
+	assert.False(t, StateDiff{OldVersion: 1, NewVersion: 2}.IsEmpty())
+	assert.False(t, StateDiff{OldVersion: 1, NewVersion: 1, RemovedPages: []string{"/a"}}.IsEmpty())
+}
+
+func TestStateDiff_String(t *testing.T) {
+	diff := StateDiff{OldVersion: 1, NewVersion: 2, AddedRedirects: []string{"/a", "/b"}, RemovedPages: []string{"/c"}}
+	assert.Equal(t, "version 1 -> 2, redirects +2 -0, pages +0 -1", diff.String())
+}
+
+func TestClient_loadState_OnReload(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, Redirects: []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/gone", Target: "/x"}}})
+
+	var diffs []StateDiff
+	c.config().OnReload = func(diff StateDiff) {
+		diffs = append(diffs, diff)
+	}
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/new", Target: "/target", Status: types.RedirectStatusMovedPermanent},
+	}
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []StateDiff{{OldVersion: 1, NewVersion: 2, AddedRedirects: []string{"/new"}, RemovedRedirects: []string{"/gone"}}}, diffs)
+	assert.Equal(t, redirects, c.load().Redirects)
+}
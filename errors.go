@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned when the manager answers with an unexpected status code.
+// Message and Code are filled when the body is a JSON error document.
+type APIError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       []byte
+	Message    string
+	Code       string
+	// RetryAfter is the delay requested by a Retry-After header on a 429 or 503 response.
+	RetryAfter time.Duration
+}
+
+func newAPIError(url string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+		Code    any    `json:"code"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		apiErr.Message = payload.Error
+		if apiErr.Message == "" {
+			apiErr.Message = payload.Message
+		}
+		if payload.Code != nil {
+			apiErr.Code = fmt.Sprint(payload.Code)
+		}
+	}
+
+	return apiErr
+}
+
+// apiError builds an APIError and resolves its Retry-After against the client clock.
+func (c *client) apiError(url string, resp *http.Response, body []byte) *APIError {
+	apiErr := newAPIError(url, resp, body)
+	apiErr.RetryAfter = parseRetryAfter(resp, c.clock.Now())
+	return apiErr
+}
+
+// parseRetryAfter reads Retry-After, in seconds or as an HTTP date, on 429 and 503 responses.
+func parseRetryAfter(resp *http.Response, now time.Time) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// retryAfter returns the Retry-After carried by err, if any.
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// ErrorClass tells how a reload error should be handled.
+type ErrorClass int
+
+const (
+	// ErrorClassNone is the class of a nil error.
+	ErrorClassNone ErrorClass = iota
+	// ErrorClassRetryable errors may go away on their own: network errors,
+	// timeouts, 408, 429 and 5xx responses, an open circuit breaker.
+	ErrorClassRetryable
+	// ErrorClassFatal errors need a change of configuration: 401, 403 and the
+	// other 4xx responses.
+	ErrorClassFatal
+	// ErrorClassCanceled errors come from a context canceled by the caller.
+	ErrorClassCanceled
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassRetryable:
+		return "retryable"
+	case ErrorClassFatal:
+		return "fatal"
+	case ErrorClassCanceled:
+		return "canceled"
+	default:
+		return "none"
+	}
+}
+
+// ClassifyError classifies a client error. Errors it does not know, e.g. a
+// malformed response, are retryable: the next reload may fetch fixed data.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassNone
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusRequestTimeout, apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode >= 500:
+			return ErrorClassRetryable
+		case apiErr.StatusCode >= 400:
+			return ErrorClassFatal
+		}
+		return ErrorClassRetryable
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+	return ErrorClassRetryable
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" && e.Code == "" {
+		return fmt.Sprintf("unexpected status code for %s: %s (%d) %s", e.URL, e.Status, e.StatusCode, e.Body)
+	}
+	if e.Code == "" {
+		return fmt.Sprintf("unexpected status code for %s: %s (%d) %s", e.URL, e.Status, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("unexpected status code for %s: %s (%d) %s [%s]", e.URL, e.Status, e.StatusCode, e.Message, e.Code)
+}
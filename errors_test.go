@@ -0,0 +1,244 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantCode    string
+		wantError   string
+	}{
+		{
+			name:        "plain text body",
+			body:        "internal failure",
+			wantMessage: "",
+			wantCode:    "",
+			wantError:   "unexpected status code for http://localhost/api: Internal Server Error (500) internal failure",
+		},
+		{
+			name:        "json error and code",
+			body:        `{"error":"project not found","code":"NOT_FOUND"}`,
+			wantMessage: "project not found",
+			wantCode:    "NOT_FOUND",
+			wantError:   "unexpected status code for http://localhost/api: Internal Server Error (500) project not found [NOT_FOUND]",
+		},
+		{
+			name:        "json message",
+			body:        `{"message":"token expired"}`,
+			wantMessage: "token expired",
+			wantCode:    "",
+			wantError:   "unexpected status code for http://localhost/api: Internal Server Error (500) token expired",
+		},
+		{
+			name:        "json numeric code",
+			body:        `{"message":"boom","code":500}`,
+			wantMessage: "boom",
+			wantCode:    "500",
+			wantError:   "unexpected status code for http://localhost/api: Internal Server Error (500) boom [500]",
+		},
+		{
+			name:        "json without known fields",
+			body:        `{"foo":"bar"}`,
+			wantMessage: "",
+			wantCode:    "",
+			wantError:   `unexpected status code for http://localhost/api: Internal Server Error (500) {"foo":"bar"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusInternalServerError, Status: "Internal Server Error"}
+
+			apiErr := newAPIError("http://localhost/api", resp, []byte(tt.body))
+
+			assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+			assert.Equal(t, []byte(tt.body), apiErr.Body)
+			assert.Equal(t, tt.wantMessage, apiErr.Message)
+			assert.Equal(t, tt.wantCode, apiErr.Code)
+			assert.EqualError(t, apiErr, tt.wantError)
+		})
+	}
+}
+
+func TestClient_getProjectVersion_APIError(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(&http.Response{
+		StatusCode: http.StatusForbidden,
+		Status:     "Forbidden",
+		Body:       io.NopCloser(bytes.NewBufferString(`{"error":"access denied","code":"FORBIDDEN"}`)),
+	}, nil)
+
+	_, err := c.getProjectVersion(context.Background())
+
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.Equal(t, "access denied", apiErr.Message)
+	assert.Equal(t, "FORBIDDEN", apiErr.Code)
+	assert.Equal(t, c.config().GetUrlApiVersion(), apiErr.URL)
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status int
+		header string
+		want   time.Duration
+	}{
+		{name: "seconds on 429", status: http.StatusTooManyRequests, header: "120", want: 2 * time.Minute},
+		{name: "seconds on 503", status: http.StatusServiceUnavailable, header: " 30 ", want: 30 * time.Second},
+		{name: "http date", status: http.StatusTooManyRequests, header: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{name: "date in the past", status: http.StatusTooManyRequests, header: now.Add(-time.Hour).Format(http.TimeFormat), want: 0},
+		{name: "negative seconds", status: http.StatusTooManyRequests, header: "-5", want: 0},
+		{name: "invalid value", status: http.StatusTooManyRequests, header: "soon", want: 0},
+		{name: "missing header", status: http.StatusTooManyRequests, want: 0},
+		{name: "ignored on other statuses", status: http.StatusInternalServerError, header: "120", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+
+			assert.Equal(t, tt.want, parseRetryAfter(resp, now))
+		})
+	}
+}
+
+func makeRetryAfterResponse(retryAfter string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Status:     "Too Many Requests",
+		Header:     http.Header{"Retry-After": {retryAfter}},
+		Body:       io.NopCloser(bytes.NewBufferString("slow down")),
+	}
+}
+
+func TestClient_getProjectVersion_RetryAfterDate(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	fakeClock := clockwork.NewFakeClockAt(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC))
+	c.clock = fakeClock
+
+	mockHTTP.expect(makeRetryAfterResponse(fakeClock.Now().Add(10*time.Minute).Format(http.TimeFormat)), nil)
+
+	_, err := c.getProjectVersion(context.Background())
+
+	assert.Equal(t, 10*time.Minute, retryAfter(err))
+}
+
+func TestClient_Start_HonorsRetryAfter(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeRetryAfterResponse("1800"), nil)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 1)
+
+	// the regular interval is held back by Retry-After
+	fakeClock.Advance(29 * time.Minute)
+	assert.Len(t, mockHTTP.calls, 1)
+
+	fakeClock.Advance(time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Len(t, mockHTTP.calls, 3)
+
+	cancel()
+	<-done
+}
+
+func TestClient_Start_RetryAfterHoldsBackTrigger(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeRetryAfterResponse("1800"), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+
+	c.TriggerReload()
+	assert.Eventually(t, func() bool { return len(c.trigger) == 0 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	<-done
+	assert.Len(t, mockHTTP.calls, 1)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{name: "nil", err: nil, want: ErrorClassNone},
+		{name: "unauthorized", err: &APIError{StatusCode: http.StatusUnauthorized}, want: ErrorClassFatal},
+		{name: "forbidden", err: &APIError{StatusCode: http.StatusForbidden}, want: ErrorClassFatal},
+		{name: "not found", err: &APIError{StatusCode: http.StatusNotFound}, want: ErrorClassFatal},
+		{name: "request timeout", err: &APIError{StatusCode: http.StatusRequestTimeout}, want: ErrorClassRetryable},
+		{name: "too many requests", err: &APIError{StatusCode: http.StatusTooManyRequests}, want: ErrorClassRetryable},
+		{name: "server error", err: &APIError{StatusCode: http.StatusBadGateway}, want: ErrorClassRetryable},
+		{name: "wrapped api error", err: fmt.Errorf("project ns/shop: %w", &APIError{StatusCode: http.StatusForbidden}), want: ErrorClassFatal},
+		{name: "net error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: ErrorClassRetryable},
+		{name: "url error", err: &url.Error{Op: "Get", URL: "http://localhost", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, want: ErrorClassRetryable},
+		{name: "deadline", err: context.DeadlineExceeded, want: ErrorClassRetryable},
+		{name: "reload budget", err: fmt.Errorf("%w (10s)", ErrReloadBudgetExceeded), want: ErrorClassRetryable},
+		{name: "circuit open", err: ErrCircuitOpen, want: ErrorClassRetryable},
+		{name: "canceled", err: fmt.Errorf("get version: %w", context.Canceled), want: ErrorClassCanceled},
+		{name: "unknown", err: errors.New("invalid character"), want: ErrorClassRetryable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err))
+		})
+	}
+}
+
+func TestErrorClass_String(t *testing.T) {
+	assert.Equal(t, "none", ErrorClassNone.String())
+	assert.Equal(t, "retryable", ErrorClassRetryable.String())
+	assert.Equal(t, "fatal", ErrorClassFatal.String())
+	assert.Equal(t, "canceled", ErrorClassCanceled.String())
+}
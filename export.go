@@ -0,0 +1,102 @@
+package client
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// DefaultExportContentBytes is how much of each page content State.MarshalJSON keeps.
+const DefaultExportContentBytes = 256
+
+// StateExport is a stable, human-readable view of a State, e.g. for an admin endpoint.
+type StateExport struct {
+	Version     int              `json:"version"`
+	ContentHash string           `json:"content_hash,omitempty"`
+	Redirects   []RedirectExport `json:"redirects"`
+	Pages       []PageExport     `json:"pages"`
+}
+
+type RedirectExport struct {
+	Type   types.RedirectType   `json:"type"`
+	Source string               `json:"source"`
+	Target string               `json:"target"`
+	Status types.RedirectStatus `json:"status"`
+}
+
+type PageExport struct {
+	Type          types.PageType        `json:"type"`
+	Path          string                `json:"path"`
+	ContentType   types.PageContentType `json:"content_type"`
+	ContentLength int                   `json:"content_length"`
+	Content       string                `json:"content,omitempty"`
+	Truncated     bool                  `json:"truncated,omitempty"`
+}
+
+// Export returns the rules of the state sorted by type then source or path.
+// Page contents are cut to maxContent bytes; zero omits them and a negative value keeps them whole.
+func (s *State) Export(maxContent int) StateExport {
+	export := StateExport{
+		Version:     s.ProjectVersion,
+		ContentHash: s.ContentHash,
+		Redirects:   make([]RedirectExport, 0, len(s.Redirects)),
+		Pages:       make([]PageExport, 0, len(s.Pages)),
+	}
+	for _, r := range s.Redirects {
+		export.Redirects = append(export.Redirects, RedirectExport{Type: r.Type, Source: r.Source, Target: r.Target, Status: r.Status})
+	}
+	for _, p := range s.Pages {
+		content, truncated := truncateContent(p.Content, maxContent)
+		export.Pages = append(export.Pages, PageExport{
+			Type:          p.Type,
+			Path:          p.Path,
+			ContentType:   p.ContentType,
+			ContentLength: len(p.Content),
+			Content:       content,
+			Truncated:     truncated,
+		})
+	}
+	slices.SortFunc(export.Redirects, func(a, b RedirectExport) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Source, b.Source))
+	})
+	slices.SortFunc(export.Pages, func(a, b PageExport) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Path, b.Path))
+	})
+	return export
+}
+
+// MarshalJSON encodes Export(DefaultExportContentBytes).
+func (s *State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Export(DefaultExportContentBytes))
+}
+
+// StateExport exports the current state, see State.Export.
+func (c *client) StateExport(maxContent int) StateExport {
+	return c.load().Export(maxContent)
+}
+
+// ListRedirects returns a copy of the redirects loaded in the matcher, in the
+// order they were fetched. The tree matchers cannot be walked, so the list comes
+// from State.Redirects, i.e. after transforms and de-duplication.
+func (c *client) ListRedirects() []types.Redirect {
+	return slices.Clone(c.load().Redirects)
+}
+
+// ListPages returns a copy of the pages loaded in the matcher.
+func (c *client) ListPages() []types.Page {
+	return slices.Clone(c.load().Pages)
+}
+
+func truncateContent(content string, max int) (string, bool) {
+	if max < 0 || len(content) <= max {
+		return content, false
+	}
+	cut := content[:max]
+	for len(cut) > 0 && !utf8.ValidString(cut) {
+		cut = cut[:len(cut)-1]
+	}
+	return cut, true
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_Export(t *testing.T) {
+	c, _, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(4, []types.Redirect{
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1", Status: types.RedirectStatusFound},
+		{Type: types.RedirectTypeBasic, Source: "/z", Target: "/1", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/2", Status: types.RedirectStatusMovedPermanent},
+	}, []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}))
+
+	export := c.StateExport(4)
+
+	assert.Equal(t, 4, export.Version)
+	assert.Equal(t, c.load().ContentHash, export.ContentHash)
+	assert.Equal(t, []RedirectExport{
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/2", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeBasic, Source: "/z", Target: "/1", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1", Status: types.RedirectStatusFound},
+	}, export.Redirects)
+	assert.Equal(t, []PageExport{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", ContentType: types.PageContentTypeTextPlain, ContentLength: 13, Content: "User", Truncated: true},
+	}, export.Pages)
+
+	assert.Equal(t, "User-agent: *", c.StateExport(-1).Pages[0].Content)
+	assert.False(t, c.StateExport(-1).Pages[0].Truncated)
+	assert.Empty(t, c.StateExport(0).Pages[0].Content)
+}
+
+func TestState_MarshalJSON(t *testing.T) {
+	c, _, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(2, []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+	}, []types.Page{
+		{Type: types.PageTypeBasic, Path: "/big.txt", Content: strings.Repeat("x", 1000), ContentType: types.PageContentTypeTextPlain},
+	}))
+
+	b, err := json.Marshal(c.load())
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"version":2`)
+	assert.Contains(t, string(b), `{"type":"BASIC","source":"/old","target":"/new","status":"MOVED_PERMANENT"}`)
+	assert.Contains(t, string(b), `"path":"/big.txt"`)
+	assert.Contains(t, string(b), `"content_length":1000`)
+	assert.Contains(t, string(b), `"truncated":true`)
+	assert.NotContains(t, string(b), strings.Repeat("x", DefaultExportContentBytes+1))
+}
+
+func TestState_ExportEmpty(t *testing.T) {
+	b, err := json.Marshal(&State{})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":0,"redirects":[],"pages":[]}`, string(b))
+}
+
+func Test_truncateContent(t *testing.T) {
+	content, truncated := truncateContent("héllo", 2)
+
+	assert.Equal(t, "h", content)
+	assert.True(t, truncated)
+}
+
+func TestClient_ListRedirectsAndPages(t *testing.T) {
+	c, _, _ := newTestClient()
+	assert.Empty(t, c.ListRedirects())
+	assert.Empty(t, c.ListPages())
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/b", Target: "/two", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/one", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/one-again", Status: types.RedirectStatusMovedPermanent},
+	}
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *"}}
+	assert.NoError(t, c.LoadFromData(1, redirects, pages))
+
+	listed := c.ListRedirects()
+	assert.Equal(t, []types.Redirect{redirects[0], redirects[2]}, listed)
+	assert.Equal(t, pages, c.ListPages())
+
+	listed[0].Target = "/mutated"
+	c.ListPages()[0].Content = "mutated"
+	_, target := c.RedirectMatch("example.com", "/b")
+	assert.Equal(t, "/two", target)
+	assert.Equal(t, "/two", c.ListRedirects()[0].Target)
+	assert.Equal(t, "User-agent: *", c.PageMatch("example.com", "/robots.txt").Content)
+	assert.Equal(t, "User-agent: *", c.ListPages()[0].Content)
+}
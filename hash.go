@@ -0,0 +1,34 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"slices"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// ContentHash returns a hex SHA-256 of the redirects and pages that does not
+// depend on the order the manager returned them in.
+func ContentHash(redirects []types.Redirect, pages []types.Page) string {
+	h := sha256.New()
+	writeSorted(h, redirects)
+	h.Write([]byte{0})
+	writeSorted(h, pages)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeSorted[T any](w io.Writer, items []T) {
+	encoded := make([]string, 0, len(items))
+	for _, item := range items {
+		b, _ := json.Marshal(item)
+		encoded = append(encoded, string(b))
+	}
+	slices.Sort(encoded)
+	for _, item := range encoded {
+		_, _ = io.WriteString(w, item)
+		_, _ = w.Write([]byte{'\n'})
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentHash(t *testing.T) {
+	a := types.Redirect{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"}
+	b := types.Redirect{Type: types.RedirectTypeBasic, Source: "/b", Target: "/2"}
+	p := types.Page{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "x"}
+
+	base := ContentHash([]types.Redirect{a, b}, []types.Page{p})
+
+	assert.Len(t, base, 64)
+	assert.Equal(t, base, ContentHash([]types.Redirect{b, a}, []types.Page{p}))
+	assert.NotEqual(t, base, ContentHash([]types.Redirect{a}, []types.Page{p}))
+	assert.NotEqual(t, base, ContentHash([]types.Redirect{a, b}, nil))
+
+	b.Target = "/3"
+	assert.NotEqual(t, base, ContentHash([]types.Redirect{a, b}, []types.Page{p}))
+}
+
+func TestClient_Reload_DetectByContentHash_ContentChanged(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().DetectByContentHash = true
+	assert.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/a"}}, nil))
+
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/b", Status: types.RedirectStatusMovedPermanent}}
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.NoError(t, err)
+	_, target := c.RedirectMatch("example.com", "/old")
+	assert.Equal(t, "/b", target)
+	assert.Equal(t, ContentHash(redirects, []types.Page{}), c.load().ContentHash)
+	assert.Equal(t, "POST", mockHTTP.calls[4].Method)
+	assert.Equal(t, uint64(0), c.Stats().VersionChanges)
+}
+
+func TestClient_Reload_DetectByContentHash_ContentUnchanged(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().DetectByContentHash = true
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/a"}}
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+	state := c.load()
+	reloaded := false
+	c.config().OnReload = func(StateDiff) { reloaded = true }
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.NoError(t, err)
+	assert.Same(t, state, c.load())
+	assert.False(t, reloaded)
+	assert.Equal(t, "PATCH", mockHTTP.calls[4].Method)
+}
+
+func TestClient_loadState_DetectByContentHash_VersionChanged(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().DetectByContentHash = true
+	assert.NoError(t, c.LoadFromData(1, nil, nil))
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 0), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.GetStateVersion())
+}
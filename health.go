@@ -0,0 +1,47 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// markHealth records when a streak of failed reloads started and clears it on success.
+func (c *client) markHealth(err error) {
+	if err == nil {
+		c.staleSince.Store(0)
+		return
+	}
+	c.staleSince.CompareAndSwap(0, c.clock.Now().UnixNano())
+}
+
+// StaleSince returns the time of the first failed reload since the last
+// successful one, or the zero time when the last reload succeeded.
+func (c *client) StaleSince() time.Time {
+	nanos := c.staleSince.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Healthy reports false once reloads have been failing for longer than
+// Config.MaxStaleness. A zero MaxStaleness means always healthy.
+func (c *client) Healthy() bool {
+	staleSince := c.StaleSince()
+	return c.config().MaxStaleness <= 0 || staleSince.IsZero() || c.clock.Since(staleSince) <= c.config().MaxStaleness
+}
+
+// ShouldServeMaintenance reports whether Config.MaintenancePage is set and the
+// client is unhealthy, reloads having failed for longer than Config.MaxStaleness.
+func (c *client) ShouldServeMaintenance() bool {
+	return c.config().MaintenancePage != nil && !c.Healthy()
+}
+
+// MaintenanceResponse returns Config.MaintenancePage, answered with a 503 that
+// caches must not store, while ShouldServeMaintenance.
+func (c *client) MaintenanceResponse() (*PageResponse, bool) {
+	if !c.ShouldServeMaintenance() {
+		return nil, false
+	}
+	return newPageResponse(c.config().MaintenancePage, http.StatusServiceUnavailable, "no-store"), true
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_StaleSince(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	assert.True(t, c.StaleSince().IsZero())
+
+	failedAt := fakeClock.Now()
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	assert.Equal(t, failedAt.UnixNano(), c.StaleSince().UnixNano())
+
+	fakeClock.Advance(time.Minute)
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	assert.Equal(t, failedAt.UnixNano(), c.StaleSince().UnixNano(), "a streak keeps its start time")
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.True(t, c.StaleSince().IsZero())
+}
+
+func TestClient_Healthy(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().MaxStaleness = 10 * time.Minute
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	assert.True(t, c.Healthy())
+
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	assert.True(t, c.Healthy())
+
+	fakeClock.Advance(10 * time.Minute)
+	assert.True(t, c.Healthy())
+
+	fakeClock.Advance(time.Second)
+	assert.False(t, c.Healthy())
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.True(t, c.Healthy())
+}
+
+func TestClient_Healthy_NoMaxStaleness(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	fakeClock.Advance(24 * time.Hour)
+
+	assert.True(t, c.Healthy())
+}
+
+func TestClient_RedirectMatch_MaintenanceRedirect(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	maintenance := &types.Redirect{Type: types.RedirectTypeBasic, Source: "/", Target: "/maintenance", Status: types.RedirectStatusTemporary}
+	c.config().MaxStaleness = time.Minute
+	c.config().MaintenanceRedirect = maintenance
+	assert.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}, nil))
+
+	_, target := c.RedirectMatch("example.com", "/old")
+	assert.Equal(t, "/new", target)
+
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	fakeClock.Advance(2 * time.Minute)
+
+	redirect, target := c.RedirectMatch("example.com", "/old")
+	assert.Same(t, maintenance, redirect)
+	assert.Equal(t, "/maintenance", target)
+	target, status, ok := c.RedirectMatchStatus("example.com", "/anything")
+	assert.True(t, ok)
+	assert.Equal(t, "/maintenance", target)
+	assert.Equal(t, 307, status)
+}
+
+func TestClient_MaintenanceResponse(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().MaxStaleness = 10 * time.Minute
+	c.config().MaintenancePage = &types.Page{Content: "back soon", ContentType: types.PageContentTypeTextPlain}
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	assert.False(t, c.ShouldServeMaintenance())
+	resp, ok := c.MaintenanceResponse()
+	assert.False(t, ok)
+	assert.Nil(t, resp)
+
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	assert.False(t, c.ShouldServeMaintenance())
+
+	fakeClock.Advance(11 * time.Minute)
+	assert.True(t, c.ShouldServeMaintenance())
+	resp, ok = c.MaintenanceResponse()
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "back soon", resp.Body)
+	assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "9", resp.Header.Get("Content-Length"))
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.False(t, c.ShouldServeMaintenance())
+}
+
+func TestClient_ShouldServeMaintenance_NoPage(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().MaxStaleness = time.Minute
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	fakeClock.Advance(time.Hour)
+
+	assert.False(t, c.Healthy())
+	assert.False(t, c.ShouldServeMaintenance())
+}
@@ -1,22 +1,189 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
+const (
+	DefaultMaxResponseBytes int64 = 64 << 20
+	DefaultAuthScheme             = "Bearer"
+	// AuthSchemeNone, as HTTPConfig.AuthScheme, sends the raw token.
+	AuthSchemeNone = "none"
+)
+
+var ErrResponseTooLarge = errors.New("response body too large")
+
 type HTTPClient interface {
 	Do(req *http.Request) (res *http.Response, err error)
 }
 
+// HTTPTuning configures the transport built by NewHTTPConfig. Zero fields keep
+// the value of http.DefaultTransport, and a zero Timeout means no timeout.
+type HTTPTuning struct {
+	Timeout             time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// DisableHTTP2 stops attempting HTTP/2, which http.DefaultTransport does.
+	DisableHTTP2 bool
+}
+
+// DefaultHTTPTuning keeps idle connections around long enough to be reused
+// between polls and attempts HTTP/2.
+func DefaultHTTPTuning() HTTPTuning {
+	return HTTPTuning{
+		Timeout:             30 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     10 * time.Minute,
+	}
+}
+
+func NewHTTPConfig(opts HTTPTuning) *HTTPConfig {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.MaxIdleConns != 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout != 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if opts.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+	}
+
+	return &HTTPConfig{
+		Client:                  &http.Client{Transport: transport, Timeout: opts.Timeout},
+		HeaderAuthorizationName: "Authorization",
+		AuthScheme:              DefaultAuthScheme,
+		MaxResponseBytes:        DefaultMaxResponseBytes,
+	}
+}
+
 func NewRequest(httpCfg *HTTPConfig, method, url string, body io.Reader) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, body)
+	return NewRequestWithContext(context.Background(), httpCfg, method, url, body)
+}
+
+func NewRequestWithContext(ctx context.Context, httpCfg *HTTPConfig, method, url string, body io.Reader) (*http.Request, error) {
+	return buildRequest(ctx, httpCfg, httpCfg.token(), method, url, body, time.Now())
+}
+
+// buildRequest builds a request authorized with token and, with a signing key,
+// signed at now.
+func buildRequest(ctx context.Context, httpCfg *HTTPConfig, token, method, url string, body io.Reader, now time.Time) (*http.Request, error) {
+	var signedBody []byte
+	if len(httpCfg.SigningKey) > 0 {
+		var err error
+		if signedBody, body, err = readSignedBody(body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add(httpCfg.HeaderAuthorizationName, fmt.Sprintf("Bearer %s", httpCfg.TokenJWT))
+	if len(httpCfg.SigningKey) == 0 || httpCfg.hasToken() {
+		req.Header.Add(httpCfg.HeaderAuthorizationName, httpCfg.authorizationValue(token))
+	}
+	if len(httpCfg.SigningKey) > 0 {
+		httpCfg.sign(req, signedBody, now)
+	}
+	if httpCfg.CorrelationHeader != "" {
+		if id := httpCfg.correlationID(ctx); id != "" {
+			req.Header.Set(httpCfg.CorrelationHeader, id)
+		}
+	}
 
 	return req, nil
 }
+
+// newRequest builds a request to endpoint, on the URL returned by
+// HTTPConfig.URLRewriter if set.
+func (c *client) newRequest(ctx context.Context, endpoint Endpoint, method, url string, body io.Reader) (*http.Request, error) {
+	httpCfg := c.config().Http
+	if httpCfg.URLRewriter != nil {
+		url = httpCfg.URLRewriter(endpoint, url)
+	}
+	return buildRequest(ctx, httpCfg, c.token(), method, url, body, c.clock.Now())
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying id, sent to the manager in
+// HTTPConfig.CorrelationHeader.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+func (c *HTTPConfig) correlationID(ctx context.Context) string {
+	var key any = correlationIDKey{}
+	if c.CorrelationContextKey != nil {
+		key = c.CorrelationContextKey
+	}
+	id, _ := ctx.Value(key).(string)
+	return id
+}
+
+// maxDrainBytes bounds what closeBody reads from a body left unread: past it,
+// dropping the connection is cheaper than reading on.
+const maxDrainBytes = 256 << 10
+
+// closeBody drains what is left of a response body and closes it, so that the
+// transport can reuse the connection for the next request.
+func closeBody(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+	_ = resp.Body.Close()
+}
+
+// handleResponse turns a non-success status into an APIError for url and
+// otherwise decodes the body into v, unless v is nil. The body is always
+// drained and closed.
+func (c *client) handleResponse(resp *http.Response, url string, v any) error {
+	defer closeBody(resp)
+
+	if !c.config().Http.isSuccess(resp.StatusCode) {
+		body, _ := io.ReadAll(c.limitBody(resp))
+		return c.apiError(url, resp, body)
+	}
+	if v == nil {
+		return nil
+	}
+	return c.config().Http.ResponseCodec(resp).Decode(c.limitBody(resp), v)
+}
+
+// maxBytesReader fails with ErrResponseTooLarge instead of silently truncating
+// once more than n bytes are read.
+type maxBytesReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func newMaxBytesReader(r io.Reader, limit int64) *maxBytesReader {
+	return &maxBytesReader{r: r, n: limit, limit: limit}
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.n <= 0 {
+		var probe [1]byte
+		n, err := m.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: limit is %d bytes", ErrResponseTooLarge, m.limit)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > m.n {
+		p = p[:m.n]
+	}
+	n, err := m.r.Read(p)
+	m.n -= int64(n)
+	return n, err
+}
@@ -1,9 +1,18 @@
 package client
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/flectolab/flecto-manager/common/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,6 +29,7 @@ func TestNewRequest(t *testing.T) {
 			name: "valid GET request",
 			httpCfg: &HTTPConfig{
 				HeaderAuthorizationName: "Authorization",
+				AuthScheme:              "Bearer",
 				TokenJWT:                "my-jwt-token",
 			},
 			method:     "GET",
@@ -31,6 +41,7 @@ func TestNewRequest(t *testing.T) {
 			name: "valid POST request",
 			httpCfg: &HTTPConfig{
 				HeaderAuthorizationName: "Authorization",
+				AuthScheme:              "Bearer",
 				TokenJWT:                "another-token",
 			},
 			method:     "POST",
@@ -42,6 +53,7 @@ func TestNewRequest(t *testing.T) {
 			name: "custom header name",
 			httpCfg: &HTTPConfig{
 				HeaderAuthorizationName: "X-Custom-Auth",
+				AuthScheme:              "Bearer",
 				TokenJWT:                "custom-token",
 			},
 			method:     "GET",
@@ -53,6 +65,7 @@ func TestNewRequest(t *testing.T) {
 			name: "empty token",
 			httpCfg: &HTTPConfig{
 				HeaderAuthorizationName: "Authorization",
+				AuthScheme:              "Bearer",
 				TokenJWT:                "",
 			},
 			method:     "GET",
@@ -60,10 +73,46 @@ func TestNewRequest(t *testing.T) {
 			wantErr:    false,
 			wantHeader: "Bearer ",
 		},
+		{
+			name: "custom scheme",
+			httpCfg: &HTTPConfig{
+				HeaderAuthorizationName: "Authorization",
+				AuthScheme:              "Token",
+				TokenJWT:                "my-jwt-token",
+			},
+			method:     "GET",
+			url:        "http://localhost/api",
+			wantErr:    false,
+			wantHeader: "Token my-jwt-token",
+		},
+		{
+			name: "no scheme",
+			httpCfg: &HTTPConfig{
+				HeaderAuthorizationName: "X-Api-Key",
+				AuthScheme:              AuthSchemeNone,
+				TokenJWT:                "raw-token",
+			},
+			method:     "GET",
+			url:        "http://localhost/api",
+			wantErr:    false,
+			wantHeader: "raw-token",
+		},
+		{
+			name: "empty scheme defaults to bearer",
+			httpCfg: &HTTPConfig{
+				HeaderAuthorizationName: "Authorization",
+				TokenJWT:                "my-jwt-token",
+			},
+			method:     "GET",
+			url:        "http://localhost/api",
+			wantErr:    false,
+			wantHeader: "Bearer my-jwt-token",
+		},
 		{
 			name: "invalid url",
 			httpCfg: &HTTPConfig{
 				HeaderAuthorizationName: "Authorization",
+				AuthScheme:              "Bearer",
 				TokenJWT:                "token",
 			},
 			method:  "GET",
@@ -74,6 +123,7 @@ func TestNewRequest(t *testing.T) {
 			name: "invalid method with spaces",
 			httpCfg: &HTTPConfig{
 				HeaderAuthorizationName: "Authorization",
+				AuthScheme:              "Bearer",
 				TokenJWT:                "token",
 			},
 			method:  "INVALID METHOD",
@@ -112,4 +162,205 @@ func TestNewRequest_WithBody(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, req)
 	assert.NotNil(t, req.Body)
-}
\ No newline at end of file
+}
+
+func TestNewRequestWithContext(t *testing.T) {
+	httpCfg := &HTTPConfig{
+		HeaderAuthorizationName: "Authorization",
+		AuthScheme:              "Bearer",
+		TokenJWT:                "test-token",
+	}
+	ctx := context.WithValue(context.Background(), struct{}{}, "value")
+
+	req, err := NewRequestWithContext(ctx, httpCfg, "GET", "http://localhost/api", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ctx, req.Context())
+	assert.Equal(t, "Bearer test-token", req.Header.Get("Authorization"))
+}
+
+func TestNewRequestWithContext_CorrelationID(t *testing.T) {
+	type traceKey struct{}
+	tests := []struct {
+		name   string
+		header string
+		key    any
+		ctx    context.Context
+		want   string
+	}{
+		{name: "default key", header: "X-Request-Id", ctx: WithCorrelationID(context.Background(), "abc"), want: "abc"},
+		{name: "custom key", header: "X-Request-Id", key: traceKey{}, ctx: context.WithValue(context.Background(), traceKey{}, "trace-1"), want: "trace-1"},
+		{name: "custom key ignores default", header: "X-Request-Id", key: traceKey{}, ctx: WithCorrelationID(context.Background(), "abc"), want: ""},
+		{name: "no id in context", header: "X-Request-Id", ctx: context.Background(), want: ""},
+		{name: "non string value", header: "X-Request-Id", key: traceKey{}, ctx: context.WithValue(context.Background(), traceKey{}, 42), want: ""},
+		{name: "header not configured", ctx: WithCorrelationID(context.Background(), "abc"), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpCfg := &HTTPConfig{
+				HeaderAuthorizationName: "Authorization",
+				TokenJWT:                "test-token",
+				CorrelationHeader:       tt.header,
+				CorrelationContextKey:   tt.key,
+			}
+
+			req, err := NewRequestWithContext(tt.ctx, httpCfg, "GET", "http://localhost/api", nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, req.Header.Get("X-Request-Id"))
+		})
+	}
+}
+
+func TestMaxBytesReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "under limit", body: "12345", limit: 10, wantErr: false},
+		{name: "exactly at limit", body: "1234567890", limit: 10, wantErr: false},
+		{name: "over limit", body: "12345678901", limit: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(newMaxBytesReader(strings.NewReader(tt.body), tt.limit))
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrResponseTooLarge)
+				assert.Contains(t, err.Error(), "limit is 10 bytes")
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.body, string(got))
+			}
+		})
+	}
+}
+
+func TestNewHTTPConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		opts HTTPTuning
+	}{
+		{name: "default tuning", opts: DefaultHTTPTuning()},
+		{
+			name: "custom tuning",
+			opts: HTTPTuning{
+				Timeout:             5 * time.Second,
+				MaxIdleConns:        4,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     time.Minute,
+				DisableHTTP2:        true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpCfg := NewHTTPConfig(tt.opts)
+
+			assert.Equal(t, "Authorization", httpCfg.HeaderAuthorizationName)
+			assert.Equal(t, DefaultAuthScheme, httpCfg.AuthScheme)
+			assert.Equal(t, DefaultMaxResponseBytes, httpCfg.MaxResponseBytes)
+
+			httpClient, ok := httpCfg.Client.(*http.Client)
+			assert.True(t, ok)
+			assert.Equal(t, tt.opts.Timeout, httpClient.Timeout)
+
+			transport, ok := httpClient.Transport.(*http.Transport)
+			assert.True(t, ok)
+			assert.Equal(t, tt.opts.MaxIdleConns, transport.MaxIdleConns)
+			assert.Equal(t, tt.opts.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+			assert.Equal(t, tt.opts.IdleConnTimeout, transport.IdleConnTimeout)
+			assert.Equal(t, !tt.opts.DisableHTTP2, transport.ForceAttemptHTTP2)
+			assert.NotSame(t, http.DefaultTransport, transport)
+		})
+	}
+}
+
+func TestNewHTTPConfig_ZeroTuning(t *testing.T) {
+	defaults := http.DefaultTransport.(*http.Transport)
+
+	httpClient := NewHTTPConfig(HTTPTuning{}).Client.(*http.Client)
+
+	assert.Zero(t, httpClient.Timeout)
+	transport := httpClient.Transport.(*http.Transport)
+	assert.Equal(t, defaults.MaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, defaults.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, defaults.IdleConnTimeout, transport.IdleConnTimeout)
+	assert.Equal(t, defaults.ForceAttemptHTTP2, transport.ForceAttemptHTTP2)
+	assert.Equal(t, defaults.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	assert.Equal(t, defaults.ExpectContinueTimeout, transport.ExpectContinueTimeout)
+}
+
+func TestClient_newRequest_URLRewriter(t *testing.T) {
+	c, _, _ := newTestClient()
+	httpCfg := c.config().Http
+	httpCfg.AuthScheme = AuthSchemeNone
+	httpCfg.TokenJWT = "token"
+
+	req, err := c.newRequest(context.Background(), EndpointPages, http.MethodGet, "http://localhost/api/pages", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost/api/pages", req.URL.String())
+
+	httpCfg.URLRewriter = func(endpoint Endpoint, url string) string {
+		assert.Equal(t, EndpointPages, endpoint)
+		return "http://canary" + strings.TrimPrefix(url, "http://localhost")
+	}
+	req, err = c.newRequest(context.Background(), EndpointPages, http.MethodGet, "http://localhost/api/pages", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://canary/api/pages", req.URL.String())
+	assert.Equal(t, "token", req.Header.Get("Authorization"))
+}
+
+func TestClient_ReusesConnections(t *testing.T) {
+	// bodies are padded past what the decoders read, so that a connection is
+	// only reused once the client drained them
+	padding := strings.Repeat(" ", 16<<10)
+	var mu sync.Mutex
+	version, conns := 0, 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/version"):
+			version++
+			_, _ = fmt.Fprintf(w, "%d", version)
+		case strings.HasSuffix(r.URL.Path, "/redirects"), strings.HasSuffix(r.URL.Path, "/pages"):
+			_, _ = w.Write([]byte(`{"items":[],"total":0}` + padding))
+		default:
+			_, _ = w.Write([]byte(padding))
+		}
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	cfg := NewDefaultConfig()
+	cfg.ManagerUrl = server.URL
+	cfg.NamespaceCode = "ns"
+	cfg.ProjectCode = "proj"
+	cfg.AgentType = types.AgentTypeDefault
+	cfg.Http = NewHTTPConfig(DefaultHTTPTuning())
+	cfg.Logger = nil
+	c := New(cfg).(*client)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, c.Reload())
+	}
+	assert.NoError(t, c.sendAgentHit(context.Background(), c.config().AgentName))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 6, version)
+	assert.Equal(t, 1, conns)
+}
@@ -0,0 +1,405 @@
+package client
+
+import (
+	"maps"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// redirectIndex matches like types.RedirectTree, which it mirrors rule for rule,
+// but also deletes rules and copies cheaply, so that Config.ReuseMatchers can
+// apply a diff to a copy of the installed matcher. Rules must have unique types
+// and sources. An index shared with a state is never modified in place: clone
+// copies the maps, and buckets are replaced rather than modified.
+type redirectIndex struct {
+	rules     map[redirectKey]*types.Redirect
+	basicHost map[string]*types.Redirect
+	basic     map[string]*types.Redirect
+	regexHost regexIndex
+	regex     regexIndex
+}
+
+type compiledRegex struct {
+	redirect *types.Redirect
+	re       *regexp.Regexp
+}
+
+// regexIndex buckets regex rules by their literal prefix, like the radix trees
+// of types.RedirectTree. Buckets are sorted by source, the order in which
+// BuildRedirectMatcher inserts them.
+type regexIndex struct {
+	buckets map[string][]*compiledRegex
+	root    []*compiledRegex
+	// prefixLens counts the buckets of each prefix length, listed in lens.
+	prefixLens map[int]int
+	lens       []int
+}
+
+func newRedirectIndex() *redirectIndex {
+	return &redirectIndex{
+		rules:     make(map[redirectKey]*types.Redirect),
+		basicHost: make(map[string]*types.Redirect),
+		basic:     make(map[string]*types.Redirect),
+		regexHost: regexIndex{buckets: make(map[string][]*compiledRegex), prefixLens: make(map[int]int)},
+		regex:     regexIndex{buckets: make(map[string][]*compiledRegex), prefixLens: make(map[int]int)},
+	}
+}
+
+func (x *redirectIndex) clone() *redirectIndex {
+	return &redirectIndex{
+		rules:     maps.Clone(x.rules),
+		basicHost: maps.Clone(x.basicHost),
+		basic:     maps.Clone(x.basic),
+		regexHost: x.regexHost.clone(),
+		regex:     x.regex.clone(),
+	}
+}
+
+func (x *regexIndex) clone() regexIndex {
+	return regexIndex{buckets: maps.Clone(x.buckets), root: x.root, prefixLens: maps.Clone(x.prefixLens), lens: x.lens}
+}
+
+// Insert adds r, replacing the rule of the same type and source. A replaced
+// regex rule keeps its compiled regex.
+func (x *redirectIndex) Insert(r *types.Redirect) error {
+	key := redirectKey{r.Type, r.Source}
+	switch r.Type {
+	case types.RedirectTypeBasicHost:
+		x.basicHost[r.Source] = r
+	case types.RedirectTypeBasic:
+		x.basic[r.Source] = r
+	case types.RedirectTypeRegexHost, types.RedirectTypeRegex:
+		index := x.regexIndex(r.Type)
+		if previous, found := x.rules[key]; found {
+			index.replace(previous, r)
+			break
+		}
+		re, err := regexp.Compile(r.Source)
+		if err != nil {
+			return err
+		}
+		index.insert(&compiledRegex{redirect: r, re: re})
+	}
+	x.rules[key] = r
+	return nil
+}
+
+// Delete removes the rule of the type and source of r, and reports whether there was one.
+func (x *redirectIndex) Delete(r *types.Redirect) bool {
+	key := redirectKey{r.Type, r.Source}
+	if _, found := x.rules[key]; !found {
+		return false
+	}
+	delete(x.rules, key)
+	switch r.Type {
+	case types.RedirectTypeBasicHost:
+		delete(x.basicHost, r.Source)
+	case types.RedirectTypeBasic:
+		delete(x.basic, r.Source)
+	case types.RedirectTypeRegexHost, types.RedirectTypeRegex:
+		x.regexIndex(r.Type).delete(r.Source)
+	}
+	return true
+}
+
+func (x *redirectIndex) regexIndex(redirectType types.RedirectType) *regexIndex {
+	if redirectType == types.RedirectTypeRegexHost {
+		return &x.regexHost
+	}
+	return &x.regex
+}
+
+func (x *redirectIndex) Match(host, uri string) (*types.Redirect, string) {
+	hostURI := host + uri
+	if r, found := x.basicHost[hostURI]; found {
+		return r, r.Target
+	}
+	if r, found := x.basic[uri]; found {
+		return r, r.Target
+	}
+	if r, target := x.regexHost.match(hostURI); r != nil {
+		return r, target
+	}
+	return x.regex.match(uri)
+}
+
+func (x *regexIndex) insert(cr *compiledRegex) {
+	prefix := regexLiteralPrefix(cr.redirect.Source)
+	bucket := x.bucket(prefix)
+	// after the rules of an equal source, as a stable insertion would
+	i := sort.Search(len(bucket), func(i int) bool { return bucket[i].redirect.Source > cr.redirect.Source })
+	x.setBucket(prefix, slices.Insert(slices.Clone(bucket), i, cr))
+}
+
+func (x *regexIndex) replace(previous, r *types.Redirect) {
+	prefix := regexLiteralPrefix(r.Source)
+	bucket := slices.Clone(x.bucket(prefix))
+	for i, cr := range bucket {
+		if cr.redirect == previous {
+			bucket[i] = &compiledRegex{redirect: r, re: cr.re}
+		}
+	}
+	x.setBucket(prefix, bucket)
+}
+
+func (x *regexIndex) delete(source string) {
+	prefix := regexLiteralPrefix(source)
+	x.setBucket(prefix, slices.DeleteFunc(slices.Clone(x.bucket(prefix)), func(cr *compiledRegex) bool {
+		return cr.redirect.Source == source
+	}))
+}
+
+func (x *regexIndex) bucket(prefix string) []*compiledRegex {
+	if prefix == "" {
+		return x.root
+	}
+	return x.buckets[prefix]
+}
+
+func (x *regexIndex) setBucket(prefix string, bucket []*compiledRegex) {
+	if prefix == "" {
+		x.root = bucket
+		return
+	}
+	_, existed := x.buckets[prefix]
+	switch {
+	case len(bucket) == 0 && existed:
+		delete(x.buckets, prefix)
+		x.countPrefix(len(prefix), -1)
+	case len(bucket) > 0:
+		x.buckets[prefix] = bucket
+		if !existed {
+			x.countPrefix(len(prefix), 1)
+		}
+	}
+}
+
+func (x *regexIndex) countPrefix(length, delta int) {
+	x.prefixLens[length] += delta
+	switch x.prefixLens[length] {
+	case 0:
+		delete(x.prefixLens, length)
+		x.lens = slices.DeleteFunc(slices.Clone(x.lens), func(l int) bool { return l == length })
+	case delta:
+		x.lens = slices.Clone(x.lens)
+		i, _ := slices.BinarySearch(x.lens, length)
+		x.lens = slices.Insert(x.lens, i, length)
+	}
+}
+
+// match gathers the candidates in the order types.RedirectTree does (buckets by
+// increasing prefix length, then the root bucket) and sorts them the same way,
+// so that ties between sources of equal length resolve identically.
+func (x *regexIndex) match(input string) (*types.Redirect, string) {
+	var candidates []*compiledRegex
+	for _, n := range x.lens {
+		if n > len(input) {
+			break
+		}
+		candidates = append(candidates, x.buckets[input[:n]]...)
+	}
+	candidates = append(candidates, x.root...)
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return len(candidates[i].redirect.Source) > len(candidates[j].redirect.Source)
+	})
+
+	for _, cr := range candidates {
+		if matches := cr.re.FindStringSubmatch(input); matches != nil {
+			return cr.redirect, resolveRegexTarget(cr.redirect.Target, matches)
+		}
+	}
+	return nil, ""
+}
+
+// resolveRegexTarget replaces $1..$9 in target like types.RedirectTree.
+func resolveRegexTarget(target string, matches []string) string {
+	result := target
+	for i := len(matches) - 1; i >= 1; i-- {
+		placeholder := "$" + string(rune('0'+i))
+		result = strings.ReplaceAll(result, placeholder, matches[i])
+	}
+	return result
+}
+
+// regexLiteralPrefix returns the bucket key types.RedirectTree uses for pattern.
+func regexLiteralPrefix(pattern string) string {
+	re, err := syntax.Parse(strings.TrimPrefix(pattern, "^"), syntax.Perl)
+	if err != nil {
+		return ""
+	}
+	return literalPrefix(re)
+}
+
+func literalPrefix(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune)
+	case syntax.OpConcat:
+		var prefix strings.Builder
+	subs:
+		for _, sub := range re.Sub {
+			switch {
+			case sub.Op == syntax.OpLiteral:
+				prefix.WriteString(string(sub.Rune))
+			case sub.Op == syntax.OpCapture && len(sub.Sub) > 0:
+				inner := literalPrefix(sub.Sub[0])
+				if inner == "" {
+					break subs
+				}
+				prefix.WriteString(inner)
+			default:
+				break subs
+			}
+		}
+		return prefix.String()
+	case syntax.OpCapture:
+		if len(re.Sub) > 0 {
+			return literalPrefix(re.Sub[0])
+		}
+	}
+	return ""
+}
+
+// updateRedirectIndex returns previous with the rules of keys added, changed or
+// removed, applied to a copy. It returns previous itself when nothing changed,
+// and ok=false when keys has several rules of the same type and source.
+func updateRedirectIndex(previous *redirectIndex, keys []types.Redirect) (index *redirectIndex, ok bool, err error) {
+	wanted := make(map[redirectKey]struct{}, len(keys))
+	for _, r := range keys {
+		wanted[redirectKey{r.Type, r.Source}] = struct{}{}
+	}
+	if len(wanted) != len(keys) {
+		return nil, false, nil
+	}
+
+	index = previous
+	for i := range keys {
+		if current, found := previous.rules[redirectKey{keys[i].Type, keys[i].Source}]; found && *current == keys[i] {
+			continue
+		}
+		if index == previous {
+			index = previous.clone()
+		}
+		// a rule of its own, so that rules kept by later updates do not retain keys
+		r := keys[i]
+		if err := index.Insert(&r); err != nil {
+			return nil, true, err
+		}
+	}
+	if len(previous.rules) == len(wanted) && index == previous {
+		return previous, true, nil
+	}
+	for key, r := range previous.rules {
+		if _, found := wanted[key]; found {
+			continue
+		}
+		if index == previous {
+			index = previous.clone()
+		}
+		index.Delete(r)
+	}
+	return index, true, nil
+}
+
+type pageKey struct {
+	Type types.PageType
+	Path string
+}
+
+// pageIndex matches like types.PageTree, with the deletion and copies of redirectIndex.
+type pageIndex struct {
+	rules     map[pageKey]*types.Page
+	basicHost map[string]*types.Page
+	basic     map[string]*types.Page
+}
+
+func newPageIndex() *pageIndex {
+	return &pageIndex{
+		rules:     make(map[pageKey]*types.Page),
+		basicHost: make(map[string]*types.Page),
+		basic:     make(map[string]*types.Page),
+	}
+}
+
+func (x *pageIndex) clone() *pageIndex {
+	return &pageIndex{rules: maps.Clone(x.rules), basicHost: maps.Clone(x.basicHost), basic: maps.Clone(x.basic)}
+}
+
+// Insert adds p, replacing the page of the same type and path.
+func (x *pageIndex) Insert(p *types.Page) {
+	switch p.Type {
+	case types.PageTypeBasicHost:
+		x.basicHost[p.Path] = p
+	case types.PageTypeBasic:
+		x.basic[p.Path] = p
+	}
+	x.rules[pageKey{p.Type, p.Path}] = p
+}
+
+// Delete removes the page of the type and path of p, and reports whether there was one.
+func (x *pageIndex) Delete(p *types.Page) bool {
+	key := pageKey{p.Type, p.Path}
+	if _, found := x.rules[key]; !found {
+		return false
+	}
+	delete(x.rules, key)
+	switch p.Type {
+	case types.PageTypeBasicHost:
+		delete(x.basicHost, p.Path)
+	case types.PageTypeBasic:
+		delete(x.basic, p.Path)
+	}
+	return true
+}
+
+func (x *pageIndex) Match(host, uri string) *types.Page {
+	if p, found := x.basicHost[host+uri]; found {
+		return p
+	}
+	if p, found := x.basic[uri]; found {
+		return p
+	}
+	return nil
+}
+
+// updatePageIndex is updateRedirectIndex for pages.
+func updatePageIndex(previous *pageIndex, keys []types.Page) (index *pageIndex, ok bool) {
+	wanted := make(map[pageKey]struct{}, len(keys))
+	for _, p := range keys {
+		wanted[pageKey{p.Type, p.Path}] = struct{}{}
+	}
+	if len(wanted) != len(keys) {
+		return nil, false
+	}
+
+	index = previous
+	for i := range keys {
+		if current, found := previous.rules[pageKey{keys[i].Type, keys[i].Path}]; found && *current == keys[i] {
+			continue
+		}
+		if index == previous {
+			index = previous.clone()
+		}
+		p := keys[i]
+		index.Insert(&p)
+	}
+	if len(previous.rules) == len(wanted) && index == previous {
+		return previous, true
+	}
+	for key, p := range previous.rules {
+		if _, found := wanted[key]; found {
+			continue
+		}
+		if index == previous {
+			index = previous.clone()
+		}
+		index.Delete(p)
+	}
+	return index, true
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomPath(rng *rand.Rand) string {
+	segments := []string{"a", "b", "ab", "blog", "x"}
+	path := ""
+	for n := rng.Intn(3) + 1; n > 0; n-- {
+		path += "/" + segments[rng.Intn(len(segments))]
+	}
+	return path
+}
+
+func randomRedirect(rng *rand.Rand) types.Redirect {
+	path := randomPath(rng)
+	target := fmt.Sprintf("/t%d", rng.Intn(4))
+	switch rng.Intn(6) {
+	case 0:
+		return types.Redirect{Type: types.RedirectTypeBasicHost, Source: "example.com" + path, Target: target}
+	case 1:
+		return types.Redirect{Type: types.RedirectTypeRegex, Source: "^" + path + "/(.*)$", Target: target + "/$1"}
+	case 2:
+		return types.Redirect{Type: types.RedirectTypeRegex, Source: "(" + path + ")(/.*)?", Target: target + "$2"}
+	case 3:
+		return types.Redirect{Type: types.RedirectTypeRegex, Source: "[ab]" + path, Target: target}
+	case 4:
+		return types.Redirect{Type: types.RedirectTypeRegexHost, Source: "^example\\.com" + path + "(.*)$", Target: target + "$1"}
+	}
+	return types.Redirect{Type: types.RedirectTypeBasic, Source: path, Target: target}
+}
+
+func uniqueRedirects(redirects []types.Redirect) []types.Redirect {
+	unique, _ := dedupeRedirects(redirects)
+	return unique
+}
+
+func TestUpdateRedirectIndex_MatchesRedirectTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var inputs []string
+	for i := 0; i < 200; i++ {
+		inputs = append(inputs, randomPath(rng), randomPath(rng)+"/y")
+	}
+
+	index := newRedirectIndex()
+	var keys []types.Redirect
+	hits := 0
+	for step := 0; step < 50; step++ {
+		next := make([]types.Redirect, 0, len(keys)+10)
+		for _, r := range keys {
+			switch rng.Intn(10) {
+			case 0:
+			case 1:
+				r.Target = fmt.Sprintf("/changed%d", step)
+				next = append(next, r)
+			default:
+				next = append(next, r)
+			}
+		}
+		for n := rng.Intn(10); n > 0; n-- {
+			next = append(next, randomRedirect(rng))
+		}
+		keys = uniqueRedirects(next)
+
+		var ok bool
+		var err error
+		index, ok, err = updateRedirectIndex(index, keys)
+		require.True(t, ok)
+		require.NoError(t, err)
+		tree, err := BuildRedirectMatcher(keys)
+		require.NoError(t, err)
+
+		for _, input := range inputs {
+			wantRedirect, wantTarget := tree.Match("example.com", input)
+			gotRedirect, gotTarget := index.Match("example.com", input)
+			require.Equal(t, wantRedirect, gotRedirect, "step %d, input %q", step, input)
+			require.Equal(t, wantTarget, gotTarget, "step %d, input %q", step, input)
+			if gotRedirect != nil {
+				hits++
+			}
+		}
+	}
+	assert.Greater(t, hits, 1000)
+}
+
+func TestUpdateRedirectIndex_CopyOnWrite(t *testing.T) {
+	keys := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/2024/(.*)$", Target: "/archive/$1"},
+	}
+	previous, _, err := updateRedirectIndex(newRedirectIndex(), keys)
+	require.NoError(t, err)
+
+	next, ok, err := updateRedirectIndex(previous, []types.Redirect{
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/posts/$1"},
+		{Type: types.RedirectTypeBasic, Source: "/added", Target: "/here"},
+	})
+
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	_, target := previous.Match("example.com", "/blog/2024/x")
+	assert.Equal(t, "/archive/x", target)
+	_, target = previous.Match("example.com", "/old")
+	assert.Equal(t, "/new", target)
+	redirect, _ := previous.Match("example.com", "/added")
+	assert.Nil(t, redirect)
+
+	_, target = next.Match("example.com", "/blog/2024/x")
+	assert.Equal(t, "/posts/2024/x", target)
+	redirect, _ = next.Match("example.com", "/old")
+	assert.Nil(t, redirect)
+	_, target = next.Match("example.com", "/added")
+	assert.Equal(t, "/here", target)
+	assert.Same(t, previous.regex.buckets["/blog/"][0].re, next.regex.buckets["/blog/"][0].re)
+}
+
+func TestUpdateRedirectIndex_Unchanged(t *testing.T) {
+	keys := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}
+	previous, _, _ := updateRedirectIndex(newRedirectIndex(), keys)
+
+	next, ok, err := updateRedirectIndex(previous, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}})
+
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Same(t, previous, next)
+}
+
+func TestUpdateRedirectIndex_DuplicateKeys(t *testing.T) {
+	_, ok, err := updateRedirectIndex(newRedirectIndex(), []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/a"},
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/b"},
+	})
+
+	assert.False(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestUpdateRedirectIndex_InvalidRegex(t *testing.T) {
+	_, ok, err := updateRedirectIndex(newRedirectIndex(), []types.Redirect{{Type: types.RedirectTypeRegex, Source: "([", Target: "/a"}})
+
+	assert.True(t, ok)
+	assert.Error(t, err)
+}
+
+func TestRedirectIndex_Delete(t *testing.T) {
+	index := newRedirectIndex()
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasicHost, Source: "example.com/a", Target: "/1"},
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/2"},
+		{Type: types.RedirectTypeRegexHost, Source: "^example\\.com/a(.*)$", Target: "/3"},
+		{Type: types.RedirectTypeRegex, Source: "^/a(.*)$", Target: "/4"},
+		{Type: types.RedirectTypeRegex, Source: ".*", Target: "/5"},
+	}
+	for i := range redirects {
+		require.NoError(t, index.Insert(&redirects[i]))
+	}
+
+	for _, want := range []string{"/1", "/2", "/3", "/4", "/5"} {
+		_, target := index.Match("example.com", "/a")
+		assert.Equal(t, want, target)
+		r, _ := index.Match("example.com", "/a")
+		assert.True(t, index.Delete(r))
+	}
+
+	redirect, _ := index.Match("example.com", "/a")
+	assert.Nil(t, redirect)
+	assert.False(t, index.Delete(&redirects[0]))
+	assert.Empty(t, index.rules)
+	assert.Empty(t, index.regex.buckets)
+	assert.Empty(t, index.regex.lens)
+	assert.Empty(t, index.regex.root)
+}
+
+func TestUpdatePageIndex(t *testing.T) {
+	previous, _ := updatePageIndex(newPageIndex(), []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v1"},
+		{Type: types.PageTypeBasicHost, Path: "example.com/ads.txt", Content: "ads"},
+	})
+
+	next, ok := updatePageIndex(previous, []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v2"},
+		{Type: types.PageTypeBasic, Path: "/humans.txt", Content: "hi"},
+	})
+
+	assert.True(t, ok)
+	assert.Equal(t, "v1", previous.Match("example.com", "/robots.txt").Content)
+	assert.NotNil(t, previous.Match("example.com", "/ads.txt"))
+	assert.Nil(t, previous.Match("example.com", "/humans.txt"))
+	assert.Equal(t, "v2", next.Match("example.com", "/robots.txt").Content)
+	assert.Nil(t, next.Match("example.com", "/ads.txt"))
+	assert.Equal(t, "hi", next.Match("example.com", "/humans.txt").Content)
+
+	unchanged, _ := updatePageIndex(next, []types.Page{
+		{Type: types.PageTypeBasic, Path: "/humans.txt", Content: "hi"},
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v2"},
+	})
+	assert.Same(t, next, unchanged)
+}
+
+func TestUpdatePageIndex_DuplicateKeys(t *testing.T) {
+	_, ok := updatePageIndex(newPageIndex(), []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v1"},
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v2"},
+	})
+
+	assert.False(t, ok)
+}
+
+func TestPageIndex_Delete(t *testing.T) {
+	index := newPageIndex()
+	page := types.Page{Type: types.PageTypeBasic, Path: "/robots.txt"}
+	index.Insert(&page)
+
+	assert.True(t, index.Delete(&page))
+	assert.False(t, index.Delete(&page))
+	assert.Nil(t, index.Match("example.com", "/robots.txt"))
+}
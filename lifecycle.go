@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// reportLifecycle posts the agent status outside of a reload, when
+// Config.ReportLifecycle is set. The manager only knows the success and error
+// statuses, and rejects version 0, so a client without state reports nothing.
+// A status the last post already reported, e.g. the success of the reload run by
+// Init, is not sent again.
+func (c *client) reportLifecycle(ctx context.Context, event agentEvent) error {
+	if !c.config().ReportLifecycle {
+		return nil
+	}
+	version := c.load().ProjectVersion
+	if version == 0 {
+		return nil
+	}
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	status, err := c.transition(event)
+	if err != nil {
+		return err
+	}
+	agent := types.Agent{Name: c.config().AgentName, Type: c.config().AgentType, Version: version, Status: status}
+	if event == agentEventStopped {
+		agent.Error = AgentStoppedError
+	}
+	if c.postedFingerprint(agent) == c.lastPosted {
+		return nil
+	}
+	return c.reportStatus(ctx, agent)
+}
+
+func (c *client) closedChan() chan struct{} {
+	c.closeInit.Do(func() {
+		c.closed = make(chan struct{})
+	})
+	return c.closed
+}
+
+func (c *client) readyChan() chan struct{} {
+	c.readyInit.Do(func() {
+		c.ready = make(chan struct{})
+	})
+	return c.ready
+}
+
+func (c *client) markReady() {
+	c.readyOnce.Do(func() {
+		close(c.readyChan())
+	})
+}
+
+func (c *client) isReady() bool {
+	select {
+	case <-c.readyChan():
+		return true
+	default:
+		return false
+	}
+}
+
+// reloadOnStart reports whether Start must reload right away rather than after
+// a first IntervalCheck: nothing has been loaded yet and Config.SkipReloadOnStart
+// is not set.
+func (c *client) reloadOnStart() bool {
+	return !c.config().SkipReloadOnStart && !c.isReady()
+}
+
+// WaitReady blocks until a state has been loaded from the manager or by
+// LoadFromData, whether by Init, Warmup, Reload or Start, or until ctx is done.
+func (c *client) WaitReady(ctx context.Context) error {
+	select {
+	case <-c.readyChan():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsInitialized reports whether a state has been loaded, i.e. whether WaitReady
+// would return right away. Until then the matchers match nothing.
+func (c *client) IsInitialized() bool {
+	return c.isReady()
+}
+
+// Close stops the Start loop. Matchers keep serving the last state and Reload
+// still works. With Config.ReportLifecycle, the first Close posts an error status
+// with AgentStoppedError, upstream having no stopped status.
+func (c *client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closedChan())
+		err = c.reportLifecycle(context.Background(), agentEventStopped)
+	})
+	return err
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func agentPosts(t *testing.T, mockHTTP *mockHTTPClient, url string) []types.Agent {
+	t.Helper()
+	var agents []types.Agent
+	for _, call := range mockHTTP.calls {
+		if call.Method != http.MethodPost || call.URL.String() != url {
+			continue
+		}
+		body, _ := io.ReadAll(call.Body)
+		var agent types.Agent
+		assert.NoError(t, json.Unmarshal(body, &agent))
+		agents = append(agents, agent)
+	}
+	return agents
+}
+
+func TestClient_Init_ReportLifecycle(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ReportLifecycle = true
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	err := c.Init()
+
+	assert.NoError(t, err)
+	assert.Len(t, mockHTTP.calls, 5)
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	assert.Len(t, posts, 1)
+	assert.Equal(t, types.AgentStatusSuccess, posts[0].Status)
+	assert.Equal(t, 4, posts[0].Version)
+}
+
+func TestClient_Init_ReportLifecycle_UnchangedVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ReportLifecycle = true
+	assert.NoError(t, c.LoadFromData(4, nil, nil))
+
+	mockHTTP.expect(makeVersionResponse("4"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Init()
+
+	assert.NoError(t, err)
+	assert.Len(t, mockHTTP.calls, 3)
+	assert.Equal(t, http.MethodPatch, mockHTTP.calls[1].Method)
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	assert.Len(t, posts, 1)
+	assert.Equal(t, types.AgentStatusSuccess, posts[0].Status)
+	assert.Equal(t, 4, posts[0].Version)
+}
+
+func TestClient_Close_ReportLifecycle(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ReportLifecycle = true
+	expectLoad(mockHTTP, testLoad{version: "4"})
+	assert.NoError(t, c.Init())
+
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	assert.Len(t, posts, 2)
+	assert.Equal(t, types.AgentStatusError, posts[1].Status)
+	assert.Equal(t, AgentStoppedError, posts[1].Error)
+	assert.Equal(t, 4, posts[1].Version)
+	assert.Equal(t, types.AgentStatusSuccess, c.agentStatus)
+}
+
+func TestClient_Close_NoLifecycleByDefault(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	expectLoad(mockHTTP, testLoad{version: "4"})
+	assert.NoError(t, c.Init())
+
+	assert.NoError(t, c.Close())
+
+	assert.Len(t, mockHTTP.calls, 5)
+}
+
+func TestClient_Init_NoLifecycleByDefault(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	err := c.Init()
+
+	assert.NoError(t, err)
+	assert.Len(t, mockHTTP.calls, 5)
+}
+
+func TestClient_reportLifecycle_NoState(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().ReportLifecycle = true
+
+	err := c.reportLifecycle(context.Background(), agentEventReady)
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_Close_StopsStart(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	done := make(chan struct{})
+	go func() {
+		c.Start(context.Background())
+		close(done)
+	}()
+
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not exit after Close")
+	}
+	assert.Empty(t, mockHTTP.calls)
+}
+
+func TestClient_WaitReady_Start(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+
+	mockHTTP.expect(nil, errors.New("connection refused"))
+	expectLoad(mockHTTP, testLoad{version: "4"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Start(ctx)
+
+	ready := make(chan error, 1)
+	go func() { ready <- c.WaitReady(context.Background()) }()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	select {
+	case <-ready:
+		t.Fatal("WaitReady returned after a failed reload")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fakeClock.Advance(5 * time.Minute)
+	select {
+	case err := <-ready:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("WaitReady did not return after the first load")
+	}
+	assert.Equal(t, 4, c.GetStateVersion())
+	assert.NoError(t, c.WaitReady(context.Background()))
+}
+
+func TestClient_WaitReady_LoadFromData(t *testing.T) {
+	c, _, _ := newTestClient()
+
+	assert.NoError(t, c.LoadFromData(2, nil, nil))
+
+	assert.NoError(t, c.WaitReady(context.Background()))
+}
+
+func TestClient_IsInitialized(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	mockHTTP.expect(nil, errors.New("connection refused"))
+
+	assert.False(t, c.IsInitialized())
+	assert.Error(t, c.Init())
+	assert.False(t, c.IsInitialized())
+
+	assert.NoError(t, c.LoadFromData(1, nil, nil))
+	assert.True(t, c.IsInitialized())
+}
+
+func TestNew_MatchBeforeInit(t *testing.T) {
+	c := New(NewDefaultConfig())
+
+	assert.False(t, c.IsInitialized())
+	redirect, target := c.RedirectMatch("example.com", "/old")
+	assert.Nil(t, redirect)
+	assert.Empty(t, target)
+	assert.Nil(t, c.PageMatch("example.com", "/robots.txt"))
+	assert.Equal(t, 0, c.GetStateVersion())
+}
+
+func TestClient_load_NothingStored(t *testing.T) {
+	c := &client{clock: clockwork.NewFakeClock()}
+	c.cfg.Store(&Config{})
+
+	state := c.load()
+
+	assert.NotNil(t, state.RedirectMatcher)
+	assert.NotNil(t, state.PageMatcher)
+	assert.Equal(t, 0, c.GetStateVersion())
+	redirect, _ := c.RedirectMatch("example.com", "/old")
+	assert.Nil(t, redirect)
+	assert.Nil(t, c.PageMatch("example.com", "/robots.txt"))
+	assert.False(t, c.IsInitialized())
+}
+
+func TestClient_WaitReady_ContextDone(t *testing.T) {
+	c, _, _ := newTestClient()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := c.WaitReady(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// ErrRedirectLimit is returned when the redirects exceed Config.MaxRedirectsPerHost
+// or Config.MaxTotalRedirects.
+var ErrRedirectLimit = errors.New("redirect limit exceeded")
+
+// limitRedirects enforces Config.MaxTotalRedirects and Config.MaxRedirectsPerHost
+// on the redirects about to be loaded. With Config.TruncateRedirects, the
+// redirects beyond a limit are dropped, in fetch order, with a warning.
+func (c *client) limitRedirects(ctx context.Context, redirects []types.Redirect) ([]types.Redirect, error) {
+	cfg := c.config()
+	if cfg.MaxTotalRedirects <= 0 && cfg.MaxRedirectsPerHost <= 0 {
+		return redirects, nil
+	}
+	perHost := make(map[string]int)
+	// redirects is owned by the caller building the state: filter it in place
+	kept := redirects[:0]
+	var firstDropped error
+	dropped := 0
+	for _, r := range redirects {
+		host := redirectHost(&r)
+		overTotal := cfg.MaxTotalRedirects > 0 && len(kept) >= cfg.MaxTotalRedirects
+		overHost := cfg.MaxRedirectsPerHost > 0 && perHost[host] >= cfg.MaxRedirectsPerHost
+		if !overTotal && !overHost {
+			perHost[host]++
+			kept = append(kept, r)
+			continue
+		}
+		if dropped == 0 || !cfg.TruncateRedirects {
+			if overTotal {
+				firstDropped = fmt.Errorf("%w: more than %d redirects", ErrRedirectLimit, cfg.MaxTotalRedirects)
+			} else {
+				firstDropped = fmt.Errorf("%w: more than %d redirects for host %q", ErrRedirectLimit, cfg.MaxRedirectsPerHost, host)
+			}
+			if !cfg.TruncateRedirects {
+				return nil, firstDropped
+			}
+		}
+		dropped++
+	}
+	if dropped > 0 {
+		c.logger().WarnContext(ctx, "dropped redirects over the limits", "dropped", dropped, "kept", len(kept), "error", firstDropped)
+	}
+	return kept, nil
+}
+
+// redirectHost returns the lowercase host a BASIC_HOST or REGEX_HOST rule applies
+// to, or "" for rules applying to every host and regexes whose host is not a
+// literal.
+func redirectHost(r *types.Redirect) string {
+	var source string
+	switch r.Type {
+	case types.RedirectTypeBasicHost:
+		source = r.Source
+	case types.RedirectTypeRegexHost:
+		source = regexLiteralPrefix(r.Source)
+	default:
+		return ""
+	}
+	host, _, found := strings.Cut(source, "/")
+	if !found {
+		return ""
+	}
+	return strings.ToLower(host)
+}
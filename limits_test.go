@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func limitTestRedirects() []types.Redirect {
+	return []types.Redirect{
+		{Type: types.RedirectTypeBasicHost, Source: "shop.example.com/a", Target: "/1"},
+		{Type: types.RedirectTypeBasic, Source: "/b", Target: "/2"},
+		{Type: types.RedirectTypeRegexHost, Source: `^Shop\.example\.com/c/(.*)$`, Target: "/3"},
+		{Type: types.RedirectTypeBasicHost, Source: "blog.example.com/d", Target: "/4"},
+		{Type: types.RedirectTypeBasicHost, Source: "shop.example.com/e", Target: "/5"},
+	}
+}
+
+func TestRedirectHost(t *testing.T) {
+	tests := []struct {
+		redirect types.Redirect
+		want     string
+	}{
+		{redirect: types.Redirect{Type: types.RedirectTypeBasicHost, Source: "Example.com/a"}, want: "example.com"},
+		{redirect: types.Redirect{Type: types.RedirectTypeRegexHost, Source: `^example\.com/(.*)$`}, want: "example.com"},
+		{redirect: types.Redirect{Type: types.RedirectTypeRegexHost, Source: `^(www\.)?example\.com/(.*)$`}, want: ""},
+		{redirect: types.Redirect{Type: types.RedirectTypeBasic, Source: "/a"}, want: ""},
+		{redirect: types.Redirect{Type: types.RedirectTypeRegex, Source: "^/a/(.*)$"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.redirect.Source, func(t *testing.T) {
+			assert.Equal(t, tt.want, redirectHost(&tt.redirect))
+		})
+	}
+}
+
+func TestClient_limitRedirects(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		perHost   int
+		truncate  bool
+		wantErr   string
+		wantKept  []string
+		wantWarns bool
+	}{
+		{name: "no limits", wantKept: []string{"/1", "/2", "/3", "/4", "/5"}},
+		{name: "within limits", total: 5, perHost: 3, wantKept: []string{"/1", "/2", "/3", "/4", "/5"}},
+		{name: "total exceeded", total: 4, wantErr: "redirect limit exceeded: more than 4 redirects"},
+		{name: "per host exceeded", perHost: 2, wantErr: `redirect limit exceeded: more than 2 redirects for host "shop.example.com"`},
+		{name: "total truncated", total: 3, truncate: true, wantKept: []string{"/1", "/2", "/3"}, wantWarns: true},
+		{name: "per host truncated", perHost: 2, truncate: true, wantKept: []string{"/1", "/2", "/3", "/4"}, wantWarns: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _ := newTestClient()
+			c.config().MaxTotalRedirects = tt.total
+			c.config().MaxRedirectsPerHost = tt.perHost
+			c.config().TruncateRedirects = tt.truncate
+			var logs bytes.Buffer
+			c.config().Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+			kept, err := c.limitRedirects(context.Background(), limitTestRedirects())
+
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrRedirectLimit)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			var targets []string
+			for _, r := range kept {
+				targets = append(targets, r.Target)
+			}
+			assert.Equal(t, tt.wantKept, targets)
+			assert.Equal(t, tt.wantWarns, bytes.Contains(logs.Bytes(), []byte("dropped redirects over the limits")))
+		})
+	}
+}
+
+func TestClient_Reload_RedirectLimitKeepsState(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().MaxRedirectsPerHost = 1
+	assert.NoError(t, c.LoadFromData(1, nil, nil))
+	previous := c.load()
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(limitTestRedirects(), 5), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.ErrorIs(t, err, ErrRedirectLimit)
+	assert.Same(t, previous, c.load())
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	assert.Len(t, posts, 1)
+	assert.Equal(t, types.AgentStatusError, posts[0].Status)
+	assert.Contains(t, posts[0].Error, "redirect limit exceeded")
+}
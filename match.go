@@ -0,0 +1,303 @@
+package client
+
+import (
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// MatchSource tells whether a match came from a host-specific rule or a rule
+// applying to every host.
+type MatchSource int
+
+const (
+	MatchSourceNone MatchSource = iota
+	MatchSourceExact
+	MatchSourceWildcard
+)
+
+func (s MatchSource) String() string {
+	switch s {
+	case MatchSourceExact:
+		return "exact"
+	case MatchSourceWildcard:
+		return "wildcard"
+	default:
+		return "none"
+	}
+}
+
+// RedirectMatchResult describes a redirect lookup for debugging purposes.
+type RedirectMatchResult struct {
+	Redirect *types.Redirect
+	Target   string
+	Source   MatchSource
+	// Precedence is the RedirectPrecedence of the winning rule, zero without a match.
+	Precedence int
+}
+
+// IsAbsoluteTarget reports whether Target names its host, with a scheme
+// ("https://other.com/new") or protocol-relative ("//other.com/new"), rather
+// than being relative to the request host ("/new").
+func (r RedirectMatchResult) IsAbsoluteTarget() bool {
+	if strings.HasPrefix(r.Target, "//") {
+		return true
+	}
+	target, err := url.Parse(r.Target)
+	return err == nil && target.IsAbs()
+}
+
+// ResolveTarget returns the Location header value to answer a request on
+// requestHost over scheme, "http" when empty, with: an absolute target is kept,
+// a protocol-relative one inherits scheme, and a relative one is put on
+// requestHost. It returns "" without a match.
+func (r RedirectMatchResult) ResolveTarget(requestHost, scheme string) string {
+	if r.Redirect == nil {
+		return ""
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+	target, err := url.Parse(r.Target)
+	switch {
+	case err != nil:
+		// not a valid reference: only prefix the request origin
+		return scheme + "://" + requestHost + "/" + strings.TrimPrefix(r.Target, "/")
+	case target.IsAbs():
+		return r.Target
+	case target.Host != "":
+		return scheme + ":" + r.Target
+	}
+	base := &url.URL{Scheme: scheme, Host: requestHost, Path: "/"}
+	return base.ResolveReference(target).String()
+}
+
+// RedirectPrecedence ranks redirect types when several rules match the same URI:
+// the higher rank wins. Host-specific exact paths come first, then exact paths,
+// then host-specific regexes, then regexes. Among regexes of one type the longest
+// source wins, ties being broken by a fixed order independent of the fetch order.
+func RedirectPrecedence(typ types.RedirectType) int {
+	switch typ {
+	case types.RedirectTypeBasicHost:
+		return 4
+	case types.RedirectTypeBasic:
+		return 3
+	case types.RedirectTypeRegexHost:
+		return 2
+	case types.RedirectTypeRegex:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// PageMatchResult describes a page lookup for debugging purposes.
+type PageMatchResult struct {
+	Page   *types.Page
+	Source MatchSource
+}
+
+func redirectMatchSource(redirect *types.Redirect) MatchSource {
+	if redirect == nil {
+		return MatchSourceNone
+	}
+	switch redirect.Type {
+	case types.RedirectTypeBasicHost, types.RedirectTypeRegexHost:
+		return MatchSourceExact
+	default:
+		return MatchSourceWildcard
+	}
+}
+
+func pageMatchSource(page *types.Page) MatchSource {
+	if page == nil {
+		return MatchSourceNone
+	}
+	if page.Type == types.PageTypeBasicHost {
+		return MatchSourceExact
+	}
+	return MatchSourceWildcard
+}
+
+func (c *client) RedirectMatchDebug(host, uri string) RedirectMatchResult {
+	redirect, target := c.RedirectMatch(host, uri)
+	result := RedirectMatchResult{Redirect: redirect, Target: target, Source: redirectMatchSource(redirect)}
+	if redirect != nil {
+		result.Precedence = RedirectPrecedence(redirect.Type)
+	}
+	return result
+}
+
+func (c *client) PageMatchDebug(host, uri string) PageMatchResult {
+	page := c.PageMatch(host, uri)
+	return PageMatchResult{Page: page, Source: pageMatchSource(page)}
+}
+
+// RedirectMatchFull matches a request URI with its query string, as returned by
+// http.Request.RequestURI. Rules are matched on the path only, except those
+// whose source spells a query string ("/list?page=2", or `\?` in a regex): they
+// are tried first against the path and the query. The fragment is ignored.
+func (c *client) RedirectMatchFull(host, fullURI string) (*types.Redirect, string) {
+	fullURI, _, _ = strings.Cut(fullURI, "#")
+	path, query, hasQuery := strings.Cut(fullURI, "?")
+	if hasQuery && query != "" {
+		if redirect, target := c.RedirectMatch(host, fullURI); redirect != nil && isQuerySensitive(redirect) {
+			return redirect, target
+		}
+	}
+	return c.RedirectMatch(host, path)
+}
+
+// DefaultMaxRedirectChainDepth is the number of redirects ResolveRedirectChain
+// follows when Config.MaxRedirectChainDepth is zero.
+const DefaultMaxRedirectChainDepth = 10
+
+// ResolveRedirectChain follows the redirects matching a request URI, e.g.
+// /a -> /b then /b -> /c, and returns the final target with the number of
+// redirects followed, so that a proxy answers a single redirect. A target on
+// another host ends the chain: its rules may not be served by this client. Up to
+// Config.MaxRedirectChainDepth redirects are followed; coming back to a URI
+// already visited stops the chain with loopDetected set and the target reached
+// so far. Without match, it returns an empty target and zero hops.
+func (c *client) ResolveRedirectChain(host, uri string) (finalTarget string, hops int, loopDetected bool) {
+	current, err := url.Parse(uri)
+	if err != nil {
+		current = &url.URL{}
+	}
+	current.Host = host
+	visited := map[string]bool{strings.ToLower(host) + uri: true}
+	for hops < c.config().GetMaxRedirectChainDepth() {
+		redirect, target := c.RedirectMatchFull(host, uri)
+		if redirect == nil {
+			break
+		}
+		finalTarget, hops = target, hops+1
+		ref, err := url.Parse(target)
+		if err != nil {
+			break
+		}
+		next := current.ResolveReference(ref)
+		if !strings.EqualFold(next.Host, host) {
+			break
+		}
+		uri = next.RequestURI()
+		if visited[strings.ToLower(host)+uri] {
+			return finalTarget, hops, true
+		}
+		visited[strings.ToLower(host)+uri] = true
+		current = next
+	}
+	return finalTarget, hops, false
+}
+
+// isQuerySensitive tells whether a redirect source matches on the query string.
+func isQuerySensitive(redirect *types.Redirect) bool {
+	switch redirect.Type {
+	case types.RedirectTypeRegex, types.RedirectTypeRegexHost:
+		return strings.Contains(redirect.Source, `\?`) || strings.Contains(redirect.Source, "[?]")
+	default:
+		return strings.Contains(redirect.Source, "?")
+	}
+}
+
+// PageResponse is a matched page ready to be written by a proxy.
+type PageResponse struct {
+	Page *types.Page
+	// StatusCode is http.StatusOK, or http.StatusServiceUnavailable for the maintenance page.
+	StatusCode int
+	Body       string
+	// Header holds Content-Type, Content-Length and, when Config.PageCacheControl
+	// is set, Cache-Control.
+	Header http.Header
+}
+
+// PageMatchResponse returns the matched page with the headers to answer it with.
+// Upstream pages define no headers of their own.
+func (c *client) PageMatchResponse(host, uri string) (*PageResponse, bool) {
+	page := c.PageMatch(host, uri)
+	if page == nil {
+		return nil, false
+	}
+	return newPageResponse(page, http.StatusOK, c.config().PageCacheControl), true
+}
+
+func newPageResponse(page *types.Page, statusCode int, cacheControl string) *PageResponse {
+	header := make(http.Header)
+	header.Set("Content-Type", page.HTTPContentType()+"; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(page.Content)))
+	if cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+	return &PageResponse{Page: page, StatusCode: statusCode, Body: page.Content, Header: header}
+}
+
+// PageMatchAccept returns the matched page only when its content type is
+// acceptable to the Accept header. An empty header accepts any page.
+func (c *client) PageMatchAccept(host, uri, accept string) *types.Page {
+	page := c.PageMatch(host, uri)
+	if page == nil || !acceptsMediaType(accept, page.HTTPContentType()) {
+		return nil
+	}
+	return page
+}
+
+// foldRedirect returns the copy of a redirect inserted under
+// Config.CaseInsensitivePaths: exact sources are lowercased, regex sources made
+// case-insensitive, so that escapes such as \D keep their meaning. The target
+// is left as is.
+func foldRedirect(redirect types.Redirect) *types.Redirect {
+	switch redirect.Type {
+	case types.RedirectTypeRegex, types.RedirectTypeRegexHost:
+		redirect.Source = "(?i)" + redirect.Source
+	default:
+		redirect.Source = strings.ToLower(redirect.Source)
+	}
+	return &redirect
+}
+
+func foldPage(page types.Page) *types.Page {
+	page.Path = strings.ToLower(page.Path)
+	return &page
+}
+
+// normalizeHost turns a Host header into the bare lowercase host the rules are
+// keyed on: "Example.com:8443" gives "example.com", "[::1]:8080" gives "::1".
+func normalizeHost(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	return strings.ToLower(host)
+}
+
+// acceptsMediaType does basic Accept matching: exact, type/* and */* ranges,
+// ignoring ranges with q=0.
+func acceptsMediaType(accept, contentType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	typ, subtype, _ := strings.Cut(contentType, "/")
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+			continue
+		}
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+		acceptType, acceptSubtype, _ := strings.Cut(mediaType, "/")
+		if (acceptType == "*" || acceptType == typ) && (acceptSubtype == "*" || acceptSubtype == subtype) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchSource_String(t *testing.T) {
+	assert.Equal(t, "none", MatchSourceNone.String())
+	assert.Equal(t, "exact", MatchSourceExact.String())
+	assert.Equal(t, "wildcard", MatchSourceWildcard.String())
+}
+
+func Test_client_RedirectMatchDebug(t *testing.T) {
+	c, _, _ := newTestClient()
+	tree := types.NewRedirectTreeMatcher()
+	_ = tree.Insert(&types.Redirect{Type: types.RedirectTypeBasicHost, Source: "example.com/host", Target: "/host-target"})
+	_ = tree.Insert(&types.Redirect{Type: types.RedirectTypeBasic, Source: "/any", Target: "/any-target"})
+	_ = tree.Insert(&types.Redirect{Type: types.RedirectTypeRegexHost, Source: "^example\\.com/blog/(.*)$", Target: "/news/$1"})
+	c.State.Store(&State{RedirectMatcher: tree})
+
+	tests := []struct {
+		name       string
+		host       string
+		uri        string
+		wantTarget string
+		wantSource MatchSource
+	}{
+		{name: "host specific rule", host: "example.com", uri: "/host", wantTarget: "/host-target", wantSource: MatchSourceExact},
+		{name: "host specific regex rule", host: "example.com", uri: "/blog/post", wantTarget: "/news/post", wantSource: MatchSourceExact},
+		{name: "catch-all rule", host: "other.com", uri: "/any", wantTarget: "/any-target", wantSource: MatchSourceWildcard},
+		{name: "no match", host: "other.com", uri: "/host", wantTarget: "", wantSource: MatchSourceNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.RedirectMatchDebug(tt.host, tt.uri)
+			assert.Equal(t, tt.wantTarget, result.Target)
+			assert.Equal(t, tt.wantSource, result.Source)
+			assert.Equal(t, tt.wantSource == MatchSourceNone, result.Redirect == nil)
+		})
+	}
+}
+
+func TestRedirectMatchResult_ResolveTarget(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		scheme       string
+		wantAbsolute bool
+		want         string
+	}{
+		{name: "relative", target: "/new?a=1", scheme: "https", want: "https://example.com/new?a=1"},
+		{name: "relative without slash", target: "new", scheme: "https", want: "https://example.com/new"},
+		{name: "default scheme", target: "/new", want: "http://example.com/new"},
+		{name: "absolute", target: "https://other.com/new", scheme: "http", wantAbsolute: true, want: "https://other.com/new"},
+		{name: "absolute other scheme", target: "http://other.com/new", scheme: "https", wantAbsolute: true, want: "http://other.com/new"},
+		{name: "protocol-relative", target: "//other.com/new", scheme: "https", wantAbsolute: true, want: "https://other.com/new"},
+		{name: "invalid", target: "/new%zz", scheme: "https", want: "https://example.com/new%zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RedirectMatchResult{Redirect: &types.Redirect{Target: tt.target}, Target: tt.target}
+
+			assert.Equal(t, tt.wantAbsolute, result.IsAbsoluteTarget())
+			assert.Equal(t, tt.want, result.ResolveTarget("example.com", tt.scheme))
+		})
+	}
+}
+
+func TestRedirectMatchResult_ResolveTargetNoMatch(t *testing.T) {
+	assert.Equal(t, "", RedirectMatchResult{}.ResolveTarget("example.com", "https"))
+}
+
+func Test_client_RedirectMatchDebug_ResolveTarget(t *testing.T) {
+	c, _, _ := newTestClient()
+	require.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1"}}, nil))
+
+	result := c.RedirectMatchDebug("example.com:8443", "/blog/post")
+
+	assert.False(t, result.IsAbsoluteTarget())
+	assert.Equal(t, "https://example.com:8443/news/post", result.ResolveTarget("example.com:8443", "https"))
+}
+
+func TestRedirectPrecedence(t *testing.T) {
+	assert.Greater(t, RedirectPrecedence(types.RedirectTypeBasicHost), RedirectPrecedence(types.RedirectTypeBasic))
+	assert.Greater(t, RedirectPrecedence(types.RedirectTypeBasic), RedirectPrecedence(types.RedirectTypeRegexHost))
+	assert.Greater(t, RedirectPrecedence(types.RedirectTypeRegexHost), RedirectPrecedence(types.RedirectTypeRegex))
+	assert.Equal(t, 0, RedirectPrecedence("unknown"))
+}
+
+func Test_client_RedirectMatchDebug_OverlappingRules(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeRegex, Source: "^/shop/(.*)$", Target: "/regex"},
+		{Type: types.RedirectTypeRegex, Source: "^/shop/(x.*)$", Target: "/longer-regex"},
+		{Type: types.RedirectTypeRegex, Source: "^/shop/(y*)$", Target: "/tie-a"},
+		{Type: types.RedirectTypeRegex, Source: "^/shop/(.y)$", Target: "/tie-b"},
+		{Type: types.RedirectTypeRegexHost, Source: "^example\\.com/shop/(.*)$", Target: "/regex-host"},
+		{Type: types.RedirectTypeBasic, Source: "/shop/sale", Target: "/basic"},
+		{Type: types.RedirectTypeBasicHost, Source: "example.com/shop/sale", Target: "/basic-host"},
+	}
+
+	tests := []struct {
+		name           string
+		host           string
+		uri            string
+		wantTarget     string
+		wantPrecedence int
+	}{
+		{name: "host exact path over everything", host: "example.com", uri: "/shop/sale", wantTarget: "/basic-host", wantPrecedence: 4},
+		{name: "exact path over regexes", host: "other.com", uri: "/shop/sale", wantTarget: "/basic", wantPrecedence: 3},
+		{name: "host regex over regex", host: "example.com", uri: "/shop/xmas", wantTarget: "/regex-host", wantPrecedence: 2},
+		{name: "longest regex source", host: "other.com", uri: "/shop/xmas", wantTarget: "/longer-regex", wantPrecedence: 1},
+		{name: "no match", host: "other.com", uri: "/cart", wantTarget: "", wantPrecedence: 0},
+	}
+
+	forward, _, _ := newTestClient()
+	assert.NoError(t, forward.LoadFromData(1, redirects, nil))
+	reversed := slices.Clone(redirects)
+	slices.Reverse(reversed)
+	backward, _, _ := newTestClient()
+	assert.NoError(t, backward.LoadFromData(1, reversed, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []*client{forward, backward} {
+				result := c.RedirectMatchDebug(tt.host, tt.uri)
+				assert.Equal(t, tt.wantTarget, result.Target)
+				assert.Equal(t, tt.wantPrecedence, result.Precedence)
+			}
+		})
+	}
+
+	t.Run("regex tie independent of fetch order", func(t *testing.T) {
+		assert.Equal(t, forward.RedirectMatchDebug("other.com", "/shop/yy").Target, backward.RedirectMatchDebug("other.com", "/shop/yy").Target)
+	})
+}
+
+func Test_client_PageMatchDebug(t *testing.T) {
+	c, _, _ := newTestClient()
+	tree := types.NewPageTreeMatcher()
+	tree.Insert(&types.Page{Type: types.PageTypeBasicHost, Path: "example.com/robots.txt", Content: "host"})
+	tree.Insert(&types.Page{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "any"})
+	c.State.Store(&State{PageMatcher: tree})
+
+	result := c.PageMatchDebug("example.com", "/robots.txt")
+	assert.Equal(t, MatchSourceExact, result.Source)
+	assert.Equal(t, "host", result.Page.Content)
+
+	result = c.PageMatchDebug("other.com", "/robots.txt")
+	assert.Equal(t, MatchSourceWildcard, result.Source)
+	assert.Equal(t, "any", result.Page.Content)
+
+	result = c.PageMatchDebug("other.com", "/missing")
+	assert.Equal(t, MatchSourceNone, result.Source)
+	assert.Nil(t, result.Page)
+}
+
+func Test_acceptsMediaType(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{name: "empty accepts anything", accept: "", want: true},
+		{name: "exact", accept: "application/xml", want: true},
+		{name: "exact with parameters", accept: "application/xml; charset=utf-8", want: true},
+		{name: "case insensitive", accept: "Application/XML", want: true},
+		{name: "subtype wildcard", accept: "application/*", want: true},
+		{name: "full wildcard", accept: "text/html, */*;q=0.8", want: true},
+		{name: "bare star", accept: "*", want: true},
+		{name: "list", accept: "text/html, application/xml;q=0.9", want: true},
+		{name: "other type", accept: "text/html", want: false},
+		{name: "other subtype", accept: "application/json", want: false},
+		{name: "refused with q=0", accept: "application/xml;q=0", want: false},
+		{name: "invalid range ignored", accept: "/;;, text/html", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, acceptsMediaType(tt.accept, "application/xml"))
+		})
+	}
+}
+
+func Test_client_PageMatchAccept(t *testing.T) {
+	c, _, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(1, nil, []types.Page{
+		{Type: types.PageTypeBasic, Path: "/sitemap.xml", Content: "<urlset/>", ContentType: types.PageContentTypeXML},
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}))
+
+	assert.NotNil(t, c.PageMatchAccept("example.com", "/sitemap.xml", "application/xml"))
+	assert.Nil(t, c.PageMatchAccept("example.com", "/sitemap.xml", "text/html"))
+	assert.NotNil(t, c.PageMatchAccept("example.com", "/robots.txt", "text/*"))
+	assert.Nil(t, c.PageMatchAccept("example.com", "/robots.txt", "application/xml"))
+	assert.Nil(t, c.PageMatchAccept("example.com", "/missing", "*/*"))
+}
+
+func Test_normalizeHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "example.com", want: "example.com"},
+		{host: "Example.COM", want: "example.com"},
+		{host: "example.com:8443", want: "example.com"},
+		{host: "[::1]:8080", want: "::1"},
+		{host: "[2001:DB8::1]", want: "2001:db8::1"},
+		{host: "::1", want: "::1"},
+		{host: "127.0.0.1:80", want: "127.0.0.1"},
+		{host: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeHost(tt.host))
+		})
+	}
+}
+
+func Test_client_NormalizeHost(t *testing.T) {
+	c, _, _ := newTestClient()
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasicHost, Source: "example.com/old", Target: "/new"},
+		{Type: types.RedirectTypeBasicHost, Source: "::1/old", Target: "/local"},
+	}
+	pages := []types.Page{{Type: types.PageTypeBasicHost, Path: "example.com/robots.txt", Content: "host"}}
+	assert.NoError(t, c.LoadFromData(1, redirects, pages))
+
+	redirect, _ := c.RedirectMatch("Example.com:8443", "/old")
+	assert.Nil(t, redirect)
+
+	c.config().NormalizeHost = true
+	for host, want := range map[string]string{"Example.com:8443": "/new", "EXAMPLE.COM": "/new", "[::1]:8080": "/local"} {
+		_, target := c.RedirectMatch(host, "/old")
+		assert.Equal(t, want, target, host)
+	}
+	assert.NotNil(t, c.PageMatch("example.com:443", "/robots.txt"))
+}
+
+func Test_client_RedirectMatchFull(t *testing.T) {
+	c, _, _ := newTestClient()
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/products", Target: "/catalog"},
+		{Type: types.RedirectTypeBasic, Source: "/list?page=2", Target: "/list/2"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1"},
+		{Type: types.RedirectTypeRegex, Source: "^/search\\?q=(.*)$", Target: "/find/$1"},
+	}
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+
+	tests := []struct {
+		name       string
+		uri        string
+		wantTarget string
+	}{
+		{name: "path only", uri: "/products", wantTarget: "/catalog"},
+		{name: "query stripped", uri: "/products?utm_source=mail", wantTarget: "/catalog"},
+		{name: "fragment ignored", uri: "/products#top", wantTarget: "/catalog"},
+		{name: "regex on path only", uri: "/blog/post?ref=home", wantTarget: "/news/post"},
+		{name: "query sensitive rule", uri: "/list?page=2", wantTarget: "/list/2"},
+		{name: "query sensitive rule other query", uri: "/list?page=3", wantTarget: ""},
+		{name: "query sensitive regex", uri: "/search?q=shoes", wantTarget: "/find/shoes"},
+		{name: "empty query", uri: "/products?", wantTarget: "/catalog"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, target := c.RedirectMatchFull("example.com", tt.uri)
+			assert.Equal(t, tt.wantTarget, target)
+		})
+	}
+}
+
+func Test_client_ResolveRedirectChain(t *testing.T) {
+	c, _, _ := newTestClient()
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/b"},
+		{Type: types.RedirectTypeBasic, Source: "/b", Target: "https://Example.com/c?from=b"},
+		{Type: types.RedirectTypeBasic, Source: "/c", Target: "/final"},
+		{Type: types.RedirectTypeBasic, Source: "/dir/old", Target: "new"},
+		{Type: types.RedirectTypeBasic, Source: "/dir/new", Target: "/final"},
+		{Type: types.RedirectTypeBasic, Source: "/ping", Target: "/pong"},
+		{Type: types.RedirectTypeBasic, Source: "/pong", Target: "/ping"},
+		{Type: types.RedirectTypeBasic, Source: "/self", Target: "/self"},
+		{Type: types.RedirectTypeBasic, Source: "/away", Target: "https://other.com/a"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/a?post=$1"},
+	}
+	for i := 0; i < 12; i++ {
+		redirects = append(redirects, types.Redirect{Type: types.RedirectTypeBasic, Source: fmt.Sprintf("/step/%d", i), Target: fmt.Sprintf("/step/%d", i+1)})
+	}
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+
+	tests := []struct {
+		name       string
+		uri        string
+		wantTarget string
+		wantHops   int
+		wantLoop   bool
+	}{
+		{name: "no match", uri: "/none", wantTarget: "", wantHops: 0},
+		{name: "single hop", uri: "/c", wantTarget: "/final", wantHops: 1},
+		{name: "chain through absolute target on same host", uri: "/a", wantTarget: "/final", wantHops: 3},
+		{name: "relative target", uri: "/dir/old", wantTarget: "/final", wantHops: 2},
+		{name: "query kept between hops", uri: "/blog/x", wantTarget: "/final", wantHops: 4},
+		{name: "loop", uri: "/ping", wantTarget: "/ping", wantHops: 2, wantLoop: true},
+		{name: "self redirect", uri: "/self", wantTarget: "/self", wantHops: 1, wantLoop: true},
+		{name: "other host ends chain", uri: "/away", wantTarget: "https://other.com/a", wantHops: 1},
+		{name: "max depth", uri: "/step/0", wantTarget: "/step/10", wantHops: DefaultMaxRedirectChainDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, hops, loop := c.ResolveRedirectChain("example.com", tt.uri)
+			assert.Equal(t, tt.wantTarget, target)
+			assert.Equal(t, tt.wantHops, hops)
+			assert.Equal(t, tt.wantLoop, loop)
+		})
+	}
+
+	c.config().MaxRedirectChainDepth = 2
+	target, hops, loop := c.ResolveRedirectChain("example.com", "/a")
+	assert.Equal(t, "https://Example.com/c?from=b", target)
+	assert.Equal(t, 2, hops)
+	assert.False(t, loop)
+}
+
+func Test_client_CaseInsensitivePaths(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().CaseInsensitivePaths = true
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/Old-Path", Target: "/New-Path"},
+		{Type: types.RedirectTypeBasicHost, Source: "example.com/Promo", Target: "https://Shop.example.com/Sale"},
+		{Type: types.RedirectTypeRegex, Source: "^/Blog/(\\d+)$", Target: "/News/$1"},
+		{Type: types.RedirectTypeRegex, Source: "^/Tag/(\\D+)$", Target: "/Tags/$1"},
+		{Type: types.RedirectTypeRegexHost, Source: "^example\\.com/Shop/(.*)$", Target: "/Store/$1"},
+	}
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/Robots.txt", Content: "User-agent: *"}}
+	assert.NoError(t, c.LoadFromData(1, redirects, pages))
+
+	tests := []struct {
+		uri        string
+		wantTarget string
+	}{
+		{uri: "/old-path", wantTarget: "/New-Path"},
+		{uri: "/OLD-PATH", wantTarget: "/New-Path"},
+		{uri: "/Old-Path", wantTarget: "/New-Path"},
+		{uri: "/PROMO", wantTarget: "https://Shop.example.com/Sale"},
+		{uri: "/blog/42", wantTarget: "/News/42"},
+		{uri: "/TAG/Go", wantTarget: "/Tags/Go"},
+		{uri: "/BLOG/42", wantTarget: "/News/42"},
+		{uri: "/SHOP/Red-Shoes", wantTarget: "/Store/Red-Shoes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			_, target := c.RedirectMatch("example.com", tt.uri)
+			assert.Equal(t, tt.wantTarget, target)
+		})
+	}
+
+	page := c.PageMatch("example.com", "/ROBOTS.TXT")
+	if assert.NotNil(t, page) {
+		assert.Equal(t, "User-agent: *", page.Content)
+	}
+	assert.Equal(t, "/Old-Path", c.load().Redirects[0].Source)
+	assert.Equal(t, "/Robots.txt", c.load().Pages[0].Path)
+}
+
+func Test_client_CaseSensitivePathsByDefault(t *testing.T) {
+	c, _, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/Old-Path", Target: "/New-Path"}}, nil))
+
+	redirect, _ := c.RedirectMatch("example.com", "/old-path")
+	assert.Nil(t, redirect)
+}
+
+func Test_client_PageMatchResponse(t *testing.T) {
+	c, _, _ := newTestClient()
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+		{Type: types.PageTypeBasic, Path: "/sitemap.xml", Content: "<urlset/>", ContentType: types.PageContentTypeXML},
+		{Type: types.PageTypeBasic, Path: "/humans.txt", Content: "team"},
+	}
+	assert.NoError(t, c.LoadFromData(1, nil, pages))
+
+	tests := []struct {
+		uri             string
+		wantContentType string
+		wantBody        string
+	}{
+		{uri: "/robots.txt", wantContentType: "text/plain; charset=utf-8", wantBody: "User-agent: *"},
+		{uri: "/sitemap.xml", wantContentType: "application/xml; charset=utf-8", wantBody: "<urlset/>"},
+		{uri: "/humans.txt", wantContentType: "text/plain; charset=utf-8", wantBody: "team"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			resp, ok := c.PageMatchResponse("example.com", tt.uri)
+			if assert.True(t, ok) {
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Equal(t, tt.wantBody, resp.Body)
+				assert.Equal(t, tt.wantContentType, resp.Header.Get("Content-Type"))
+				assert.Equal(t, strconv.Itoa(len(tt.wantBody)), resp.Header.Get("Content-Length"))
+				assert.Empty(t, resp.Header.Get("Cache-Control"))
+				assert.Equal(t, tt.uri, resp.Page.Path)
+			}
+		})
+	}
+
+	resp, ok := c.PageMatchResponse("example.com", "/missing")
+	assert.False(t, ok)
+	assert.Nil(t, resp)
+
+	c.config().PageCacheControl = "public, max-age=3600"
+	resp, _ = c.PageMatchResponse("example.com", "/robots.txt")
+	assert.Equal(t, "public, max-age=3600", resp.Header.Get("Cache-Control"))
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// BuildRedirectMatcher builds a matcher from redirects, as a reload does, for
+// callers using the matching engine without a client. It fails on the first
+// invalid regex. The rules are copied.
+func BuildRedirectMatcher(redirects []types.Redirect) (types.RedirectTreeMatcher, error) {
+	redirects = slices.Clone(redirects)
+	// Insert in a canonical order so that ties between regex rules do not
+	// depend on the order the rules were given in.
+	order := make([]int, len(redirects))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return strings.Compare(redirects[a].Source, redirects[b].Source)
+	})
+	matcher := types.NewRedirectTreeMatcher()
+	for _, i := range order {
+		if err := matcher.Insert(&redirects[i]); err != nil {
+			return nil, err
+		}
+	}
+	return matcher, nil
+}
+
+// BuildPageMatcher builds a matcher from pages, as a reload does. The pages are copied.
+func BuildPageMatcher(pages []types.Page) types.PageTreeMatcher {
+	pages = slices.Clone(pages)
+	matcher := types.NewPageTreeMatcher()
+	for i := range pages {
+		matcher.Insert(&pages[i])
+	}
+	return matcher
+}
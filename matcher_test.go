@@ -0,0 +1,245 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildRedirectMatcher(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+		{Type: types.RedirectTypeBasicHost, Source: "example.com/old", Target: "/host"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1"},
+	}
+
+	matcher, err := BuildRedirectMatcher(redirects)
+	assert.NoError(t, err)
+
+	redirects[0].Target = "/modified"
+	_, target := matcher.Match("other.com", "/old")
+	assert.Equal(t, "/new", target)
+	_, target = matcher.Match("example.com", "/old")
+	assert.Equal(t, "/host", target)
+	_, target = matcher.Match("other.com", "/blog/post")
+	assert.Equal(t, "/news/post", target)
+	redirect, _ := matcher.Match("other.com", "/missing")
+	assert.Nil(t, redirect)
+}
+
+func TestBuildRedirectMatcher_InvalidRegex(t *testing.T) {
+	matcher, err := BuildRedirectMatcher([]types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/([a-z$", Target: "/news"},
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, matcher)
+}
+
+func TestBuildRedirectMatcher_MatchesLoadState(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeRegex, Source: "^/shop/(y*)$", Target: "/tie-a"},
+		{Type: types.RedirectTypeRegex, Source: "^/shop/(.y)$", Target: "/tie-b"},
+	}
+	c, _, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+
+	matcher, err := BuildRedirectMatcher(redirects)
+	assert.NoError(t, err)
+
+	_, want := c.RedirectMatch("example.com", "/shop/yy")
+	_, got := matcher.Match("example.com", "/shop/yy")
+	assert.Equal(t, want, got)
+}
+
+func TestBuildPageMatcher(t *testing.T) {
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "any"},
+		{Type: types.PageTypeBasicHost, Path: "example.com/robots.txt", Content: "host"},
+	}
+
+	matcher := BuildPageMatcher(pages)
+	pages[0].Content = "modified"
+
+	assert.Equal(t, "any", matcher.Match("other.com", "/robots.txt").Content)
+	assert.Equal(t, "host", matcher.Match("example.com", "/robots.txt").Content)
+	assert.Nil(t, matcher.Match("other.com", "/missing"))
+	assert.Nil(t, BuildPageMatcher(nil).Match("other.com", "/robots.txt"))
+}
+
+func TestClient_ReuseMatchers(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().ReuseMatchers = true
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1"},
+	}
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v1"}}
+	assert.NoError(t, c.LoadFromData(1, redirects, pages))
+	first := c.load()
+
+	pages = []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v2"}}
+	assert.NoError(t, c.LoadFromData(2, redirects, pages))
+	second := c.load()
+	assert.Same(t, first.RedirectMatcher, second.RedirectMatcher)
+	assert.NotSame(t, first.PageMatcher, second.PageMatcher)
+	assert.Equal(t, "v2", c.PageMatch("example.com", "/robots.txt").Content)
+
+	redirects = []types.Redirect{redirects[1], {Type: types.RedirectTypeBasic, Source: "/added", Target: "/here"}}
+	assert.NoError(t, c.LoadFromData(3, redirects, pages))
+	third := c.load()
+	assert.NotSame(t, second.RedirectMatcher, third.RedirectMatcher)
+	assert.Same(t, second.PageMatcher, third.PageMatcher)
+	redirect, _ := c.RedirectMatch("example.com", "/old")
+	assert.Nil(t, redirect)
+	redirect, target := c.RedirectMatch("example.com", "/added")
+	assert.NotNil(t, redirect)
+	assert.Equal(t, "/here", target)
+	_, target = c.RedirectMatch("example.com", "/blog/post")
+	assert.Equal(t, "/news/post", target)
+}
+
+func TestClient_ReuseMatchers_AppliesChangesToCopy(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().ReuseMatchers = true
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1"},
+	}
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+	first := c.load()
+
+	redirects = []types.Redirect{{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/posts/$1"}}
+	assert.NoError(t, c.LoadFromData(2, redirects, nil))
+
+	_, target := first.RedirectMatcher.Match("example.com", "/blog/a")
+	assert.Equal(t, "/news/a", target)
+	redirect, _ := first.RedirectMatcher.Match("example.com", "/old")
+	assert.NotNil(t, redirect)
+	_, target = c.RedirectMatch("example.com", "/blog/a")
+	assert.Equal(t, "/posts/a", target)
+	redirect, _ = c.RedirectMatch("example.com", "/old")
+	assert.Nil(t, redirect)
+	assert.Same(t, first.RedirectMatcher.(*redirectIndex).regex.buckets["/blog/"][0].re,
+		c.load().RedirectMatcher.(*redirectIndex).regex.buckets["/blog/"][0].re)
+}
+
+func TestClient_ReuseMatchers_DuplicateKeysRebuilt(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().ReuseMatchers = true
+	c.config().CaseInsensitivePaths = true
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/Old", Target: "/a"},
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/b"},
+	}
+
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+
+	assert.IsType(t, &types.RedirectTree{}, c.load().RedirectMatcher)
+	_, target := c.RedirectMatch("example.com", "/OLD")
+	assert.Equal(t, "/b", target)
+}
+
+func TestClient_ReuseMatchers_Disabled(t *testing.T) {
+	c, _, _ := newTestClient()
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+	first := c.load()
+
+	assert.NoError(t, c.LoadFromData(2, redirects, nil))
+
+	assert.NotSame(t, first.RedirectMatcher, c.load().RedirectMatcher)
+}
+
+func TestClient_ReuseMatchers_CaseInsensitivePathsChanged(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().ReuseMatchers = true
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/Old", Target: "/new"}}
+	assert.NoError(t, c.LoadFromData(1, redirects, nil))
+	first := c.load()
+
+	c.config().CaseInsensitivePaths = true
+	assert.NoError(t, c.LoadFromData(2, redirects, nil))
+
+	assert.NotSame(t, first.RedirectMatcher, c.load().RedirectMatcher)
+	redirect, _ := c.RedirectMatch("example.com", "/OLD")
+	assert.NotNil(t, redirect)
+}
+
+func benchmarkRedirects(n int) []types.Redirect {
+	redirects := make([]types.Redirect, 0, n)
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			redirects = append(redirects, types.Redirect{Type: types.RedirectTypeBasic, Source: fmt.Sprintf("/old/%d", i), Target: fmt.Sprintf("/new/%d", i)})
+		} else {
+			redirects = append(redirects, types.Redirect{Type: types.RedirectTypeRegex, Source: fmt.Sprintf("^/blog/%d/(.*)$", i), Target: "/news/$1"})
+		}
+	}
+	return redirects
+}
+
+func benchmarkReload(b *testing.B, reuse bool) {
+	c, _, _ := newTestClient()
+	c.config().ReuseMatchers = reuse
+	redirects := benchmarkRedirects(10000)
+	pages := []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v1"}}
+	_ = c.LoadFromData(1, redirects, pages)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// each reload changes the target of 1% of the rules
+		redirects = slices.Clone(redirects)
+		for j := i % 100; j < len(redirects); j += 100 {
+			redirects[j].Target = fmt.Sprintf("/v%d/%d", i, j)
+		}
+		_ = c.LoadFromData(i+2, redirects, pages)
+	}
+}
+
+func BenchmarkReload_FullRebuild(b *testing.B) {
+	benchmarkReload(b, false)
+}
+
+func BenchmarkReload_ReuseMatchers(b *testing.B) {
+	benchmarkReload(b, true)
+}
+
+func benchmarkMatchClient() *client {
+	c, _, _ := newTestClient()
+	_ = c.LoadFromData(1, benchmarkRedirects(1000), []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "v1"}})
+	return c
+}
+
+func BenchmarkRedirectMatch(b *testing.B) {
+	c := benchmarkMatchClient()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.RedirectMatch("example.com", "/old/500")
+		}
+	})
+}
+
+func BenchmarkRedirectMatch_Matcher(b *testing.B) {
+	matcher := benchmarkMatchClient().load().RedirectMatcher
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			matcher.Match("example.com", "/old/500")
+		}
+	})
+}
+
+func BenchmarkPageMatch(b *testing.B) {
+	c := benchmarkMatchClient()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.PageMatch("example.com", "/robots.txt")
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package client
+
+import "time"
+
+// Endpoint identifies a manager API route called by the client.
+type Endpoint string
+
+const (
+	EndpointVersion     Endpoint = "version"
+	EndpointRedirects   Endpoint = "redirects"
+	EndpointPages       Endpoint = "pages"
+	EndpointAgentStatus Endpoint = "agent_status"
+	EndpointAgentHit    Endpoint = "agent_hit"
+	// EndpointManagerInfo returns the manager version, checked by Init (see Config.SkipCompatibilityCheck).
+	EndpointManagerInfo Endpoint = "manager_info"
+	// EndpointAgentStatusBatch posts the statuses of several projects, see Config.AgentStatusBatchWindow.
+	EndpointAgentStatusBatch Endpoint = "agent_status_batch"
+	// EndpointRedirect and EndpointPage return a single rule, see Client.FetchRedirect.
+	EndpointRedirect Endpoint = "redirect"
+	EndpointPage     Endpoint = "page"
+	// EndpointState returns the version, redirects and pages at once, see Config.UseCombinedStateEndpoint.
+	EndpointState Endpoint = "state"
+)
+
+// Metrics receives instrumentation events from the client.
+type Metrics interface {
+	// ObserveRequest is called after each request to the manager with the time
+	// spent waiting for the response and the transport error, if any.
+	ObserveRequest(endpoint Endpoint, duration time.Duration, err error)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveRequest(Endpoint, time.Duration, error) {}
+
+// SkipMetrics is implemented by Metrics counting the reloads skipped because
+// another one was running (see ReloadStats.SkippedReloads).
+type SkipMetrics interface {
+	ObserveSkippedReload()
+}
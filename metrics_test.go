@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+type requestObservation struct {
+	endpoint Endpoint
+	duration time.Duration
+	err      error
+}
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []requestObservation
+}
+
+func (m *recordingMetrics) ObserveRequest(endpoint Endpoint, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, requestObservation{endpoint: endpoint, duration: duration, err: err})
+}
+
+func (m *recordingMetrics) countByEndpoint() map[Endpoint]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[Endpoint]int)
+	for _, r := range m.requests {
+		counts[r.endpoint]++
+	}
+	return counts
+}
+
+// slowHTTPClient advances the fake clock on every request to simulate latency.
+type slowHTTPClient struct {
+	next    HTTPClient
+	clock   clockwork.FakeClock
+	latency time.Duration
+}
+
+func (s *slowHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	s.clock.Advance(s.latency)
+	return s.next.Do(req)
+}
+
+func TestClient_Metrics_ObserveRequest_VersionChange(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	metrics := &recordingMetrics{}
+	c.config().Metrics = metrics
+	c.config().Http.Client = &slowHTTPClient{next: mockHTTP, clock: fakeClock, latency: 10 * time.Millisecond}
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{}, 0), nil)
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[Endpoint]int{
+		EndpointVersion:     2,
+		EndpointRedirects:   1,
+		EndpointPages:       1,
+		EndpointAgentStatus: 1,
+	}, metrics.countByEndpoint())
+	for _, r := range metrics.requests {
+		assert.Equal(t, 10*time.Millisecond, r.duration)
+		assert.NoError(t, r.err)
+	}
+}
+
+func TestClient_Metrics_ObserveRequest_NoVersionChange(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	metrics := &recordingMetrics{}
+	c.config().Metrics = metrics
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[Endpoint]int{
+		EndpointVersion:  1,
+		EndpointAgentHit: 1,
+	}, metrics.countByEndpoint())
+}
+
+func TestClient_Metrics_ObserveRequest_Error(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	metrics := &recordingMetrics{}
+	c.config().Metrics = metrics
+
+	mockHTTP.expect(nil, errors.New("network error"))
+
+	_, err := c.getProjectVersion(context.Background())
+
+	assert.Error(t, err)
+	assert.Len(t, metrics.requests, 1)
+	assert.Equal(t, EndpointVersion, metrics.requests[0].endpoint)
+	assert.EqualError(t, metrics.requests[0].err, "network error")
+}
+
+func TestClient_Metrics_NilIsNoop(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Metrics = nil
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+
+	version, err := c.getProjectVersion(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+}
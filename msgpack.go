@@ -0,0 +1,536 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MsgpackCodec decodes msgpack list responses into the same structs as JSON,
+// matching map keys to fields by their json tag, or name, case-insensitively.
+// Extension types are not supported.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+func (MsgpackCodec) Decode(r io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("msgpack: decode into non-pointer %T", v)
+	}
+	mr, ok := r.(msgpackReader)
+	if !ok {
+		mr = bufio.NewReader(r)
+	}
+	d := &msgpackDecoder{r: mr}
+	return d.decode(rv.Elem())
+}
+
+type msgpackReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+type msgpackDecoder struct {
+	r msgpackReader
+	// scratch holds short strings and map keys between reads.
+	scratch []byte
+	// depth counts the arrays and maps being decoded or skipped.
+	depth int
+}
+
+// msgpackMaxDepth bounds the nesting of arrays and maps, as encoding/json does,
+// so that a deeply nested body fails instead of overflowing the stack.
+const msgpackMaxDepth = 10000
+
+var (
+	errMsgpackUnsupported = errors.New("msgpack: unsupported type")
+	errMsgpackTooDeep     = errors.New("msgpack: exceeded max depth")
+)
+
+// enter records one more level of nesting; each successful call is paired with
+// a deferred leave.
+func (d *msgpackDecoder) enter() error {
+	if d.depth >= msgpackMaxDepth {
+		return errMsgpackTooDeep
+	}
+	d.depth++
+	return nil
+}
+
+func (d *msgpackDecoder) leave() {
+	d.depth--
+}
+
+func (d *msgpackDecoder) readN(n int) ([]byte, error) {
+	if n > 4096 {
+		// grow as bytes arrive rather than trusting the announced length
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(d.r, buf)
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf, err
+}
+
+// readScratch reads n bytes, valid until the next read, into a reused buffer.
+func (d *msgpackDecoder) readScratch(n int) ([]byte, error) {
+	if n > 4096 {
+		return d.readN(n)
+	}
+	if cap(d.scratch) < n {
+		d.scratch = make([]byte, 4096)
+	}
+	buf := d.scratch[:n]
+	_, err := io.ReadFull(d.r, buf)
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf, err
+}
+
+func (d *msgpackDecoder) readUint(size int) (uint64, error) {
+	buf, err := d.readN(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(buf[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(buf)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(buf)), nil
+	default:
+		return binary.BigEndian.Uint64(buf), nil
+	}
+}
+
+type msgpackKind int
+
+const (
+	msgpackNil msgpackKind = iota
+	msgpackBool
+	msgpackInt
+	msgpackUint
+	msgpackFloat
+	msgpackString
+	msgpackBinary
+	msgpackArray
+	msgpackMap
+)
+
+// msgpackValue is a decoded scalar, or the header of a string, binary, array or map
+// whose length is in n.
+type msgpackValue struct {
+	kind msgpackKind
+	b    bool
+	i    int64
+	u    uint64
+	f    float64
+	n    int
+}
+
+func (d *msgpackDecoder) readHeader() (msgpackValue, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return msgpackValue{}, err
+	}
+	switch {
+	case c <= 0x7f:
+		return msgpackValue{kind: msgpackUint, u: uint64(c)}, nil
+	case c >= 0xe0:
+		return msgpackValue{kind: msgpackInt, i: int64(int8(c))}, nil
+	case c >= 0xa0 && c <= 0xbf:
+		return msgpackValue{kind: msgpackString, n: int(c & 0x1f)}, nil
+	case c >= 0x90 && c <= 0x9f:
+		return msgpackValue{kind: msgpackArray, n: int(c & 0x0f)}, nil
+	case c >= 0x80 && c <= 0x8f:
+		return msgpackValue{kind: msgpackMap, n: int(c & 0x0f)}, nil
+	}
+
+	var size int
+	var kind msgpackKind
+	switch c {
+	case 0xc0:
+		return msgpackValue{kind: msgpackNil}, nil
+	case 0xc2, 0xc3:
+		return msgpackValue{kind: msgpackBool, b: c == 0xc3}, nil
+	case 0xca:
+		bits, err := d.readUint(4)
+		return msgpackValue{kind: msgpackFloat, f: float64(math.Float32frombits(uint32(bits)))}, err
+	case 0xcb:
+		bits, err := d.readUint(8)
+		return msgpackValue{kind: msgpackFloat, f: math.Float64frombits(bits)}, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.readUint(1 << (c - 0xcc))
+		return msgpackValue{kind: msgpackUint, u: u}, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size = 1 << (c - 0xd0)
+		u, err := d.readUint(size)
+		// sign-extend from the encoded width
+		shift := 64 - 8*size
+		return msgpackValue{kind: msgpackInt, i: int64(u<<shift) >> shift}, err
+	case 0xd9, 0xda, 0xdb:
+		size, kind = 1<<(c-0xd9), msgpackString
+	case 0xc4, 0xc5, 0xc6:
+		size, kind = 1<<(c-0xc4), msgpackBinary
+	case 0xdc, 0xdd:
+		size, kind = 2<<(c-0xdc), msgpackArray
+	case 0xde, 0xdf:
+		size, kind = 2<<(c-0xde), msgpackMap
+	default:
+		return msgpackValue{}, fmt.Errorf("%w: 0x%02x", errMsgpackUnsupported, c)
+	}
+	n, err := d.readUint(size)
+	if err != nil {
+		return msgpackValue{}, err
+	}
+	if n > math.MaxInt32 {
+		return msgpackValue{}, fmt.Errorf("msgpack: length %d too large", n)
+	}
+	return msgpackValue{kind: kind, n: int(n)}, nil
+}
+
+func (d *msgpackDecoder) decode(v reflect.Value) error {
+	h, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+	return d.decodeValue(h, v)
+}
+
+func (d *msgpackDecoder) decodeValue(h msgpackValue, v reflect.Value) error {
+	if h.kind == msgpackNil {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			v.SetZero()
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(h, v.Elem())
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		generic, err := d.decodeAny(h)
+		if err != nil {
+			return err
+		}
+		if generic != nil {
+			v.Set(reflect.ValueOf(generic))
+		} else {
+			v.SetZero()
+		}
+		return nil
+	}
+
+	switch h.kind {
+	case msgpackString, msgpackBinary:
+		switch {
+		case v.Kind() == reflect.String:
+			buf, err := d.readScratch(h.n)
+			if err != nil {
+				return err
+			}
+			v.SetString(string(buf))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			buf, err := d.readN(h.n)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(buf)
+		default:
+			return d.mismatch("string", v)
+		}
+		return nil
+	case msgpackArray:
+		return d.decodeArray(h.n, v)
+	case msgpackMap:
+		return d.decodeMap(h.n, v)
+	case msgpackBool:
+		if v.Kind() != reflect.Bool {
+			return d.mismatch("bool", v)
+		}
+		v.SetBool(h.b)
+		return nil
+	case msgpackFloat:
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return d.mismatch("float", v)
+		}
+		v.SetFloat(h.f)
+		return nil
+	default:
+		return d.setInt(h, v)
+	}
+}
+
+func (d *msgpackDecoder) setInt(h msgpackValue, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := h.i
+		if h.kind == msgpackUint {
+			if h.u > math.MaxInt64 {
+				return fmt.Errorf("msgpack: %d overflows %s", h.u, v.Type())
+			}
+			i = int64(h.u)
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("msgpack: %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := h.u
+		if h.kind == msgpackInt {
+			if h.i < 0 {
+				return fmt.Errorf("msgpack: %d overflows %s", h.i, v.Type())
+			}
+			u = uint64(h.i)
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("msgpack: %d overflows %s", u, v.Type())
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if h.kind == msgpackUint {
+			v.SetFloat(float64(h.u))
+		} else {
+			v.SetFloat(float64(h.i))
+		}
+	default:
+		return d.mismatch("integer", v)
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) decodeArray(n int, v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	switch v.Kind() {
+	case reflect.Slice:
+		// grow as elements arrive rather than trusting the announced length
+		slice := reflect.New(v.Type()).Elem()
+		slice.Set(reflect.MakeSlice(v.Type(), 0, min(n, 1024)))
+		for i := 0; i < n; i++ {
+			slice.Grow(1)
+			slice.SetLen(i + 1)
+			if err := d.decode(slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		for i := 0; i < n; i++ {
+			if i >= v.Len() {
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return d.mismatch("array", v)
+	}
+}
+
+func (d *msgpackDecoder) decodeMap(n int, v reflect.Value) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := msgpackFields(v.Type())
+		for i := 0; i < n; i++ {
+			key, err := d.readKey()
+			if err != nil {
+				return err
+			}
+			index, found := fields.lookup(key)
+			if !found {
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.FieldByIndex(index)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for i := 0; i < n; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := d.decode(key); err != nil {
+				return err
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return nil
+	default:
+		return d.mismatch("map", v)
+	}
+}
+
+// readKey reads a string map key into the scratch buffer.
+func (d *msgpackDecoder) readKey() ([]byte, error) {
+	h, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	if h.kind != msgpackString && h.kind != msgpackBinary {
+		return nil, errors.New("msgpack: struct keys must be strings")
+	}
+	return d.readScratch(h.n)
+}
+
+// decodeAny decodes the value of h like encoding/json does into an empty interface,
+// except that integers stay int64 or uint64.
+func (d *msgpackDecoder) decodeAny(h msgpackValue) (any, error) {
+	switch h.kind {
+	case msgpackString:
+		buf, err := d.readN(h.n)
+		return string(buf), err
+	case msgpackBinary:
+		return d.readN(h.n)
+	case msgpackArray:
+		var values []any
+		err := d.decodeArray(h.n, reflect.ValueOf(&values).Elem())
+		return values, err
+	case msgpackMap:
+		values := make(map[string]any, min(h.n, 1024))
+		err := d.decodeMap(h.n, reflect.ValueOf(&values).Elem())
+		return values, err
+	case msgpackBool:
+		return h.b, nil
+	case msgpackFloat:
+		return h.f, nil
+	case msgpackInt:
+		return h.i, nil
+	case msgpackUint:
+		return h.u, nil
+	default:
+		return nil, nil
+	}
+}
+
+// skip reads and drops the next value.
+func (d *msgpackDecoder) skip() error {
+	h, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+	switch h.kind {
+	case msgpackString, msgpackBinary:
+		_, err = d.readN(h.n)
+		return err
+	case msgpackArray, msgpackMap:
+		if err := d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+		n := h.n
+		if h.kind == msgpackMap {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) mismatch(got string, v reflect.Value) error {
+	return fmt.Errorf("msgpack: cannot decode %s into %s", got, v.Type())
+}
+
+// msgpackStructFields maps the keys of a struct to its field indexes.
+type msgpackStructFields struct {
+	exact map[string][]int
+	fold  map[string][]int
+}
+
+func (f msgpackStructFields) lookup(key []byte) ([]int, bool) {
+	if index, found := f.exact[string(key)]; found {
+		return index, true
+	}
+	index, found := f.fold[strings.ToLower(string(key))]
+	return index, found
+}
+
+var msgpackFieldCache sync.Map
+
+func msgpackFields(t reflect.Type) msgpackStructFields {
+	if cached, found := msgpackFieldCache.Load(t); found {
+		return cached.(msgpackStructFields)
+	}
+	fields := msgpackStructFields{exact: make(map[string][]int), fold: make(map[string][]int)}
+	collectMsgpackFields(t, nil, fields)
+	msgpackFieldCache.Store(t, fields)
+	return fields
+}
+
+func collectMsgpackFields(t reflect.Type, parent []int, fields msgpackStructFields) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			collectMsgpackFields(field.Type, index, fields)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		// fields of the outer struct win over promoted ones
+		if _, found := fields.exact[name]; !found || len(parent) == 0 {
+			fields.exact[name] = index
+		}
+		if _, found := fields.fold[strings.ToLower(name)]; !found || len(parent) == 0 {
+			fields.fold[strings.ToLower(name)] = index
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeMsgpack is a minimal encoder for the test fixtures: structs are written
+// as maps keyed by their json names.
+func encodeMsgpack(buf *bytes.Buffer, v reflect.Value) {
+	writeHeader := func(fix, base byte, n int) {
+		switch {
+		case fix != 0 && n < 16:
+			buf.WriteByte(fix | byte(n))
+		case n < 1<<16:
+			buf.WriteByte(base)
+			_ = binary.Write(buf, binary.BigEndian, uint16(n))
+		default:
+			buf.WriteByte(base + 1)
+			_ = binary.Write(buf, binary.BigEndian, uint32(n))
+		}
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteByte(0xc0)
+			return
+		}
+		encodeMsgpack(buf, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteByte(0xcf)
+		_ = binary.Write(buf, binary.BigEndian, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(v.Float()))
+	case reflect.String:
+		if v.Len() < 32 {
+			buf.WriteByte(0xa0 | byte(v.Len()))
+		} else {
+			writeHeader(0, 0xda, v.Len())
+		}
+		buf.WriteString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteByte(0xc0)
+			return
+		}
+		writeHeader(0x90, 0xdc, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			encodeMsgpack(buf, v.Index(i))
+		}
+	case reflect.Map:
+		writeHeader(0x80, 0xde, v.Len())
+		for _, key := range v.MapKeys() {
+			encodeMsgpack(buf, key)
+			encodeMsgpack(buf, v.MapIndex(key))
+		}
+	case reflect.Struct:
+		writeHeader(0x80, 0xde, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			if name == "" {
+				name = v.Type().Field(i).Name
+			}
+			encodeMsgpack(buf, reflect.ValueOf(name))
+			encodeMsgpack(buf, v.Field(i))
+		}
+	}
+}
+
+func marshalMsgpack(v any) []byte {
+	var buf bytes.Buffer
+	encodeMsgpack(&buf, reflect.ValueOf(v))
+	return buf.Bytes()
+}
+
+func makeMsgpackResponse(v any) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/msgpack"}},
+		Body:       io.NopCloser(bytes.NewReader(marshalMsgpack(v))),
+	}
+}
+
+func TestMsgpackCodec_RoundTrip(t *testing.T) {
+	redirects := redirectListPage{
+		Items: []types.Redirect{
+			{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+			{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/" + strings.Repeat("x", 70000), Status: types.RedirectStatusFound},
+		},
+		Total:      2,
+		Limit:      100,
+		NextCursor: "c2",
+	}
+	var decoded redirectListPage
+
+	err := MsgpackCodec{}.Decode(bytes.NewReader(marshalMsgpack(redirects)), &decoded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, redirects, decoded)
+}
+
+func TestMsgpackCodec_Decode(t *testing.T) {
+	type target struct {
+		Name     string `json:"name"`
+		Count    int
+		Small    int8
+		Unsigned uint16
+		Ratio    float64
+		Ok       bool
+		Raw      []byte
+		Tags     []string
+		Labels   map[string]int
+		Ptr      *int
+		Any      any
+		Skipped  string `json:"-"`
+	}
+	tests := []struct {
+		name    string
+		input   []byte
+		want    target
+		wantErr string
+	}{
+		{name: "tagged field", input: []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xa2, 'h', 'i'}, want: target{Name: "hi"}},
+		{name: "case-insensitive key", input: []byte{0x81, 0xa5, 'c', 'o', 'u', 'n', 't', 0x05}, want: target{Count: 5}},
+		{name: "negative fixint", input: []byte{0x81, 0xa5, 'C', 'o', 'u', 'n', 't', 0xff}, want: target{Count: -1}},
+		{name: "int16", input: []byte{0x81, 0xa5, 'C', 'o', 'u', 'n', 't', 0xd1, 0xfc, 0x18}, want: target{Count: -1000}},
+		{name: "uint32", input: []byte{0x81, 0xa5, 'C', 'o', 'u', 'n', 't', 0xce, 0x00, 0x01, 0x00, 0x00}, want: target{Count: 65536}},
+		{name: "float32", input: []byte{0x81, 0xa5, 'R', 'a', 't', 'i', 'o', 0xca, 0x3f, 0xc0, 0x00, 0x00}, want: target{Ratio: 1.5}},
+		{name: "bool", input: []byte{0x81, 0xa2, 'O', 'k', 0xc3}, want: target{Ok: true}},
+		{name: "bin", input: []byte{0x81, 0xa3, 'R', 'a', 'w', 0xc4, 0x02, 0x01, 0x02}, want: target{Raw: []byte{1, 2}}},
+		{name: "str8 into string", input: []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xd9, 0x01, 'x'}, want: target{Name: "x"}},
+		{name: "array", input: []byte{0x81, 0xa4, 'T', 'a', 'g', 's', 0x92, 0xa1, 'a', 0xa1, 'b'}, want: target{Tags: []string{"a", "b"}}},
+		{name: "map", input: []byte{0x81, 0xa6, 'L', 'a', 'b', 'e', 'l', 's', 0x81, 0xa1, 'a', 0x01}, want: target{Labels: map[string]int{"a": 1}}},
+		{name: "pointer", input: []byte{0x81, 0xa3, 'P', 't', 'r', 0x07}, want: target{Ptr: func() *int { i := 7; return &i }()}},
+		{name: "nil", input: []byte{0x81, 0xa4, 'T', 'a', 'g', 's', 0xc0}, want: target{}},
+		{name: "any", input: []byte{0x81, 0xa3, 'A', 'n', 'y', 0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x91, 0xc2}, want: target{Any: map[string]any{"a": uint64(1), "b": []any{false}}}},
+		{name: "unknown and ignored keys skipped", input: []byte{0x83, 0xa1, 'x', 0x81, 0xa1, 'y', 0x92, 0x01, 0xc4, 0x01, 0x00, 0xa7, 'S', 'k', 'i', 'p', 'p', 'e', 'd', 0xa1, 'z', 0xa5, 'S', 'm', 'a', 'l', 'l', 0x02}, want: target{Small: 2}},
+		{name: "int overflow", input: []byte{0x81, 0xa5, 'S', 'm', 'a', 'l', 'l', 0xcd, 0x01, 0x00}, wantErr: "overflows int8"},
+		{name: "negative into unsigned", input: []byte{0x81, 0xa8, 'U', 'n', 's', 'i', 'g', 'n', 'e', 'd', 0xff}, wantErr: "overflows uint16"},
+		{name: "type mismatch", input: []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0x01}, wantErr: "cannot decode integer into string"},
+		{name: "extension", input: []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xd4, 0x01, 0x00}, wantErr: "unsupported type"},
+		{name: "truncated", input: []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xa5, 'h'}, wantErr: "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got target
+			err := MsgpackCodec{}.Decode(bytes.NewReader(tt.input), &got)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMsgpackCodec_DecodeMaxDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		return append(bytes.Repeat([]byte{0x91}, depth), 0xc0)
+	}
+	tests := []struct {
+		name   string
+		prefix []byte
+		depth  int
+		target any
+		err    error
+	}{
+		{name: "any at limit", depth: msgpackMaxDepth, target: new(any)},
+		{name: "any past limit", depth: msgpackMaxDepth + 1, target: new(any), err: errMsgpackTooDeep},
+		{name: "deep body", depth: 20 << 20, target: new(any), err: errMsgpackTooDeep},
+		{name: "skipped", prefix: []byte{0x81, 0xa1, 'x'}, depth: 20 << 20, target: new(types.RedirectList), err: errMsgpackTooDeep},
+		{name: "slices", depth: 20 << 20, target: new([][][]any), err: errMsgpackTooDeep},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append(tt.prefix, nested(tt.depth)...)
+
+			err := MsgpackCodec{}.Decode(bytes.NewReader(input), tt.target)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMsgpackCodec_DecodeNonPointer(t *testing.T) {
+	var list types.RedirectList
+
+	err := MsgpackCodec{}.Decode(bytes.NewReader([]byte{0x80}), list)
+
+	assert.Error(t, err)
+}
+
+func TestClient_getProjectRedirects_AcceptFormatMsgpack(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.AcceptFormat = AcceptFormatMsgpack
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+	}
+	mockHTTP.expect(makeMsgpackResponse(redirectListPage{Items: redirects, Total: 1}), nil)
+
+	result, err := c.getProjectRedirects(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, redirects, result)
+	assert.Equal(t, "application/msgpack, application/json;q=0.9", mockHTTP.calls[0].Header.Get("Accept"))
+}
+
+func TestClient_getProjectPages_AcceptFormatMsgpackJSONFallback(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.AcceptFormat = AcceptFormatMsgpack
+
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain},
+	}
+	mockHTTP.expect(makePagesResponse(pages, 1), nil)
+
+	result, err := c.getProjectPages(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, pages, result)
+}
+
+func TestClient_Init_InvalidAcceptFormat(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().Http.AcceptFormat = "xml"
+
+	err := c.Init()
+
+	assert.EqualError(t, err, "invalid accept format: xml")
+	assert.Empty(t, mockHTTP.calls)
+}
@@ -0,0 +1,253 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/jonboulle/clockwork"
+)
+
+// ProjectRef selects one project of a MultiClient. Hosts lists the request hosts
+// routed to that project by the host-based match methods.
+type ProjectRef struct {
+	NamespaceCode string
+	ProjectCode   string
+	Hosts         []string
+}
+
+func (p ProjectRef) key() string {
+	return p.NamespaceCode + "/" + p.ProjectCode
+}
+
+// MultiClient serves several projects with one HTTP client and one refresh loop.
+type MultiClient interface {
+	Init() error
+	Reload() error
+	Start(ctx context.Context)
+	Project(namespaceCode, projectCode string) Client
+	ForHost(host string) Client
+	RedirectMatch(host, uri string) (*types.Redirect, string)
+	PageMatch(host, uri string) *types.Page
+	Flush(ctx context.Context) error
+	Close() error
+}
+
+type multiClient struct {
+	cfg     *Config
+	clock   clockwork.Clock
+	clients []*client
+	byKey   map[string]*client
+	byHost  map[string]*client
+	batch   *statusBatcher
+
+	closed    chan struct{}
+	closeInit sync.Once
+	closeOnce sync.Once
+}
+
+// NewMulti builds one client per project from cfg; NamespaceCode and ProjectCode
+// of cfg are ignored. Every project shares cfg.Http and the options.
+func NewMulti(cfg *Config, projects []ProjectRef, opts ...Option) (MultiClient, error) {
+	base := &client{clock: clockwork.NewRealClock()}
+	for _, opt := range opts {
+		opt(base)
+	}
+	m := &multiClient{cfg: cfg, clock: base.clock, byKey: make(map[string]*client), byHost: make(map[string]*client)}
+	projectOpts := append(slices.Clone(opts), WithClock(m.clock))
+	requestSlots := newRequestSlots(cfg.MaxConcurrentRequests)
+	if cfg.AgentStatusBatchWindow > 0 {
+		m.batch = newStatusBatcher(cfg.AgentStatusBatchWindow, m.clock)
+	}
+	for _, project := range projects {
+		if _, found := m.byKey[project.key()]; found {
+			return nil, fmt.Errorf("duplicate project %s", project.key())
+		}
+		projectCfg := *cfg
+		projectCfg.NamespaceCode = project.NamespaceCode
+		projectCfg.ProjectCode = project.ProjectCode
+		c := New(&projectCfg, projectOpts...).(*client)
+		c.statusBatch = m.batch
+		c.requestSlots = requestSlots
+		for _, host := range project.Hosts {
+			host = strings.ToLower(host)
+			if _, found := m.byHost[host]; found {
+				return nil, fmt.Errorf("host %s routed to several projects", host)
+			}
+			m.byHost[host] = c
+		}
+		m.byKey[project.key()] = c
+		m.clients = append(m.clients, c)
+	}
+	return m, nil
+}
+
+func (m *multiClient) Init() error {
+	var errs []error
+	for _, c := range m.clients {
+		if err := c.Init(); err != nil {
+			errs = append(errs, fmt.Errorf("project %s/%s: %w", c.config().NamespaceCode, c.config().ProjectCode, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *multiClient) Reload() error {
+	var errs []error
+	for _, c := range m.clients {
+		if err := c.Reload(); err != nil {
+			errs = append(errs, fmt.Errorf("project %s/%s: %w", c.config().NamespaceCode, c.config().ProjectCode, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start reloads every project on its IntervalCheck until ctx is done or Close is
+// called. Like Client.Start, a project not loaded yet is reloaded right away, a
+// paused project skips its reloads, and a failing one backs off up to
+// MaxIntervalCheck and honors Retry-After. A project with a zero IntervalCheck is
+// never reloaded in the background. Projects are reloaded
+// in turn by this single loop, so TriggerReload only serves a project's own Start.
+func (m *multiClient) Start(ctx context.Context) {
+	backoffs := make([]reloadBackoff, len(m.clients))
+	due := make([]time.Time, len(m.clients))
+	startup := make([]bool, len(m.clients))
+	for i, c := range m.clients {
+		startup[i] = c.reloadOnStart()
+		if startup[i] {
+			due[i] = m.clock.Now()
+		} else {
+			due[i] = m.planReload(c, c.config().GetIntervalCheck())
+		}
+	}
+	next, planned := m.untilNext(due)
+	timer := m.clock.NewTimer(next)
+	defer timer.Stop()
+	if !planned {
+		stopTimer(timer)
+	}
+	closed := m.closedChan()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-timer.Chan():
+		case <-ctx.Done():
+			return
+		}
+		for i, c := range m.clients {
+			if due[i].IsZero() || m.clock.Now().Before(due[i]) {
+				continue
+			}
+			if c.IsPaused() {
+				due[i] = m.planReload(c, c.config().GetIntervalCheck())
+				continue
+			}
+			reason := TriggerReasonTimer
+			if startup[i] {
+				reason, startup[i] = TriggerReasonStartup, false
+			}
+			_, err := c.ReloadDetailed(withTriggerReason(context.Background(), reason))
+			due[i] = m.planReload(c, backoffs[i].next(c, err))
+		}
+		if next, planned := m.untilNext(due); planned {
+			timer.Reset(next)
+		} else {
+			stopTimer(timer)
+		}
+	}
+}
+
+// planReload returns when to reload c in d, or the zero time when its
+// IntervalCheck is zero: no background reload is planned then.
+func (m *multiClient) planReload(c *client, d time.Duration) time.Time {
+	if c.config().GetIntervalCheck() == 0 {
+		return time.Time{}
+	}
+	return m.clock.Now().Add(d)
+}
+
+// untilNext returns the delay before the earliest planned reload, if any.
+func (m *multiClient) untilNext(due []time.Time) (time.Duration, bool) {
+	planned := slices.DeleteFunc(slices.Clone(due), time.Time.IsZero)
+	if len(planned) == 0 {
+		return 0, false
+	}
+	return max(slices.MinFunc(planned, time.Time.Compare).Sub(m.clock.Now()), 0), true
+}
+
+func (m *multiClient) closedChan() chan struct{} {
+	m.closeInit.Do(func() {
+		m.closed = make(chan struct{})
+	})
+	return m.closed
+}
+
+// Close stops the Start loop and closes every project, then posts the agent
+// statuses waiting for the batch window. Matchers keep serving the last states.
+func (m *multiClient) Close() error {
+	var errs []error
+	m.closeOnce.Do(func() {
+		close(m.closedChan())
+		for _, c := range m.clients {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("project %s/%s: %w", c.config().NamespaceCode, c.config().ProjectCode, err))
+			}
+		}
+		if err := m.Flush(context.Background()); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Flush posts the agent statuses waiting for the batch window, e.g. before
+// shutting down. It does nothing unless Config.AgentStatusBatchWindow is set.
+func (m *multiClient) Flush(ctx context.Context) error {
+	if m.batch == nil {
+		return nil
+	}
+	return m.batch.flush(ctx)
+}
+
+// Project returns the client of a project, or nil when it is not configured.
+func (m *multiClient) Project(namespaceCode, projectCode string) Client {
+	c, found := m.byKey[ProjectRef{NamespaceCode: namespaceCode, ProjectCode: projectCode}.key()]
+	if !found {
+		return nil
+	}
+	return c
+}
+
+// ForHost returns the client of the project routed for host, or nil.
+func (m *multiClient) ForHost(host string) Client {
+	if m.cfg.NormalizeHost {
+		host = normalizeHost(host)
+	}
+	c, found := m.byHost[strings.ToLower(host)]
+	if !found {
+		return nil
+	}
+	return c
+}
+
+func (m *multiClient) RedirectMatch(host, uri string) (*types.Redirect, string) {
+	c := m.ForHost(host)
+	if c == nil {
+		return nil, ""
+	}
+	return c.RedirectMatch(host, uri)
+}
+
+func (m *multiClient) PageMatch(host, uri string) *types.Page {
+	c := m.ForHost(host)
+	if c == nil {
+		return nil
+	}
+	return c.PageMatch(host, uri)
+}
@@ -0,0 +1,301 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMultiClient(t *testing.T, configure ...func(*Config)) (MultiClient, *mockHTTPClient, clockwork.FakeClock) {
+	mockHTTP := newMockHTTPClient()
+	fakeClock := clockwork.NewFakeClock()
+	cfg := NewDefaultConfig()
+	cfg.ManagerUrl = "http://localhost:8080"
+	cfg.AgentName = "test-node"
+	cfg.AgentType = types.AgentTypeDefault
+	cfg.Http.Client = mockHTTP
+	cfg.Logger = nil
+	cfg.SkipCompatibilityCheck = true
+	cfg.SkipReloadOnStart = true
+	for _, f := range configure {
+		f(cfg)
+	}
+
+	m, err := NewMulti(cfg, []ProjectRef{
+		{NamespaceCode: "ns", ProjectCode: "shop", Hosts: []string{"shop.example.com"}},
+		{NamespaceCode: "ns", ProjectCode: "blog", Hosts: []string{"Blog.example.com"}},
+	}, WithClock(fakeClock))
+	assert.NoError(t, err)
+	return m, mockHTTP, fakeClock
+}
+
+func TestMultiClient_Init_StateIsolation(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t)
+
+	expectLoad(mockHTTP, testLoad{version: "3", redirects: []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/shop"}}})
+	expectLoad(mockHTTP, testLoad{version: "8", redirects: []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/blog"}}})
+
+	err := m.Init()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/api/namespace/ns/project/shop/version", mockHTTP.calls[0].URL.String())
+	assert.Equal(t, "http://localhost:8080/api/namespace/ns/project/blog/version", mockHTTP.calls[5].URL.String())
+	assert.Equal(t, 3, m.Project("ns", "shop").GetStateVersion())
+	assert.Equal(t, 8, m.Project("ns", "blog").GetStateVersion())
+	assert.Nil(t, m.Project("ns", "missing"))
+
+	_, target := m.RedirectMatch("shop.example.com", "/old")
+	assert.Equal(t, "/shop", target)
+	_, target = m.RedirectMatch("blog.EXAMPLE.com", "/old")
+	assert.Equal(t, "/blog", target)
+	redirect, _ := m.RedirectMatch("unknown.example.com", "/old")
+	assert.Nil(t, redirect)
+	assert.Nil(t, m.PageMatch("unknown.example.com", "/robots.txt"))
+	assert.Same(t, m.Project("ns", "blog"), m.ForHost("blog.example.com"))
+}
+
+func TestMultiClient_Reload_ErrorNamesProject(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t)
+
+	expectLoad(mockHTTP, testLoad{version: "1"})
+	mockHTTP.expect(makeErrorResponse(500), nil)
+
+	err := m.Reload()
+
+	assert.ErrorContains(t, err, "project ns/blog")
+	assert.NotContains(t, err.Error(), "project ns/shop")
+	assert.Equal(t, 1, m.Project("ns", "shop").GetStateVersion())
+	assert.Equal(t, 0, m.Project("ns", "blog").GetStateVersion())
+}
+
+func TestMultiClient_Start(t *testing.T) {
+	m, mockHTTP, fakeClock := newTestMultiClient(t)
+
+	expectLoad(mockHTTP, testLoad{version: "1"})
+	expectLoad(mockHTTP, testLoad{version: "2"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	cancel()
+	<-done
+
+	assert.Len(t, mockHTTP.calls, 10)
+	assert.Equal(t, 2, m.Project("ns", "blog").GetStateVersion())
+}
+
+func TestMultiClient_Start_ReloadOnStart(t *testing.T) {
+	m, mockHTTP, fakeClock := newTestMultiClient(t, func(cfg *Config) { cfg.SkipReloadOnStart = false })
+	require.NoError(t, m.Project("ns", "blog").LoadFromData(1, nil, nil))
+
+	expectLoad(mockHTTP, testLoad{version: "3"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Start(ctx)
+		close(done)
+	}()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	assert.NoError(t, m.Project("ns", "shop").WaitReady(waitCtx))
+	fakeClock.BlockUntil(1)
+	cancel()
+	<-done
+
+	assert.Len(t, mockHTTP.calls, 5)
+	assert.Equal(t, 3, m.Project("ns", "shop").GetStateVersion())
+	assert.Equal(t, 1, m.Project("ns", "blog").GetStateVersion())
+	assert.Equal(t, []TriggerReason{TriggerReasonStartup}, postedTriggerReasons(t, mockHTTP, "http://localhost:8080/api/namespace/ns/project/shop/agents"))
+}
+
+func TestMultiClient_Start_ZeroIntervalCheck(t *testing.T) {
+	m, mockHTTP, fakeClock := newTestMultiClient(t, func(cfg *Config) {
+		cfg.IntervalCheck = 0
+		cfg.SkipReloadOnStart = false
+	})
+	require.NoError(t, m.Project("ns", "blog").LoadFromData(1, nil, nil))
+	expectLoad(mockHTTP, testLoad{version: "3"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Start(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return m.Project("ns", "shop").GetStateVersion() == 3 }, time.Second, time.Millisecond)
+	fakeClock.Advance(24 * time.Hour)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Len(t, mockHTTP.calls, 5)
+}
+
+func TestMultiClient_Start_BackoffPerProject(t *testing.T) {
+	m, mockHTTP, fakeClock := newTestMultiClient(t, func(cfg *Config) {
+		cfg.MaxIntervalCheck = 20 * time.Minute
+	})
+	shop, blog := m.Project("ns", "shop"), m.Project("ns", "blog")
+
+	mockHTTP.expect(nil, errors.New("network error"))
+	expectLoad(mockHTTP, testLoad{version: "1"})
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	expectLoad(mockHTTP, testLoad{version: "2"})
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Equal(t, uint64(1), shop.Stats().Attempted)
+	assert.Equal(t, uint64(1), blog.Stats().Attempted)
+
+	// the failed project waits twice the interval, the other one keeps its pace
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Equal(t, uint64(1), shop.Stats().Attempted)
+	assert.Equal(t, uint64(2), blog.Stats().Attempted)
+
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	assert.Equal(t, uint64(2), shop.Stats().Attempted)
+	assert.Equal(t, 2, shop.GetStateVersion())
+	assert.Equal(t, uint64(3), blog.Stats().Attempted)
+
+	cancel()
+	<-done
+	assert.Len(t, mockHTTP.calls, 15)
+}
+
+func TestMultiClient_Start_PausedProject(t *testing.T) {
+	m, mockHTTP, fakeClock := newTestMultiClient(t)
+	m.Project("ns", "shop").Pause()
+
+	expectLoad(mockHTTP, testLoad{version: "2"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Start(ctx)
+		close(done)
+	}()
+
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(5 * time.Minute)
+	fakeClock.BlockUntil(1)
+	cancel()
+	<-done
+
+	assert.Len(t, mockHTTP.calls, 5)
+	assert.Equal(t, 0, m.Project("ns", "shop").GetStateVersion())
+	assert.Equal(t, 2, m.Project("ns", "blog").GetStateVersion())
+}
+
+func TestMultiClient_Close(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t, func(cfg *Config) {
+		cfg.ReportLifecycle = true
+	})
+	expectLoad(mockHTTP, testLoad{version: "3"})
+	expectLoad(mockHTTP, testLoad{version: "8"})
+	assert.NoError(t, m.Init())
+
+	done := make(chan struct{})
+	go func() {
+		m.Start(context.Background())
+		close(done)
+	}()
+
+	mockHTTP.expect(makeAgentResponse(), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, m.Close())
+	assert.NoError(t, m.Close())
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not exit after Close")
+	}
+	assert.Len(t, mockHTTP.calls, 12)
+	for _, call := range mockHTTP.calls[10:] {
+		assert.Equal(t, http.MethodPost, call.Method)
+	}
+	assert.Equal(t, "http://localhost:8080/api/namespace/ns/project/blog/agents", mockHTTP.calls[11].URL.String())
+}
+
+func TestMultiClient_Close_FlushesBatch(t *testing.T) {
+	m, mockHTTP, _ := newTestMultiClient(t, func(cfg *Config) {
+		cfg.ReportLifecycle = true
+		cfg.AgentStatusBatchWindow = time.Minute
+	})
+	for _, version := range []string{"3", "8"} {
+		mockHTTP.expect(makeVersionResponse(version), nil)
+		mockHTTP.expect(makeVersionResponse(version), nil)
+		mockHTTP.expect(makeRedirectsResponse(nil, 0), nil)
+		mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	}
+	assert.NoError(t, m.Init())
+
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, m.Close())
+
+	assert.Len(t, mockHTTP.calls, 9)
+	assert.Equal(t, "http://localhost:8080/api/agents/batch", mockHTTP.calls[8].URL.String())
+	body, _ := io.ReadAll(mockHTTP.calls[8].Body)
+	assert.Equal(t, 2, strings.Count(string(body), AgentStoppedError))
+}
+
+func TestNewMulti_Duplicates(t *testing.T) {
+	cfg := NewDefaultConfig()
+
+	_, err := NewMulti(cfg, []ProjectRef{{NamespaceCode: "ns", ProjectCode: "a"}, {NamespaceCode: "ns", ProjectCode: "a"}})
+	assert.ErrorContains(t, err, "duplicate project ns/a")
+
+	_, err = NewMulti(cfg, []ProjectRef{
+		{NamespaceCode: "ns", ProjectCode: "a", Hosts: []string{"example.com"}},
+		{NamespaceCode: "ns", ProjectCode: "b", Hosts: []string{"EXAMPLE.com"}},
+	})
+	assert.ErrorContains(t, err, "host example.com routed to several projects")
+}
+
+func TestMultiClient_ForHost_NormalizeHost(t *testing.T) {
+	m, _, _ := newTestMultiClient(t, func(cfg *Config) {
+		cfg.NormalizeHost = true
+	})
+
+	assert.Same(t, m.Project("ns", "shop"), m.ForHost("Shop.example.com:8443"))
+}
+
+func TestNewMulti_SharesRequestSlots(t *testing.T) {
+	m, _, _ := newTestMultiClient(t, func(cfg *Config) { cfg.MaxConcurrentRequests = 2 })
+
+	shop := m.Project("ns", "shop").(*client)
+	blog := m.Project("ns", "blog").(*client)
+	assert.Equal(t, 2, cap(shop.requestSlots))
+	assert.True(t, shop.requestSlots == blog.requestSlots)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// buildRedirectIndexParallel builds a redirectIndex from keys, compiling the regex
+// rules on up to workers goroutines, for Config.ParallelInsert. The index matches
+// like BuildRedirectMatcher and fails on the same regex, the first invalid one by
+// source. It returns ok=false when keys has several rules of the same type and
+// source, which only types.RedirectTree resolves.
+func buildRedirectIndexParallel(keys []types.Redirect, workers int) (index *redirectIndex, ok bool, err error) {
+	if len(lastRedirects(keys)) != len(keys) {
+		return nil, false, nil
+	}
+	keys = slices.Clone(keys)
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return strings.Compare(keys[a].Source, keys[b].Source)
+	})
+
+	compiled, errs := compileRegexes(keys, workers)
+	for _, i := range order {
+		if errs[i] != nil {
+			return nil, true, errs[i]
+		}
+	}
+
+	// Buckets are filled in source order, so each is sorted as regexIndex.insert keeps it.
+	index = newRedirectIndex()
+	buckets := map[types.RedirectType]map[string][]*compiledRegex{
+		types.RedirectTypeRegexHost: {},
+		types.RedirectTypeRegex:     {},
+	}
+	for _, i := range order {
+		r := &keys[i]
+		switch r.Type {
+		case types.RedirectTypeBasicHost:
+			index.basicHost[r.Source] = r
+		case types.RedirectTypeBasic:
+			index.basic[r.Source] = r
+		case types.RedirectTypeRegexHost, types.RedirectTypeRegex:
+			prefix := regexLiteralPrefix(r.Source)
+			buckets[r.Type][prefix] = append(buckets[r.Type][prefix], &compiledRegex{redirect: r, re: compiled[i]})
+		}
+		index.rules[redirectKey{r.Type, r.Source}] = r
+	}
+	for redirectType, byPrefix := range buckets {
+		regexes := index.regexIndex(redirectType)
+		for prefix, bucket := range byPrefix {
+			regexes.setBucket(prefix, bucket)
+		}
+	}
+	return index, true, nil
+}
+
+// compileRegexes compiles the sources of the regex rules of redirects on up to
+// workers goroutines. Results and errors are at the index of their rule.
+func compileRegexes(redirects []types.Redirect, workers int) ([]*regexp.Regexp, []error) {
+	compiled := make([]*regexp.Regexp, len(redirects))
+	errs := make([]error, len(redirects))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, min(workers, len(redirects))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				compiled[i], errs[i] = regexp.Compile(redirects[i].Source)
+			}
+		}()
+	}
+	for i, r := range redirects {
+		if r.Type == types.RedirectTypeRegexHost || r.Type == types.RedirectTypeRegex {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return compiled, errs
+}
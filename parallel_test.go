@@ -0,0 +1,113 @@
+package client
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRedirectIndexParallel_MatchesRedirectTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var keys []types.Redirect
+	for i := 0; i < 500; i++ {
+		keys = append(keys, randomRedirect(rng))
+	}
+	keys = uniqueRedirects(keys)
+	var inputs []string
+	for i := 0; i < 500; i++ {
+		inputs = append(inputs, randomPath(rng), randomPath(rng)+"/y")
+	}
+
+	tree, err := BuildRedirectMatcher(keys)
+	require.NoError(t, err)
+	for _, workers := range []int{1, 4, 64} {
+		index, ok, err := buildRedirectIndexParallel(keys, workers)
+		require.True(t, ok)
+		require.NoError(t, err)
+
+		hits := 0
+		for _, input := range inputs {
+			wantRedirect, wantTarget := tree.Match("example.com", input)
+			gotRedirect, gotTarget := index.Match("example.com", input)
+			require.Equal(t, wantRedirect, gotRedirect, "workers %d, input %q", workers, input)
+			require.Equal(t, wantTarget, gotTarget, "workers %d, input %q", workers, input)
+			if gotRedirect != nil {
+				hits++
+			}
+		}
+		assert.Greater(t, hits, 100)
+	}
+}
+
+func TestBuildRedirectIndexParallel_InvalidRegex(t *testing.T) {
+	keys := []types.Redirect{
+		{Type: types.RedirectTypeRegex, Source: "^/z(", Target: "/a"},
+		{Type: types.RedirectTypeRegex, Source: "^/ok/(.*)$", Target: "/b"},
+		{Type: types.RedirectTypeRegex, Source: "^/b[", Target: "/c"},
+	}
+	_, want := BuildRedirectMatcher(keys)
+	require.Error(t, want)
+
+	for i := 0; i < 20; i++ {
+		_, ok, err := buildRedirectIndexParallel(keys, 3)
+
+		assert.True(t, ok)
+		assert.EqualError(t, err, want.Error())
+	}
+}
+
+func TestBuildRedirectIndexParallel_DuplicateKeys(t *testing.T) {
+	_, ok, err := buildRedirectIndexParallel([]types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/a"},
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/b"},
+	}, 2)
+
+	assert.False(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestClient_ParallelInsert(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().ParallelInsert = true
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1"},
+		{Type: types.RedirectTypeRegexHost, Source: `^example\.com/shop/(.*)$`, Target: "/store/$1"},
+	}
+
+	require.NoError(t, c.LoadFromData(1, redirects, nil))
+
+	assert.IsType(t, &redirectIndex{}, c.load().RedirectMatcher)
+	_, target := c.RedirectMatch("example.com", "/blog/post")
+	assert.Equal(t, "/news/post", target)
+	_, target = c.RedirectMatch("example.com", "/shop/x")
+	assert.Equal(t, "/store/x", target)
+	_, target = c.RedirectMatch("example.com", "/old")
+	assert.Equal(t, "/new", target)
+	assert.ErrorContains(t, c.LoadFromData(2, []types.Redirect{{Type: types.RedirectTypeRegex, Source: "([", Target: "/a"}}, nil), "missing closing ]")
+	assert.Equal(t, 1, c.GetStateVersion())
+}
+
+func BenchmarkBuildRedirectMatcher_Serial(b *testing.B) {
+	redirects := benchmarkRedirects(20000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildRedirectMatcher(redirects); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildRedirectMatcher_Parallel(b *testing.B) {
+	redirects := benchmarkRedirects(20000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := buildRedirectIndexParallel(redirects, runtime.GOMAXPROCS(0)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// decodeMatchPath percent-decodes the path of uri for Config.DecodePathForMatch,
+// leaving its query as is. Escapes of "/", "?", "#" and "%" are kept, in
+// uppercase, so that they do not change how the path splits nor get decoded
+// twice, and so are those of space and control characters, which would
+// otherwise reach targets built from regex captures, e.g. as CR LF in a
+// Location header. A path with an invalid escape is returned unchanged.
+func decodeMatchPath(uri string) string {
+	path, query, hasQuery := strings.Cut(uri, "?")
+	if !strings.Contains(path, "%") {
+		return uri
+	}
+	var decoded strings.Builder
+	decoded.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] != '%' {
+			decoded.WriteByte(path[i])
+			continue
+		}
+		if i+2 >= len(path) || !isHex(path[i+1]) || !isHex(path[i+2]) {
+			return uri
+		}
+		b := unhex(path[i+1])<<4 | unhex(path[i+2])
+		switch {
+		case b == '/', b == '?', b == '#', b == '%', b <= ' ', b == 0x7f:
+			decoded.WriteString(strings.ToUpper(path[i : i+3]))
+		default:
+			decoded.WriteByte(b)
+		}
+		i += 2
+	}
+	if hasQuery {
+		decoded.WriteByte('?')
+		decoded.WriteString(query)
+	}
+	return decoded.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	default:
+		return c - 'a' + 10
+	}
+}
+
+// decodeRedirect returns the copy of a redirect inserted under
+// Config.DecodePathForMatch: exact sources are decoded like request paths.
+// Regex sources are left as is and run against the decoded path.
+func decodeRedirect(redirect types.Redirect) *types.Redirect {
+	if redirect.Type == types.RedirectTypeBasic || redirect.Type == types.RedirectTypeBasicHost {
+		redirect.Source = decodeMatchPath(redirect.Source)
+	}
+	return &redirect
+}
+
+func decodePage(page types.Page) *types.Page {
+	page.Path = decodeMatchPath(page.Path)
+	return &page
+}
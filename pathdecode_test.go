@@ -0,0 +1,119 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeMatchPath(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{uri: "/plain", want: "/plain"},
+		{uri: "/caf%C3%A9", want: "/café"},
+		{uri: "/caf%c3%a9", want: "/café"},
+		{uri: "/a%20b", want: "/a%20b"},
+		{uri: "/a%0d%0aSet-Cookie:%20x=1", want: "/a%0D%0ASet-Cookie:%20x=1"},
+		{uri: "/a%00b%09c%7Fd", want: "/a%00b%09c%7Fd"},
+		{uri: "/a%7E%0Ab", want: "/a~%0Ab"},
+		{uri: "/a%2fb", want: "/a%2Fb"},
+		{uri: "/a%3Fb%23c", want: "/a%3Fb%23c"},
+		{uri: "/100%25", want: "/100%25"},
+		{uri: "/100%", want: "/100%"},
+		{uri: "/bad%zz/caf%C3%A9", want: "/bad%zz/caf%C3%A9"},
+		{uri: "/caf%C3%A9?q=a%26b", want: "/café?q=a%26b"},
+		{uri: "/search?q=%C3%A9", want: "/search?q=%C3%A9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodeMatchPath(tt.uri))
+		})
+	}
+}
+
+func TestClient_DecodePathForMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		uri    string
+		want   string
+	}{
+		{name: "encoded uri, decoded source", source: "/café", uri: "/caf%C3%A9", want: "/coffee"},
+		{name: "decoded uri, encoded source", source: "/caf%C3%A9", uri: "/café", want: "/coffee"},
+		{name: "both encoded, different case", source: "/caf%c3%a9", uri: "/caf%C3%A9", want: "/coffee"},
+		{name: "both decoded", source: "/café", uri: "/café", want: "/coffee"},
+		{name: "encoded slash stays distinct", source: "/a/b", uri: "/a%2Fb", want: ""},
+		{name: "invalid escape matched as is", source: "/100%", uri: "/100%", want: "/coffee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _ := newTestClient()
+			c.config().DecodePathForMatch = true
+			require.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeBasic, Source: tt.source, Target: "/coffee"}}, nil))
+
+			_, target := c.RedirectMatch("example.com", tt.uri)
+
+			assert.Equal(t, tt.want, target)
+		})
+	}
+}
+
+func TestClient_DecodePathForMatch_Disabled(t *testing.T) {
+	c, _, _ := newTestClient()
+	require.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/café", Target: "/coffee"}}, nil))
+
+	redirect, _ := c.RedirectMatch("example.com", "/caf%C3%A9")
+
+	assert.Nil(t, redirect)
+}
+
+func TestClient_DecodePathForMatch_RegexAndPages(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().DecodePathForMatch = true
+	c.config().CaseInsensitivePaths = true
+	require.NoError(t, c.LoadFromData(1,
+		[]types.Redirect{
+			{Type: types.RedirectTypeRegex, Source: "^/menu/(.*)$", Target: "/carte/$1"},
+			{Type: types.RedirectTypeBasicHost, Source: "example.com/Cr%C3%A8me", Target: "/cream"},
+		},
+		[]types.Page{{Type: types.PageTypeBasic, Path: "/m%C3%A9nu.txt", Content: "menu"}},
+	))
+
+	_, target := c.RedirectMatch("example.com", "/menu/caf%C3%A9")
+	assert.Equal(t, "/carte/café", target)
+	_, target = c.RedirectMatch("example.com", "/CRÈME")
+	assert.Equal(t, "/cream", target)
+	page := c.PageMatch("example.com", "/M%C3%A9nu.txt")
+	if assert.NotNil(t, page) {
+		assert.Equal(t, "menu", page.Content)
+	}
+}
+
+func TestClient_DecodePathForMatch_ControlCharacters(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().DecodePathForMatch = true
+	require.NoError(t, c.LoadFromData(1, []types.Redirect{{Type: types.RedirectTypeRegex, Source: "^/old/([^?]*)$", Target: "/new/$1"}}, nil))
+
+	_, target := c.RedirectMatch("h", "/old/a%0D%0ASet-Cookie:%20x=1")
+
+	assert.Equal(t, "/new/a%0D%0ASet-Cookie:%20x=1", target)
+	assert.NotContains(t, target, "\r")
+	assert.NotContains(t, target, "\n")
+}
+
+func TestClient_DecodePathForMatch_ReuseMatchers(t *testing.T) {
+	c, _, _ := newTestClient()
+	c.config().ReuseMatchers = true
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/caf%C3%A9", Target: "/coffee"}}
+	require.NoError(t, c.LoadFromData(1, redirects, nil))
+
+	c.config().DecodePathForMatch = true
+	require.NoError(t, c.LoadFromData(2, redirects, nil))
+
+	_, target := c.RedirectMatch("example.com", "/café")
+	assert.Equal(t, "/coffee", target)
+}
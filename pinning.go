@@ -0,0 +1,68 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrCertificatePinMismatch is returned when no certificate presented by the
+// manager matches the pins given to NewPinnedHTTPConfig.
+var ErrCertificatePinMismatch = errors.New("certificate pin mismatch")
+
+// CertificatePin returns the pin of cert: the base64 SHA-256 of its subject
+// public key info.
+func CertificatePin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// NewPinnedHTTPConfig is NewHTTPConfig with DefaultHTTPTuning, whose transport
+// only talks to a manager presenting a certificate, leaf or intermediate, whose
+// CertificatePin is one of pins. A pin may be prefixed by "sha256/". The chain
+// is still verified against the root CAs first; with no pins, every connection
+// fails.
+func NewPinnedHTTPConfig(pins []string) *HTTPConfig {
+	cfg := NewHTTPConfig(DefaultHTTPTuning())
+	transport := cfg.Client.(*http.Client).Transport.(*http.Transport)
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.VerifyPeerCertificate = verifyPins(pins)
+	return cfg
+}
+
+func verifyPins(pins []string) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	accepted := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		accepted = append(accepted, strings.TrimPrefix(strings.TrimSpace(pin), "sha256/"))
+	}
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		certs := slices.Concat(verifiedChains...)
+		if len(verifiedChains) == 0 {
+			// InsecureSkipVerify: only the pins vouch for the manager
+			for _, raw := range rawCerts {
+				cert, err := x509.ParseCertificate(raw)
+				if err != nil {
+					return err
+				}
+				certs = append(certs, cert)
+			}
+		}
+		for _, cert := range certs {
+			if slices.Contains(accepted, CertificatePin(cert)) {
+				return nil
+			}
+		}
+		if len(certs) == 0 {
+			return fmt.Errorf("%w: no certificate presented", ErrCertificatePinMismatch)
+		}
+		return fmt.Errorf("%w: certificate %q has pin %s", ErrCertificatePinMismatch, certs[0].Subject.String(), CertificatePin(certs[0]))
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPinnedTestClient(t *testing.T, pins func(server *httptest.Server) []string) *client {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("1"))
+	}))
+	// rejected handshakes are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	httpCfg := NewPinnedHTTPConfig(pins(server))
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	httpCfg.Client.(*http.Client).Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+
+	cfg := NewDefaultConfig()
+	cfg.ManagerUrl = server.URL
+	cfg.NamespaceCode = "ns"
+	cfg.ProjectCode = "proj"
+	cfg.Http = httpCfg
+	cfg.Logger = nil
+	return New(cfg).(*client)
+}
+
+func TestNewPinnedHTTPConfig_Match(t *testing.T) {
+	tests := []struct {
+		name string
+		pin  func(pin string) string
+	}{
+		{name: "raw", pin: func(pin string) string { return pin }},
+		{name: "prefixed", pin: func(pin string) string { return "sha256/" + pin }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newPinnedTestClient(t, func(server *httptest.Server) []string {
+				return []string{"c29tZXRoaW5nIGVsc2U=", tt.pin(CertificatePin(server.Certificate()))}
+			})
+
+			version, err := c.getProjectVersion(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, 1, version)
+		})
+	}
+}
+
+func TestNewPinnedHTTPConfig_Mismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		pins []string
+	}{
+		{name: "other pin", pins: []string{"c29tZXRoaW5nIGVsc2U="}},
+		{name: "no pins"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newPinnedTestClient(t, func(*httptest.Server) []string { return tt.pins })
+
+			_, err := c.getProjectVersion(context.Background())
+
+			assert.ErrorIs(t, err, ErrCertificatePinMismatch)
+			assert.Contains(t, err.Error(), "has pin")
+		})
+	}
+}
+
+func TestNewPinnedHTTPConfig_InsecureSkipVerify(t *testing.T) {
+	c := newPinnedTestClient(t, func(server *httptest.Server) []string {
+		return []string{CertificatePin(server.Certificate())}
+	})
+	transport := c.config().Http.Client.(*http.Client).Transport.(*http.Transport)
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: transport.TLSClientConfig.VerifyPeerCertificate,
+	}
+
+	_, err := c.getProjectVersion(context.Background())
+
+	assert.NoError(t, err)
+}
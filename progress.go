@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultReloadProgressInterval is the minimum delay between two progress reports.
+const DefaultReloadProgressInterval = 30 * time.Second
+
+// reportProgress sends a liveness hit while a reload is paginating, when
+// Config.ReportReloadProgress is set and the interval elapsed. Agent statuses
+// carry no progress and would announce the new version too early, so a hit is
+// sent and the progress logged. The reload goes on if the hit fails.
+func (c *client) reportProgress(ctx context.Context, rules string, loaded, total int) {
+	cfg := c.config()
+	if !cfg.ReportReloadProgress || c.progressAt.IsZero() {
+		return
+	}
+	interval := cfg.ReloadProgressInterval
+	if interval <= 0 {
+		interval = DefaultReloadProgressInterval
+	}
+	now := c.clock.Now()
+	if now.Sub(c.progressAt) < interval {
+		return
+	}
+	c.progressAt = now
+	c.logger().InfoContext(ctx, "reload in progress", "rules", rules, "loaded", loaded, "total", total)
+	if err := c.sendAgentHit(ctx, cfg.AgentName); err != nil {
+		c.logger().WarnContext(ctx, "reporting reload progress", "error", err)
+		return
+	}
+	c.stats.hitsSent.Add(1)
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func callMethods(mockHTTP *mockHTTPClient) []string {
+	methods := make([]string, 0, len(mockHTTP.calls))
+	for _, call := range mockHTTP.calls {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+func TestClient_ReportReloadProgress(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().ReportReloadProgress = true
+	c.config().ReloadProgressInterval = 25 * time.Second
+	c.config().Http.Client = &slowHTTPClient{next: mockHTTP, clock: fakeClock, latency: 10 * time.Second}
+
+	expectLoad(mockHTTP, testLoad{version: "2", redirectsTotal: 300, progressHits: true})
+
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, []string{
+		http.MethodGet, http.MethodGet, http.MethodGet, http.MethodGet,
+		http.MethodPatch,
+		http.MethodGet, http.MethodGet,
+		http.MethodPatch,
+		http.MethodPost,
+	}, callMethods(mockHTTP))
+	assert.Contains(t, mockHTTP.calls[4].URL.Path, "/agents/test-node/hit")
+	assert.Equal(t, uint64(2), c.Stats().HitsSent)
+	assert.Equal(t, 2, c.GetStateVersion())
+}
+
+func TestClient_ReportReloadProgress_Disabled(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().Http.Client = &slowHTTPClient{next: mockHTTP, clock: fakeClock, latency: time.Minute}
+
+	expectLoad(mockHTTP, testLoad{version: "2", redirectsTotal: 300})
+
+	assert.NoError(t, c.Reload())
+	assert.NotContains(t, callMethods(mockHTTP), http.MethodPatch)
+}
+
+func TestClient_ReportReloadProgress_NotDuringWarmup(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().ReportReloadProgress = true
+	c.config().Http.Client = &slowHTTPClient{next: mockHTTP, clock: fakeClock, latency: time.Minute}
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 300), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 300), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 300), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+	assert.NoError(t, c.Warmup(context.Background()))
+	assert.NotContains(t, callMethods(mockHTTP), http.MethodPatch)
+}
+
+func TestClient_ReportReloadProgress_HitFailureKeepsLoading(t *testing.T) {
+	c, mockHTTP, fakeClock := newTestClient()
+	c.config().ReportReloadProgress = true
+	c.config().Http.Client = &slowHTTPClient{next: mockHTTP, clock: fakeClock, latency: DefaultReloadProgressInterval}
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 0), nil)
+	mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeErrorResponse(http.StatusInternalServerError), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, 2, c.GetStateVersion())
+	assert.Equal(t, uint64(0), c.Stats().HitsSent)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"errors"
+)
+
+func validateConfig(cfg *Config) error {
+	if cfg == nil {
+		return errors.New("nil config")
+	}
+	if cfg.Http == nil || cfg.Http.Client == nil {
+		return errors.New("config without HTTP client")
+	}
+	if err := validateAgentTypes(cfg); err != nil {
+		return err
+	}
+	if err := validateIntervalCheck(cfg); err != nil {
+		return err
+	}
+	if err := validateMaxManagerVersion(cfg); err != nil {
+		return err
+	}
+	return validateAgentVersion(cfg.AgentVersion)
+}
+
+// Reconfigure swaps the configuration, e.g. from a SIGHUP handler to pick up a
+// rotated token or a moved manager. It waits for an in-flight reload to finish
+// and keeps the loaded state, unless the namespace or project changed: the state
+// is then dropped and the new project loaded at once. cfg must not be modified
+// afterwards; pass a new Config on every call.
+func (c *client) Reconfigure(cfg *Config) error {
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+
+	c.reloadMu.Lock()
+	previous := c.config()
+	projectChanged := cfg.NamespaceCode != previous.NamespaceCode || cfg.ProjectCode != previous.ProjectCode
+	c.cfg.Store(cfg)
+	if projectChanged {
+		c.State.Store(emptyState())
+		c.lastReport = ""
+		c.lastPosted = ""
+		c.agentStatus = ""
+		c.breaker = circuitBreaker{}
+	}
+	c.reloadMu.Unlock()
+
+	if !projectChanged {
+		return nil
+	}
+	return c.Reload()
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func reconfigured(c *client, update func(cfg *Config)) *Config {
+	cfg := *c.config()
+	httpCfg := *cfg.Http
+	cfg.Http = &httpCfg
+	update(&cfg)
+	return &cfg
+}
+
+func TestClient_Reconfigure_TokenSwap(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}
+	assert.NoError(t, c.LoadFromData(2, redirects, nil))
+
+	err := c.Reconfigure(reconfigured(c, func(cfg *Config) {
+		cfg.Http.TokenJWT = "rotated-token"
+	}))
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockHTTP.calls)
+	assert.Equal(t, 2, c.GetStateVersion())
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, "Bearer rotated-token", mockHTTP.calls[0].Header.Get("Authorization"))
+	_, target := c.RedirectMatch("example.com", "/old")
+	assert.Equal(t, "/new", target)
+}
+
+func TestClient_Reconfigure_HTTPClientSwap(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	other := newMockHTTPClient()
+
+	assert.NoError(t, c.Reconfigure(reconfigured(c, func(cfg *Config) {
+		cfg.Http.Client = other
+	})))
+
+	other.expect(makeVersionResponse("0"), nil)
+	_, err := c.getProjectVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, mockHTTP.calls)
+	assert.Len(t, other.calls, 1)
+}
+
+func TestClient_Reconfigure_ProjectChange(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}
+	assert.NoError(t, c.LoadFromData(2, redirects, nil))
+
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 0), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	err := c.Reconfigure(reconfigured(c, func(cfg *Config) {
+		cfg.ProjectCode = "other-proj"
+	}))
+
+	assert.NoError(t, err)
+	assert.Len(t, mockHTTP.calls, 5)
+	assert.Contains(t, mockHTTP.calls[0].URL.Path, "/project/other-proj/version")
+	assert.Equal(t, http.MethodPost, mockHTTP.calls[4].Method)
+	assert.Equal(t, 2, c.GetStateVersion())
+	redirect, _ := c.RedirectMatch("example.com", "/old")
+	assert.Nil(t, redirect)
+}
+
+func TestClient_Reconfigure_Invalid(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	original := c.config()
+
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{name: "nil config", cfg: nil},
+		{name: "no HTTP client", cfg: reconfigured(c, func(cfg *Config) { cfg.Http.Client = nil })},
+		{name: "invalid agent type", cfg: reconfigured(c, func(cfg *Config) { cfg.AgentType = "invalid" })},
+		{name: "invalid agent version", cfg: reconfigured(c, func(cfg *Config) { cfg.AgentVersion = "not valid!" })},
+		{name: "interval check too small", cfg: reconfigured(c, func(cfg *Config) { cfg.IntervalCheck = 100 * time.Millisecond })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, c.Reconfigure(tt.cfg))
+			assert.Same(t, original, c.config())
+		})
+	}
+	assert.Empty(t, mockHTTP.calls)
+}
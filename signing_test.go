@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignRequest_Stable(t *testing.T) {
+	key := []byte("secret")
+	got := signRequest(key, http.MethodPost, "/api/agents?x=1", "1700000000", []byte(`{"a":1}`))
+
+	assert.Equal(t, "8bacbe1c7607ec6635029b832beee772a0c6e3c500c48a14e19d00d7c96047c9", got)
+	assert.Equal(t, got, signRequest(key, http.MethodPost, "/api/agents?x=1", "1700000000", []byte(`{"a":1}`)))
+}
+
+func TestSignRequest_ChangesWithInputs(t *testing.T) {
+	key := []byte("secret")
+	base := signRequest(key, http.MethodPost, "/api/agents", "1700000000", []byte(`{"a":1}`))
+
+	assert.NotEqual(t, base, signRequest(key, http.MethodPost, "/api/agents", "1700000000", []byte(`{"a":2}`)))
+	assert.NotEqual(t, base, signRequest(key, http.MethodPost, "/api/pages", "1700000000", []byte(`{"a":1}`)))
+	assert.NotEqual(t, base, signRequest(key, http.MethodPatch, "/api/agents", "1700000000", []byte(`{"a":1}`)))
+	assert.NotEqual(t, base, signRequest(key, http.MethodPost, "/api/agents", "1700000001", []byte(`{"a":1}`)))
+	assert.NotEqual(t, base, signRequest([]byte("other"), http.MethodPost, "/api/agents", "1700000000", []byte(`{"a":1}`)))
+}
+
+func TestNewRequestWithContext_Signed(t *testing.T) {
+	httpCfg := &HTTPConfig{HeaderAuthorizationName: "Authorization", SigningKey: []byte("secret")}
+
+	req, err := NewRequestWithContext(context.Background(), httpCfg, http.MethodPost, "http://localhost/api/agents?x=1", strings.NewReader(`{"a":1}`))
+	require.NoError(t, err)
+
+	timestamp := req.Header.Get(TimestampHeader)
+	require.NotEmpty(t, timestamp)
+	assert.Equal(t, signRequest(httpCfg.SigningKey, http.MethodPost, "/api/agents?x=1", timestamp, []byte(`{"a":1}`)), req.Header.Get(SignatureHeader))
+	assert.Empty(t, req.Header.Values("Authorization"))
+
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(body))
+	require.NotNil(t, req.GetBody)
+}
+
+func TestNewRequestWithContext_SignedWithToken(t *testing.T) {
+	httpCfg := &HTTPConfig{HeaderAuthorizationName: "Authorization", AuthScheme: "Bearer", TokenJWT: "token", SigningKey: []byte("secret")}
+
+	req, err := NewRequestWithContext(context.Background(), httpCfg, http.MethodGet, "http://localhost/api/version", nil)
+	require.NoError(t, err)
+
+	timestamp := req.Header.Get(TimestampHeader)
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	assert.Equal(t, signRequest(httpCfg.SigningKey, http.MethodGet, "/api/version", timestamp, nil), req.Header.Get(SignatureHeader))
+}
+
+func TestNewRequestWithContext_Unsigned(t *testing.T) {
+	httpCfg := &HTTPConfig{HeaderAuthorizationName: "Authorization", TokenJWT: "token"}
+
+	req, err := NewRequestWithContext(context.Background(), httpCfg, http.MethodGet, "http://localhost/api/version", nil)
+	require.NoError(t, err)
+
+	assert.Empty(t, req.Header.Get(SignatureHeader))
+	assert.Empty(t, req.Header.Get(TimestampHeader))
+}
+
+func TestClient_newRequest_SignedWithClock(t *testing.T) {
+	c, _, fakeClock := newTestClient()
+	c.config().Http.SigningKey = []byte("secret")
+	fakeClock.Advance(time.Hour)
+
+	req, err := c.newRequest(context.Background(), EndpointVersion, http.MethodGet, c.config().GetUrlApiVersion(), nil)
+	require.NoError(t, err)
+
+	timestamp := strconv.FormatInt(fakeClock.Now().Unix(), 10)
+	assert.Equal(t, timestamp, req.Header.Get(TimestampHeader))
+	assert.Equal(t, signRequest(c.config().Http.SigningKey, http.MethodGet, req.URL.RequestURI(), timestamp, nil), req.Header.Get(SignatureHeader))
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// stateCache is the content of Config.StateCacheFile: the rules as fetched, before
+// transforms and de-duplication, which are applied again when it is loaded.
+type stateCache struct {
+	Version    int              `json:"version"`
+	VersionKey string           `json:"version_key"`
+	Redirects  []types.Redirect `json:"redirects"`
+	Pages      []types.Page     `json:"pages"`
+}
+
+// saveStateCache writes cached to Config.StateCacheFile through a temporary file,
+// so that a crash never leaves a partial cache behind.
+func (c *client) saveStateCache(cached stateCache) error {
+	path := c.config().StateCacheFile
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := writeStateCache(file, cached, c.config().StateCacheCompress); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func writeStateCache(w io.Writer, cached stateCache, compress bool) error {
+	if !compress {
+		return json.NewEncoder(w).Encode(cached)
+	}
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(cached); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readStateCache decodes a state cache, gzip-compressed or not.
+func readStateCache(r io.Reader) (stateCache, error) {
+	var cached stateCache
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return cached, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+	err := json.NewDecoder(r).Decode(&cached)
+	return cached, err
+}
+
+// loadStateCache installs the state of Config.StateCacheFile.
+func (c *client) loadStateCache() error {
+	file, err := os.Open(c.config().StateCacheFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.LoadFromReader(file)
+}
+
+// LoadFromReader installs a state decoded from r, in the format of
+// Config.StateCacheFile, gzip-compressed or not, e.g. a snapshot embedded in the
+// binary. A snapshot without version_key is keyed by its version.
+func (c *client) LoadFromReader(r io.Reader) error {
+	cached, err := readStateCache(r)
+	if err != nil {
+		return err
+	}
+	if cached.VersionKey == "" {
+		cached.VersionKey = strconv.Itoa(cached.Version)
+	}
+
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	return c.installData(ParsedVersion{Key: cached.VersionKey, Number: cached.Version}, cached.Redirects, cached.Pages)
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStateCache() stateCache {
+	return stateCache{
+		Version:    7,
+		VersionKey: "7",
+		Redirects:  []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusFound}},
+		Pages:      []types.Page{{Type: types.PageTypeBasic, Path: "/robots.txt", Content: "User-agent: *", ContentType: types.PageContentTypeTextPlain}},
+	}
+}
+
+func TestStateCache_RoundTrip(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		assert.NoError(t, writeStateCache(&buf, testStateCache(), compress))
+		assert.Equal(t, compress, bytes.HasPrefix(buf.Bytes(), []byte{0x1f, 0x8b}))
+
+		cached, err := readStateCache(&buf)
+
+		assert.NoError(t, err)
+		assert.Equal(t, testStateCache(), cached)
+	}
+}
+
+func TestStateCache_Invalid(t *testing.T) {
+	_, err := readStateCache(bytes.NewBufferString("not json"))
+	assert.Error(t, err)
+
+	_, err = readStateCache(bytes.NewBuffer([]byte{0x1f, 0x8b, 0x00}))
+	assert.Error(t, err)
+}
+
+func newStateCacheClient(t *testing.T, compress bool) (*client, *mockHTTPClient, string) {
+	c, mockHTTP, _ := newTestClient()
+	path := filepath.Join(t.TempDir(), "state.json")
+	c.config().StateCacheFile = path
+	c.config().StateCacheCompress = compress
+	return c, mockHTTP, path
+}
+
+func TestClient_StateCache_WrittenOnLoad(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		c, mockHTTP, path := newStateCacheClient(t, compress)
+		cached := testStateCache()
+		mockHTTP.expect(makeVersionResponse("7"), nil)
+		mockHTTP.expect(makeVersionResponse("7"), nil)
+		mockHTTP.expect(makeRedirectsResponse(cached.Redirects, 1), nil)
+		mockHTTP.expect(makePagesResponse(cached.Pages, 1), nil)
+		mockHTTP.expect(makeAgentResponse(), nil)
+
+		assert.NoError(t, c.Reload())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, compress, bytes.HasPrefix(content, []byte{0x1f, 0x8b}))
+		read, err := readStateCache(bytes.NewReader(content))
+		assert.NoError(t, err)
+		assert.Equal(t, cached, read)
+	}
+}
+
+func TestClient_StateCache_KeepsFetchedRules(t *testing.T) {
+	c, mockHTTP, path := newStateCacheClient(t, false)
+	c.config().RedirectTransform = func(r *types.Redirect) (*types.Redirect, bool) {
+		r.Target = "/transformed" + r.Target
+		return r, true
+	}
+	cached := testStateCache()
+	mockHTTP.expect(makeVersionResponse("7"), nil)
+	mockHTTP.expect(makeVersionResponse("7"), nil)
+	mockHTTP.expect(makeRedirectsResponse(cached.Redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(cached.Pages, 1), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.Reload())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	read, err := readStateCache(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "/new", read.Redirects[0].Target)
+}
+
+func TestClient_Init_StateCacheFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		writeCompress bool
+		readCompress  bool
+	}{
+		{name: "plain", writeCompress: false, readCompress: false},
+		{name: "compressed", writeCompress: true, readCompress: true},
+		{name: "plain file read with compression on", writeCompress: false, readCompress: true},
+		{name: "compressed file read with compression off", writeCompress: true, readCompress: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mockHTTP, path := newStateCacheClient(t, tt.readCompress)
+			var buf bytes.Buffer
+			assert.NoError(t, writeStateCache(&buf, testStateCache(), tt.writeCompress))
+			assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+			mockHTTP.expect(nil, errors.New("connection refused"))
+
+			err := c.Init()
+
+			assert.NoError(t, err)
+			assert.Equal(t, 7, c.GetStateVersion())
+			_, target := c.RedirectMatch("example.com", "/old")
+			assert.Equal(t, "/new", target)
+			assert.NotNil(t, c.PageMatch("example.com", "/robots.txt"))
+		})
+	}
+}
+
+func TestClient_Init_StateCacheMissing(t *testing.T) {
+	c, mockHTTP, _ := newStateCacheClient(t, false)
+	mockHTTP.expect(nil, errors.New("connection refused"))
+
+	err := c.Init()
+
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, 0, c.GetStateVersion())
+}
+
+func TestClient_LoadFromReader(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		c, mockHTTP, _ := newTestClient()
+		var buf bytes.Buffer
+		assert.NoError(t, writeStateCache(&buf, testStateCache(), compress))
+
+		err := c.LoadFromReader(&buf)
+
+		assert.NoError(t, err)
+		assert.Empty(t, mockHTTP.calls)
+		assert.Equal(t, 7, c.GetStateVersion())
+		_, target := c.RedirectMatch("example.com", "/old")
+		assert.Equal(t, "/new", target)
+		assert.NotNil(t, c.PageMatch("example.com", "/robots.txt"))
+	}
+}
+
+func TestClient_LoadFromReader_WithoutVersionKey(t *testing.T) {
+	c, _, _ := newTestClient()
+
+	err := c.LoadFromReader(strings.NewReader(`{"version":3,"redirects":[{"type":"BASIC","source":"/a","target":"/b","status":"FOUND"}]}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "3", c.load().VersionKey)
+	_, target := c.RedirectMatch("example.com", "/a")
+	assert.Equal(t, "/b", target)
+}
+
+func TestClient_LoadFromReader_Invalid(t *testing.T) {
+	c, _, _ := newTestClient()
+	assert.NoError(t, c.LoadFromData(1, nil, nil))
+	previous := c.load()
+
+	err := c.LoadFromReader(strings.NewReader("not json"))
+
+	assert.Error(t, err)
+	assert.Same(t, previous, c.load())
+}
@@ -0,0 +1,48 @@
+package client
+
+import "sync/atomic"
+
+// ReloadStats is a snapshot of the reload counters since the client was created.
+type ReloadStats struct {
+	Attempted      uint64
+	Succeeded      uint64
+	Failed         uint64
+	VersionChanges uint64
+	HitsSent       uint64
+	// SkippedReloads counts the reloads skipped because another one was running,
+	// e.g. when a reload outlasts IntervalCheck.
+	SkippedReloads uint64
+}
+
+type reloadCounters struct {
+	attempted      atomic.Uint64
+	succeeded      atomic.Uint64
+	failed         atomic.Uint64
+	versionChanges atomic.Uint64
+	hitsSent       atomic.Uint64
+	skippedReloads atomic.Uint64
+}
+
+func (r *reloadCounters) snapshot() ReloadStats {
+	return ReloadStats{
+		Attempted:      r.attempted.Load(),
+		Succeeded:      r.succeeded.Load(),
+		Failed:         r.failed.Load(),
+		VersionChanges: r.versionChanges.Load(),
+		HitsSent:       r.hitsSent.Load(),
+		SkippedReloads: r.skippedReloads.Load(),
+	}
+}
+
+func (c *client) Stats() ReloadStats {
+	return c.stats.snapshot()
+}
+
+// skipReload counts a reload skipped by an in-flight one.
+func (c *client) skipReload() ReloadResult {
+	c.stats.skippedReloads.Add(1)
+	if metrics, ok := c.metrics().(SkipMetrics); ok {
+		metrics.ObserveSkippedReload()
+	}
+	return ReloadResult{Skipped: true}
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Stats(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.State.Store(&State{ProjectVersion: 1, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	assert.Equal(t, ReloadStats{}, c.Stats())
+
+	// unchanged version: hit sent
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, ReloadStats{Attempted: 1, Succeeded: 1, HitsSent: 1}, c.Stats())
+
+	// version change loaded
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+	mockHTTP.expect(makeRedirectsResponse([]types.Redirect{}, 0), nil)
+	mockHTTP.expect(makePagesResponse([]types.Page{}, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, ReloadStats{Attempted: 2, Succeeded: 2, VersionChanges: 1, HitsSent: 1}, c.Stats())
+
+	// version change that fails to load
+	mockHTTP.expect(makeVersionResponse("3"), nil)
+	mockHTTP.expect(nil, errors.New("version error"))
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.Error(t, c.Reload())
+	assert.Equal(t, ReloadStats{Attempted: 3, Succeeded: 2, Failed: 1, VersionChanges: 2, HitsSent: 1}, c.Stats())
+
+	// version check failure
+	mockHTTP.expect(nil, errors.New("network error"))
+	assert.Error(t, c.Reload())
+	assert.Equal(t, ReloadStats{Attempted: 4, Succeeded: 2, Failed: 2, VersionChanges: 2, HitsSent: 1}, c.Stats())
+}
+
+func TestClient_Stats_Concurrent(t *testing.T) {
+	c, _, _ := newTestClient()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.stats.hitsSent.Add(1)
+			_ = c.Stats()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(10), c.Stats().HitsSent)
+}
+
+type skipMetrics struct {
+	recordingMetrics
+	skipped int
+}
+
+func (m *skipMetrics) ObserveSkippedReload() {
+	m.skipped++
+}
+
+func TestClient_Stats_SkippedReloads(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	metrics := &skipMetrics{}
+	c.config().Metrics = metrics
+	c.reloadMu.Lock()
+
+	assert.NoError(t, c.Reload())
+	result := <-c.ReloadAsync()
+
+	c.reloadMu.Unlock()
+	assert.True(t, result.Skipped)
+	assert.Equal(t, ReloadStats{SkippedReloads: 2}, c.Stats())
+	assert.Equal(t, 2, metrics.skipped)
+	assert.Empty(t, mockHTTP.calls)
+}
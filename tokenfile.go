@@ -0,0 +1,98 @@
+package client
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenFileRefresh is how long a token read from HTTPConfig.TokenFile is
+// used by a client before the file modification time is checked again.
+const DefaultTokenFileRefresh = time.Second
+
+// tokenFile caches the token a client read from HTTPConfig.TokenFile.
+type tokenFile struct {
+	mu        sync.Mutex
+	path      string
+	token     string
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// read returns the token of the file at path, reloading it when its mtime
+// changed. On error the last good token is kept.
+func (f *tokenFile) read(path string, refresh time.Duration, now time.Time, logger *slog.Logger) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path != path {
+		f.path, f.token, f.modTime, f.checkedAt = path, "", time.Time{}, time.Time{}
+	}
+	if !f.checkedAt.IsZero() && now.Sub(f.checkedAt) < refresh {
+		return f.token
+	}
+	f.checkedAt = now
+
+	info, err := os.Stat(path)
+	if err != nil {
+		logger.Warn("failed to stat token file, keeping previous token", "path", path, "error", err)
+		return f.token
+	}
+	if f.token != "" && info.ModTime().Equal(f.modTime) {
+		return f.token
+	}
+	token, err := readTokenFile(path)
+	if err != nil {
+		logger.Warn("failed to read token file, keeping previous token", "path", path, "error", err)
+		return f.token
+	}
+	if token == "" {
+		logger.Warn("token file is empty, keeping previous token", "path", path)
+		return f.token
+	}
+	f.token = token
+	f.modTime = info.ModTime()
+	return f.token
+}
+
+// invalidate makes the next read reload the file, even if its mtime did not
+// change.
+func (f *tokenFile) invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.modTime, f.checkedAt = time.Time{}, time.Time{}
+}
+
+func readTokenFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// token returns the token of requests built without a client: TokenFile is read
+// on every call.
+func (c *HTTPConfig) token() string {
+	if c.TokenFile == "" {
+		return c.TokenJWT
+	}
+	token, _ := readTokenFile(c.TokenFile)
+	return token
+}
+
+// token returns the token of the client requests, re-reading TokenFile at most
+// every TokenFileRefresh.
+func (c *client) token() string {
+	httpCfg := c.config().Http
+	if httpCfg.TokenFile == "" {
+		return httpCfg.TokenJWT
+	}
+	refresh := httpCfg.TokenFileRefresh
+	if refresh <= 0 {
+		refresh = DefaultTokenFileRefresh
+	}
+	return c.tokenFile.read(httpCfg.TokenFile, refresh, c.clock.Now(), c.logger())
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTokenFile(t *testing.T, path, token string, modTime time.Time) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(path, []byte(token+"\n"), 0o600))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestHTTPConfig_TokenFile_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	now := time.Now()
+	writeTokenFile(t, path, "first", now.Add(-time.Minute))
+	httpCfg := &HTTPConfig{
+		HeaderAuthorizationName: "Authorization",
+		AuthScheme:              "Bearer",
+		TokenJWT:                "static",
+		TokenFile:               path,
+		TokenFileRefresh:        time.Nanosecond,
+	}
+
+	req, err := NewRequest(httpCfg, "GET", "http://localhost/api", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer first", req.Header.Get("Authorization"))
+
+	writeTokenFile(t, path, "second", now)
+	req, _ = NewRequest(httpCfg, "GET", "http://localhost/api", nil)
+	assert.Equal(t, "Bearer second", req.Header.Get("Authorization"))
+}
+
+func TestHTTPConfig_TokenFile_Missing(t *testing.T) {
+	httpCfg := &HTTPConfig{TokenJWT: "static", TokenFile: filepath.Join(t.TempDir(), "missing")}
+
+	assert.Equal(t, "", httpCfg.token())
+}
+
+func newTokenFileClient(t *testing.T, refresh time.Duration) (*client, clockwork.FakeClock, string) {
+	c, _, fakeClock := newTestClient()
+	path := filepath.Join(t.TempDir(), "token")
+	c.config().Http.TokenFile = path
+	c.config().Http.TokenFileRefresh = refresh
+	return c, fakeClock, path
+}
+
+func TestClient_TokenFile_Refresh(t *testing.T) {
+	c, fakeClock, path := newTokenFileClient(t, 10*time.Second)
+	now := time.Now()
+	writeTokenFile(t, path, "first", now.Add(-time.Minute))
+
+	assert.Equal(t, "first", c.token())
+
+	writeTokenFile(t, path, "second", now)
+	fakeClock.Advance(9 * time.Second)
+	assert.Equal(t, "first", c.token())
+
+	fakeClock.Advance(time.Second)
+	assert.Equal(t, "second", c.token())
+
+	req, err := c.newRequest(context.Background(), EndpointVersion, http.MethodGet, c.config().GetUrlApiVersion(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer second", req.Header.Get("Authorization"))
+}
+
+func TestClient_TokenFile_DefaultRefresh(t *testing.T) {
+	c, fakeClock, path := newTokenFileClient(t, 0)
+	now := time.Now()
+	writeTokenFile(t, path, "first", now.Add(-time.Minute))
+	assert.Equal(t, "first", c.token())
+
+	writeTokenFile(t, path, "second", now)
+	assert.Equal(t, "first", c.token())
+	fakeClock.Advance(DefaultTokenFileRefresh)
+	assert.Equal(t, "second", c.token())
+}
+
+func TestClient_TokenFile_KeepsLastGoodToken(t *testing.T) {
+	c, fakeClock, path := newTokenFileClient(t, time.Second)
+	var logs bytes.Buffer
+	c.config().Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	writeTokenFile(t, path, "good", time.Now().Add(-time.Minute))
+
+	assert.Equal(t, "good", c.token())
+
+	assert.NoError(t, os.Remove(path))
+	fakeClock.Advance(time.Second)
+	assert.Equal(t, "good", c.token())
+	assert.Contains(t, logs.String(), "failed to stat token file")
+
+	writeTokenFile(t, path, "", time.Now())
+	fakeClock.Advance(time.Second)
+	assert.Equal(t, "good", c.token())
+	assert.Contains(t, logs.String(), "token file is empty")
+}
+
+func TestClient_TokenFile_PerClient(t *testing.T) {
+	first, _, path := newTokenFileClient(t, time.Hour)
+	writeTokenFile(t, path, "first", time.Now().Add(-time.Minute))
+	assert.Equal(t, "first", first.token())
+
+	writeTokenFile(t, path, "second", time.Now())
+	second, _, _ := newTokenFileClient(t, time.Hour)
+	second.config().Http.TokenFile = path
+
+	assert.Equal(t, "first", first.token())
+	assert.Equal(t, "second", second.token())
+}
+
+func TestClient_TokenFile_PathChanged(t *testing.T) {
+	c, _, path := newTokenFileClient(t, time.Hour)
+	writeTokenFile(t, path, "first", time.Now())
+	assert.Equal(t, "first", c.token())
+
+	other := filepath.Join(t.TempDir(), "other")
+	writeTokenFile(t, other, "other", time.Now())
+	c.config().Http.TokenFile = other
+
+	assert.Equal(t, "other", c.token())
+}
+
+func TestClient_TokenFile_RetryOnUnauthorized(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	path := filepath.Join(t.TempDir(), "token")
+	c.config().Http.TokenFile = path
+	c.config().Http.TokenFileRefresh = time.Hour
+	writeTokenFile(t, path, "expired", time.Now().Add(-time.Minute))
+	assert.Equal(t, "expired", c.token())
+	writeTokenFile(t, path, "fresh", time.Now())
+
+	mockHTTP.expect(makeErrorResponse(http.StatusUnauthorized), nil)
+	mockHTTP.expect(makeVersionResponse("2"), nil)
+
+	version, err := c.fetchVersion(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version.Number)
+	assert.Len(t, mockHTTP.calls, 2)
+	assert.Equal(t, "Bearer expired", mockHTTP.calls[0].Header.Get("Authorization"))
+	assert.Equal(t, "Bearer fresh", mockHTTP.calls[1].Header.Get("Authorization"))
+}
+
+func TestClient_TokenFile_RetryOnUnauthorizedOnce(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	path := filepath.Join(t.TempDir(), "token")
+	c.config().Http.TokenFile = path
+	c.config().Http.TokenFileRefresh = time.Hour
+	writeTokenFile(t, path, "expired", time.Now().Add(-time.Minute))
+	assert.Equal(t, "expired", c.token())
+	writeTokenFile(t, path, "revoked", time.Now())
+
+	mockHTTP.expect(makeErrorResponse(http.StatusUnauthorized), nil)
+	mockHTTP.expect(makeErrorResponse(http.StatusUnauthorized), nil)
+
+	_, err := c.fetchVersion(context.Background())
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Len(t, mockHTTP.calls, 2)
+	assert.Equal(t, "Bearer revoked", mockHTTP.calls[1].Header.Get("Authorization"))
+}
+
+func TestClient_TokenFile_NoRetryWhenTokenUnchanged(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	path := filepath.Join(t.TempDir(), "token")
+	c.config().Http.TokenFile = path
+	writeTokenFile(t, path, "same", time.Now())
+
+	mockHTTP.expect(makeErrorResponse(http.StatusUnauthorized), nil)
+
+	_, err := c.fetchVersion(context.Background())
+
+	assert.Error(t, err)
+	assert.Len(t, mockHTTP.calls, 1)
+}
+
+func TestClient_TokenFile_RetryReplaysBody(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	path := filepath.Join(t.TempDir(), "token")
+	c.config().Http.TokenFile = path
+	c.config().Http.TokenFileRefresh = time.Hour
+	writeTokenFile(t, path, "expired", time.Now().Add(-time.Minute))
+	assert.Equal(t, "expired", c.token())
+	writeTokenFile(t, path, "fresh", time.Now())
+
+	mockHTTP.expect(makeErrorResponse(http.StatusUnauthorized), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.sendAgentStatus(context.Background(), types.Agent{Name: "test-node", Type: types.AgentTypeDefault, Version: 1, Status: types.AgentStatusSuccess}))
+
+	posts := agentPosts(t, mockHTTP, c.config().GetUrlApiAgents())
+	assert.Len(t, posts, 2)
+	assert.Equal(t, posts[0], posts[1])
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// RequestTrace breaks a manager request down, see HTTPConfig.EnableTrace. Phases
+// that did not happen, e.g. DNS and connect on a reused connection, are zero.
+type RequestTrace struct {
+	DNS             time.Duration
+	Connect         time.Duration
+	TLSHandshake    time.Duration
+	TimeToFirstByte time.Duration
+	ConnReused      bool
+}
+
+// TraceMetrics is implemented by Metrics wanting the request traces. Without
+// it, traces are logged at debug level.
+type TraceMetrics interface {
+	ObserveTrace(endpoint Endpoint, trace RequestTrace)
+}
+
+// requestTracer collects the httptrace events of one request. They may fire on
+// transport goroutines.
+type requestTracer struct {
+	clock clockwork.Clock
+	start time.Time
+
+	mu                               sync.Mutex
+	dnsStart, connectStart, tlsStart time.Time
+	trace                            RequestTrace
+}
+
+func newRequestTracer(clock clockwork.Clock) *requestTracer {
+	return &requestTracer{clock: clock, start: clock.Now()}
+}
+
+func (t *requestTracer) record(f func(now time.Time)) {
+	now := t.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f(now)
+}
+
+func (t *requestTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.record(func(now time.Time) { t.dnsStart = now }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(func(now time.Time) { t.trace.DNS = now.Sub(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) { t.record(func(now time.Time) { t.connectStart = now }) },
+		ConnectDone: func(string, string, error) {
+			t.record(func(now time.Time) { t.trace.Connect = now.Sub(t.connectStart) })
+		},
+		TLSHandshakeStart: func() { t.record(func(now time.Time) { t.tlsStart = now }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(func(now time.Time) { t.trace.TLSHandshake = now.Sub(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(func(time.Time) { t.trace.ConnReused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			t.record(func(now time.Time) { t.trace.TimeToFirstByte = now.Sub(t.start) })
+		},
+	}
+}
+
+func (t *requestTracer) result() RequestTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.trace
+}
+
+// traceRequest attaches a tracer to req when HTTPConfig.EnableTrace is set.
+func (c *client) traceRequest(req *http.Request) (*http.Request, *requestTracer) {
+	if !c.config().Http.EnableTrace {
+		return req, nil
+	}
+	tracer := newRequestTracer(c.clock)
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), tracer.clientTrace())), tracer
+}
+
+func (c *client) observeTrace(ctx context.Context, endpoint Endpoint, tracer *requestTracer) {
+	if tracer == nil {
+		return
+	}
+	trace := tracer.result()
+	if metrics, ok := c.metrics().(TraceMetrics); ok {
+		metrics.ObserveTrace(endpoint, trace)
+		return
+	}
+	c.logger().DebugContext(ctx, "manager request trace", "endpoint", endpoint, "dns", trace.DNS, "connect", trace.Connect,
+		"tls", trace.TLSHandshake, "ttfb", trace.TimeToFirstByte, "reused", trace.ConnReused)
+}
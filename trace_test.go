@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type tracingMetrics struct {
+	recordingMetrics
+	mu     sync.Mutex
+	traces []RequestTrace
+}
+
+func (m *tracingMetrics) ObserveTrace(_ Endpoint, trace RequestTrace) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.traces = append(m.traces, trace)
+}
+
+func newTraceTestClient(t *testing.T, metrics Metrics) *client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("1"))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := NewDefaultConfig()
+	cfg.ManagerUrl = server.URL
+	cfg.NamespaceCode = "ns"
+	cfg.ProjectCode = "proj"
+	cfg.AgentType = types.AgentTypeDefault
+	cfg.Http = NewHTTPConfig(DefaultHTTPTuning())
+	cfg.Http.EnableTrace = true
+	cfg.Metrics = metrics
+	cfg.Logger = nil
+	return New(cfg).(*client)
+}
+
+func TestClient_EnableTrace(t *testing.T) {
+	metrics := &tracingMetrics{}
+	c := newTraceTestClient(t, metrics)
+
+	_, err := c.getProjectVersion(context.Background())
+	assert.NoError(t, err)
+	_, err = c.getProjectVersion(context.Background())
+	assert.NoError(t, err)
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if assert.Len(t, metrics.traces, 2) {
+		first, second := metrics.traces[0], metrics.traces[1]
+		assert.False(t, first.ConnReused)
+		assert.Positive(t, first.Connect)
+		assert.Positive(t, first.TimeToFirstByte)
+		assert.Zero(t, first.TLSHandshake)
+
+		assert.True(t, second.ConnReused)
+		assert.Zero(t, second.Connect)
+		assert.Positive(t, second.TimeToFirstByte)
+	}
+	assert.Equal(t, 2, metrics.countByEndpoint()[EndpointVersion])
+}
+
+func TestClient_EnableTrace_WithoutTraceMetrics(t *testing.T) {
+	c := newTraceTestClient(t, nil)
+
+	_, err := c.getProjectVersion(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestClient_TraceDisabled(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	metrics := &tracingMetrics{}
+	c.config().Metrics = metrics
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	_, err := c.getProjectVersion(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, metrics.traces)
+	assert.Nil(t, httptrace.ContextClientTrace(mockHTTP.calls[0].Context()))
+}
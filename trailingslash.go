@@ -0,0 +1,105 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+// TrailingSlashMode tells how the match methods treat a trailing slash, see
+// Config.TrailingSlashMode.
+type TrailingSlashMode string
+
+const (
+	// TrailingSlashStrict matches "/about" and "/about/" as distinct paths.
+	TrailingSlashStrict TrailingSlashMode = "strict"
+	// TrailingSlashIgnore retries a path that matches nothing with its trailing
+	// slash added or removed.
+	TrailingSlashIgnore TrailingSlashMode = "ignore"
+	// TrailingSlashRedirect makes RedirectMatch answer a 301 to the other form of a
+	// path that matches nothing, when a redirect or a page exists at that form.
+	TrailingSlashRedirect TrailingSlashMode = "redirect"
+)
+
+// toggleTrailingSlash returns uri with its trailing slash removed or added. The
+// root path has no other form.
+func toggleTrailingSlash(uri string) (string, bool) {
+	if uri == "" || uri == "/" {
+		return "", false
+	}
+	if strings.HasSuffix(uri, "/") {
+		return strings.TrimSuffix(uri, "/"), true
+	}
+	return uri + "/", true
+}
+
+func (c *client) decodePath(uri string) string {
+	if c.config().DecodePathForMatch {
+		return decodeMatchPath(uri)
+	}
+	return uri
+}
+
+func (c *client) foldPath(uri string) string {
+	uri = c.decodePath(uri)
+	if c.config().CaseInsensitivePaths {
+		return strings.ToLower(uri)
+	}
+	return uri
+}
+
+// matchRedirect matches uri against the redirects of state. Under
+// Config.CaseInsensitivePaths the exact sources are keyed in lowercase, but a
+// regex rule hit with the lowercased URI is matched again with the URI as sent,
+// so that its captures keep their case.
+func (c *client) matchRedirect(state *State, host, uri string) (*types.Redirect, string) {
+	folded := c.foldPath(uri)
+	redirect, target := state.RedirectMatcher.Match(host, folded)
+	if redirect == nil || !c.config().CaseInsensitivePaths ||
+		(redirect.Type != types.RedirectTypeRegex && redirect.Type != types.RedirectTypeRegexHost) {
+		return redirect, target
+	}
+	if original := c.decodePath(uri); original != folded {
+		if same, originalTarget := state.RedirectMatcher.Match(host, original); same == redirect {
+			return redirect, originalTarget
+		}
+	}
+	return redirect, target
+}
+
+// matchRedirectSlash is called when no redirect matches uri.
+func (c *client) matchRedirectSlash(state *State, host, uri string) (*types.Redirect, string) {
+	mode := c.config().TrailingSlashMode
+	if mode != TrailingSlashIgnore && mode != TrailingSlashRedirect {
+		return nil, ""
+	}
+	alternate, ok := toggleTrailingSlash(uri)
+	if !ok {
+		return nil, ""
+	}
+	switch mode {
+	case TrailingSlashIgnore:
+		return c.matchRedirect(state, host, alternate)
+	case TrailingSlashRedirect:
+		if state.PageMatcher == nil || state.PageMatcher.Match(host, c.foldPath(uri)) != nil {
+			return nil, ""
+		}
+		if redirect, _ := state.RedirectMatcher.Match(host, c.foldPath(alternate)); redirect == nil && state.PageMatcher.Match(host, c.foldPath(alternate)) == nil {
+			return nil, ""
+		}
+		return &types.Redirect{Type: types.RedirectTypeBasic, Source: uri, Target: alternate, Status: types.RedirectStatusMovedPermanent}, alternate
+	}
+	return nil, ""
+}
+
+// matchPageSlash is called when no page matches uri.
+func (c *client) matchPageSlash(state *State, host, uri string) *types.Page {
+	if c.config().TrailingSlashMode != TrailingSlashIgnore {
+		return nil
+	}
+	alternate, ok := toggleTrailingSlash(uri)
+	if !ok {
+		return nil
+	}
+	return state.PageMatcher.Match(host, c.foldPath(alternate))
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrailingSlashClient(t *testing.T, mode TrailingSlashMode) *client {
+	c, _, _ := newTestClient()
+	c.config().TrailingSlashMode = mode
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/about", Target: "/company", Status: types.RedirectStatusFound},
+		{Type: types.RedirectTypeBasic, Source: "/docs/", Target: "/manual/", Status: types.RedirectStatusFound},
+	}
+	pages := []types.Page{
+		{Type: types.PageTypeBasic, Path: "/legal/", Content: "legal", ContentType: types.PageContentTypeTextPlain},
+	}
+	assert.NoError(t, c.LoadFromData(1, redirects, pages))
+	return c
+}
+
+func TestToggleTrailingSlash(t *testing.T) {
+	tests := []struct {
+		uri    string
+		want   string
+		wantOk bool
+	}{
+		{uri: "/about", want: "/about/", wantOk: true},
+		{uri: "/about/", want: "/about", wantOk: true},
+		{uri: "/", wantOk: false},
+		{uri: "", wantOk: false},
+	}
+	for _, tt := range tests {
+		got, ok := toggleTrailingSlash(tt.uri)
+		assert.Equal(t, tt.wantOk, ok, tt.uri)
+		assert.Equal(t, tt.want, got, tt.uri)
+	}
+}
+
+func TestClient_TrailingSlashStrict(t *testing.T) {
+	for _, mode := range []TrailingSlashMode{"", TrailingSlashStrict} {
+		c := newTrailingSlashClient(t, mode)
+
+		redirect, target := c.RedirectMatch("example.com", "/about")
+		assert.NotNil(t, redirect)
+		assert.Equal(t, "/company", target)
+		redirect, _ = c.RedirectMatch("example.com", "/about/")
+		assert.Nil(t, redirect)
+		redirect, _ = c.RedirectMatch("example.com", "/docs")
+		assert.Nil(t, redirect)
+		assert.Nil(t, c.PageMatch("example.com", "/legal"))
+	}
+}
+
+func TestClient_TrailingSlashIgnore(t *testing.T) {
+	c := newTrailingSlashClient(t, TrailingSlashIgnore)
+
+	redirect, target := c.RedirectMatch("example.com", "/about/")
+	assert.NotNil(t, redirect)
+	assert.Equal(t, "/company", target)
+	redirect, target = c.RedirectMatch("example.com", "/docs")
+	assert.NotNil(t, redirect)
+	assert.Equal(t, "/manual/", target)
+	page := c.PageMatch("example.com", "/legal")
+	assert.NotNil(t, page)
+	assert.Equal(t, "legal", page.Content)
+	assert.NotNil(t, c.PageMatch("example.com", "/legal/"))
+	redirect, _ = c.RedirectMatch("example.com", "/missing/")
+	assert.Nil(t, redirect)
+}
+
+func TestClient_TrailingSlashRedirect(t *testing.T) {
+	c := newTrailingSlashClient(t, TrailingSlashRedirect)
+
+	target, status, ok := c.RedirectMatchStatus("example.com", "/about/")
+	assert.True(t, ok)
+	assert.Equal(t, "/about", target)
+	assert.Equal(t, http.StatusMovedPermanently, status)
+
+	target, status, ok = c.RedirectMatchStatus("example.com", "/about")
+	assert.True(t, ok)
+	assert.Equal(t, "/company", target)
+	assert.Equal(t, http.StatusFound, status)
+
+	target, _, ok = c.RedirectMatchStatus("example.com", "/legal")
+	assert.True(t, ok)
+	assert.Equal(t, "/legal/", target)
+	assert.Nil(t, c.PageMatch("example.com", "/legal"))
+
+	_, _, ok = c.RedirectMatchStatus("example.com", "/legal/")
+	assert.False(t, ok)
+	_, _, ok = c.RedirectMatchStatus("example.com", "/missing")
+	assert.False(t, ok)
+	_, _, ok = c.RedirectMatchStatus("example.com", "/")
+	assert.False(t, ok)
+}
+
+func TestClient_TrailingSlashRedirect_CaseInsensitive(t *testing.T) {
+	c := newTrailingSlashClient(t, TrailingSlashRedirect)
+	c.config().CaseInsensitivePaths = true
+	assert.NoError(t, c.LoadFromData(2, c.ListRedirects(), c.ListPages()))
+
+	target, _, ok := c.RedirectMatchStatus("example.com", "/Legal")
+	assert.True(t, ok)
+	assert.Equal(t, "/Legal/", target)
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/flectolab/flecto-manager/common/types"
+)
+
+var (
+	ErrDuplicateSource = errors.New("duplicate source")
+	ErrRedirectLoop    = errors.New("target loops back to the source")
+)
+
+// RuleError reports why a single redirect rule was rejected.
+type RuleError struct {
+	Index    int
+	Redirect types.Redirect
+	Err      error
+}
+
+func (e RuleError) Error() string {
+	return fmt.Sprintf("redirect #%d (%s %s): %s", e.Index, e.Redirect.Type, e.Redirect.Source, e.Err)
+}
+
+func (e RuleError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateRedirects inserts every redirect into a fresh matcher and returns the
+// rules that would be rejected or shadowed, without touching any client state.
+// As when loading a state, the last redirect of a (type, source) pair wins.
+func ValidateRedirects(redirects []types.Redirect) []RuleError {
+	var ruleErrors []RuleError
+	matcher := types.NewRedirectTreeMatcher()
+	last := lastRedirects(redirects)
+
+	for i := range redirects {
+		r := redirects[i]
+		if err := matcher.Insert(&r); err != nil {
+			ruleErrors = append(ruleErrors, RuleError{Index: i, Redirect: r, Err: err})
+			continue
+		}
+		if winner := last[redirectKey{r.Type, r.Source}]; winner != i {
+			ruleErrors = append(ruleErrors, duplicateError(i, r, winner))
+		}
+	}
+
+	return ruleErrors
+}
+
+// DetectRedirectLoops returns the redirects whose normalized target is matched by
+// their own source. Rules without a host only loop on a relative target, host
+// rules on a relative target or one on the same host. Regex rules are
+// best-effort: targets using capture groups are not checked, nor are REGEX_HOST
+// rules with a relative target.
+func DetectRedirectLoops(redirects []types.Redirect) []RuleError {
+	var ruleErrors []RuleError
+	for i := range redirects {
+		if isRedirectLoop(&redirects[i]) {
+			ruleErrors = append(ruleErrors, RuleError{Index: i, Redirect: redirects[i], Err: ErrRedirectLoop})
+		}
+	}
+	return ruleErrors
+}
+
+func isRedirectLoop(r *types.Redirect) bool {
+	target, err := url.Parse(r.Target)
+	if err != nil {
+		return false
+	}
+	targetPath := target.Path
+	if target.RawQuery != "" {
+		targetPath += "?" + target.RawQuery
+	}
+	targetPath = normalizeLoopPath(targetPath)
+
+	switch r.Type {
+	case types.RedirectTypeBasic:
+		return target.Host == "" && targetPath == normalizeLoopPath(r.Source)
+	case types.RedirectTypeBasicHost:
+		host, sourcePath, _ := strings.Cut(r.Source, "/")
+		if target.Host != "" && !strings.EqualFold(target.Host, host) {
+			return false
+		}
+		return targetPath == normalizeLoopPath("/"+sourcePath)
+	case types.RedirectTypeRegex, types.RedirectTypeRegexHost:
+		if strings.Contains(r.Target, "$") {
+			return false
+		}
+		input := targetPath
+		if r.Type == types.RedirectTypeRegexHost {
+			if target.Host == "" {
+				return false
+			}
+			input = target.Host + targetPath
+		} else if target.Host != "" {
+			return false
+		}
+		re, errCompile := regexp.Compile(r.Source)
+		return errCompile == nil && re.MatchString(input)
+	default:
+		return false
+	}
+}
+
+func normalizeLoopPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+type redirectKey struct {
+	Type   types.RedirectType
+	Source string
+}
+
+// dedupeRedirects keeps the last redirect of every (type, source) pair, in the
+// order of the kept rules, and reports the dropped ones.
+func dedupeRedirects(redirects []types.Redirect) ([]types.Redirect, []RuleError) {
+	last := lastRedirects(redirects)
+	if len(last) == len(redirects) {
+		return redirects, nil
+	}
+	var dropped []RuleError
+	kept := make([]types.Redirect, 0, len(last))
+	for i, r := range redirects {
+		if winner := last[redirectKey{r.Type, r.Source}]; winner != i {
+			dropped = append(dropped, duplicateError(i, r, winner))
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept, dropped
+}
+
+// lastRedirects returns the index of the last redirect of every (type, source) pair.
+func lastRedirects(redirects []types.Redirect) map[redirectKey]int {
+	last := make(map[redirectKey]int, len(redirects))
+	for i, r := range redirects {
+		last[redirectKey{r.Type, r.Source}] = i
+	}
+	return last
+}
+
+func duplicateError(index int, r types.Redirect, winner int) RuleError {
+	return RuleError{Index: index, Redirect: r, Err: fmt.Errorf("%w: overridden by redirect #%d", ErrDuplicateSource, winner)}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRedirects_Valid(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeBasicHost, Source: "example.com/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+		{Type: types.RedirectTypeRegex, Source: "^/blog/(.*)$", Target: "/news/$1", Status: types.RedirectStatusFound},
+	}
+
+	assert.Empty(t, ValidateRedirects(redirects))
+}
+
+func TestValidateRedirects_InvalidRegex(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"},
+		{Type: types.RedirectTypeRegex, Source: "[invalid(regex", Target: "/target"},
+	}
+
+	ruleErrors := ValidateRedirects(redirects)
+
+	assert.Len(t, ruleErrors, 1)
+	assert.Equal(t, 1, ruleErrors[0].Index)
+	assert.Equal(t, redirects[1], ruleErrors[0].Redirect)
+	assert.Contains(t, ruleErrors[0].Error(), "redirect #1 (REGEX [invalid(regex)")
+}
+
+func TestValidateRedirects_DuplicateSource(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new1"},
+		{Type: types.RedirectTypeBasicHost, Source: "/old", Target: "/new2"},
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new3"},
+	}
+
+	ruleErrors := ValidateRedirects(redirects)
+
+	assert.Len(t, ruleErrors, 1)
+	assert.Equal(t, 0, ruleErrors[0].Index)
+	assert.ErrorIs(t, ruleErrors[0], ErrDuplicateSource)
+	assert.Contains(t, ruleErrors[0].Error(), "overridden by redirect #2")
+
+	_, dropped := dedupeRedirects(redirects)
+	assert.Equal(t, ruleErrors, dropped)
+}
+
+func TestDetectRedirectLoops(t *testing.T) {
+	tests := []struct {
+		name     string
+		redirect types.Redirect
+		want     bool
+	}{
+		{name: "basic self redirect", redirect: types.Redirect{Type: types.RedirectTypeBasic, Source: "/old", Target: "/old"}, want: true},
+		{name: "basic trailing slash", redirect: types.Redirect{Type: types.RedirectTypeBasic, Source: "/old", Target: "/old/"}, want: true},
+		{name: "basic cross host target", redirect: types.Redirect{Type: types.RedirectTypeBasic, Source: "/old", Target: "https://newsite.com/old"}, want: false},
+		{name: "basic safe", redirect: types.Redirect{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}, want: false},
+		{name: "basic query differs", redirect: types.Redirect{Type: types.RedirectTypeBasic, Source: "/old", Target: "/old?v=2"}, want: false},
+		{name: "basic host relative target", redirect: types.Redirect{Type: types.RedirectTypeBasicHost, Source: "example.com/old", Target: "/old"}, want: true},
+		{name: "basic host same host", redirect: types.Redirect{Type: types.RedirectTypeBasicHost, Source: "example.com/old", Target: "https://EXAMPLE.com/old"}, want: true},
+		{name: "basic host other host", redirect: types.Redirect{Type: types.RedirectTypeBasicHost, Source: "example.com/old", Target: "https://other.com/old"}, want: false},
+		{name: "regex matching literal target", redirect: types.Redirect{Type: types.RedirectTypeRegex, Source: "^/blog/.*$", Target: "/blog/index"}, want: true},
+		{name: "regex cross host target", redirect: types.Redirect{Type: types.RedirectTypeRegex, Source: "^/blog/.*$", Target: "https://newsite.com/blog/index"}, want: false},
+		{name: "regex safe", redirect: types.Redirect{Type: types.RedirectTypeRegex, Source: "^/blog/.*$", Target: "/news"}, want: false},
+		{name: "regex with capture not checked", redirect: types.Redirect{Type: types.RedirectTypeRegex, Source: "^/(.*)$", Target: "/$1"}, want: false},
+		{name: "regex invalid", redirect: types.Redirect{Type: types.RedirectTypeRegex, Source: "[invalid(regex", Target: "/x"}, want: false},
+		{name: "regex host absolute target", redirect: types.Redirect{Type: types.RedirectTypeRegexHost, Source: "^example\\.com/.*$", Target: "https://example.com/home"}, want: true},
+		{name: "regex host relative target not checked", redirect: types.Redirect{Type: types.RedirectTypeRegexHost, Source: "^example\\.com/.*$", Target: "/home"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleErrors := DetectRedirectLoops([]types.Redirect{tt.redirect})
+			if !tt.want {
+				assert.Empty(t, ruleErrors)
+				return
+			}
+			assert.Len(t, ruleErrors, 1)
+			assert.ErrorIs(t, ruleErrors[0], ErrRedirectLoop)
+			assert.Equal(t, tt.redirect, ruleErrors[0].Redirect)
+		})
+	}
+}
+
+func TestClient_loadState_DetectRedirectLoops(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().DetectRedirectLoops = true
+
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/loop", Target: "/loop"},
+		{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new", Status: types.RedirectStatusMovedPermanent},
+	}
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 2), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, redirects[1:], c.load().Redirects)
+	redirect, _ := c.RedirectMatch("example.com", "/loop")
+	assert.Nil(t, redirect)
+}
+
+func TestClient_loadState_RedirectLoopsKeptByDefault(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/loop", Target: "/loop"}}
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, c.load().Redirects, 1)
+}
+
+func Test_dedupeRedirects(t *testing.T) {
+	redirects := []types.Redirect{
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"},
+		{Type: types.RedirectTypeBasic, Source: "/b", Target: "/2"},
+		{Type: types.RedirectTypeBasicHost, Source: "/a", Target: "/3"},
+		{Type: types.RedirectTypeBasic, Source: "/a", Target: "/4"},
+	}
+
+	kept, dropped := dedupeRedirects(redirects)
+
+	assert.Equal(t, redirects[1:], kept)
+	assert.Len(t, dropped, 1)
+	assert.Equal(t, 0, dropped[0].Index)
+	assert.ErrorIs(t, dropped[0], ErrDuplicateSource)
+	assert.Contains(t, dropped[0].Error(), "overridden by redirect #3")
+}
+
+func Test_dedupeRedirects_NoDuplicates(t *testing.T) {
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/a", Target: "/1"}}
+
+	kept, dropped := dedupeRedirects(redirects)
+
+	assert.Equal(t, redirects, kept)
+	assert.Empty(t, dropped)
+}
+
+func TestClient_loadState_DuplicatesAcrossPages(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	var logs bytes.Buffer
+	c.config().Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	c.config().WarnDuplicates = true
+
+	page1 := make([]types.Redirect, 100)
+	for i := range page1 {
+		page1[i] = types.Redirect{Type: types.RedirectTypeBasic, Source: fmt.Sprintf("/r%d", i), Target: "/old"}
+	}
+	// the last rule of page 1 shows up again at the start of page 2
+	page2 := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/r99", Target: "/new"}}
+
+	mockHTTP.expect(makeVersionResponse("1"), nil)
+	mockHTTP.expect(makeCursorResponse(types.RedirectList{Items: page1, Total: 101}), nil)
+	mockHTTP.expect(makeCursorResponse(types.RedirectList{Items: page2, Total: 101}), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+
+	err := c.loadState(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, c.load().Redirects, 100)
+	_, target := c.RedirectMatch("example.com", "/r99")
+	assert.Equal(t, "/new", target)
+	assert.Contains(t, logs.String(), "dropping duplicate redirect")
+	assert.Contains(t, logs.String(), "redirect #99 (BASIC /r99)")
+}
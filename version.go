@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+const DefaultMaxVersionParseTolerations = 3
+
+// ParsedVersion is a project version as understood by Config.VersionParser.
+// Changes are detected by comparing Key. Number is what State.ProjectVersion
+// and the agent reports carry, since the manager only knows integer versions;
+// it must not be zero for agent reports to be accepted.
+type ParsedVersion struct {
+	Key    string
+	Number int
+}
+
+// ParseIntVersion is the default VersionParser: the raw version is an integer.
+func ParseIntVersion(raw string) (ParsedVersion, error) {
+	number, err := strconv.Atoi(raw)
+	if err != nil {
+		return ParsedVersion{}, err
+	}
+	return ParsedVersion{Key: strconv.Itoa(number), Number: number}, nil
+}
+
+func (c *client) parseVersion(raw string) (ParsedVersion, error) {
+	if c.config().VersionParser == nil {
+		return ParseIntVersion(raw)
+	}
+	return c.config().VersionParser(raw)
+}
+
+// versionKey returns the Key of the state version, falling back to the
+// integer version for states built without a parser.
+func (s *State) versionKey() string {
+	if s.VersionKey != "" {
+		return s.VersionKey
+	}
+	return strconv.Itoa(s.ProjectVersion)
+}
+
+// versionParseError keeps the message of a VersionParser error while matching ErrInvalidVersion.
+type versionParseError struct {
+	err error
+}
+
+func (e versionParseError) Error() string { return e.err.Error() }
+
+func (e versionParseError) Unwrap() error { return e.err }
+
+func (e versionParseError) Is(target error) bool { return target == ErrInvalidVersion }
+
+// tolerateVersionError reports whether the invalid version behind err is ignored,
+// as allowed by Config.TolerateVersionParseErrors. The caller holds reloadMu.
+func (c *client) tolerateVersionError(ctx context.Context, err error) bool {
+	if !c.config().TolerateVersionParseErrors || !(errors.Is(err, ErrInvalidVersion) || errors.Is(err, ErrEmptyVersion)) {
+		return false
+	}
+	limit := c.config().GetMaxVersionParseTolerations()
+	if c.versionTolerations >= limit {
+		c.logger().WarnContext(ctx, "invalid version returned too many times in a row, failing the reload", "error", err, "max", limit)
+		return false
+	}
+	c.versionTolerations++
+	c.logger().WarnContext(ctx, "ignoring invalid version, keeping the current state", "error", err, "tolerated", c.versionTolerations, "max", limit)
+	return true
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/flectolab/flecto-manager/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIntVersion(t *testing.T) {
+	version, err := ParseIntVersion("007")
+
+	assert.NoError(t, err)
+	assert.Equal(t, ParsedVersion{Key: "7", Number: 7}, version)
+
+	_, err = ParseIntVersion("v1")
+	assert.Error(t, err)
+}
+
+func TestState_versionKey(t *testing.T) {
+	assert.Equal(t, "3", (&State{ProjectVersion: 3}).versionKey())
+	assert.Equal(t, "abc", (&State{ProjectVersion: 3, VersionKey: "abc"}).versionKey())
+}
+
+// semverParser keys on the raw string and reports the patch number to the manager.
+func semverParser(raw string) (ParsedVersion, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return ParsedVersion{}, errors.New("not a semver")
+	}
+	number, err := ParseIntVersion(parts[2])
+	if err != nil {
+		return ParsedVersion{}, err
+	}
+	return ParsedVersion{Key: raw, Number: number.Number}, nil
+}
+
+func TestClient_Reload_VersionParser(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().VersionParser = semverParser
+
+	redirects := []types.Redirect{{Type: types.RedirectTypeBasic, Source: "/old", Target: "/new"}}
+	mockHTTP.expect(makeVersionResponse("1.0.4"), nil)
+	mockHTTP.expect(makeVersionResponse("1.0.4"), nil)
+	mockHTTP.expect(makeRedirectsResponse(redirects, 1), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, "1.0.4", c.load().VersionKey)
+	assert.Equal(t, 4, c.GetStateVersion())
+
+	// same key: no reload, only a hit
+	mockHTTP.expect(makeVersionResponse("1.0.4"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, http.MethodPatch, mockHTTP.calls[6].Method)
+
+	// the number is the same but the key moved
+	mockHTTP.expect(makeVersionResponse("2.0.4"), nil)
+	mockHTTP.expect(makeVersionResponse("2.0.4"), nil)
+	mockHTTP.expect(makeRedirectsResponse(nil, 0), nil)
+	mockHTTP.expect(makePagesResponse(nil, 0), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	result, err := c.ReloadDetailed(t.Context())
+	assert.NoError(t, err)
+	assert.True(t, result.VersionChanged)
+	assert.Equal(t, "2.0.4", c.load().VersionKey)
+}
+
+func TestClient_Reload_VersionParserError(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().VersionParser = semverParser
+
+	mockHTTP.expect(makeVersionResponse("latest"), nil)
+
+	err := c.Reload()
+
+	assert.EqualError(t, err, "not a semver")
+}
+
+func TestClient_Reload_VersionParserError_IsInvalidVersion(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+
+	mockHTTP.expect(makeVersionResponse("<html>Bad Gateway</html>"), nil)
+
+	err := c.Reload()
+
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+	var numErr *strconv.NumError
+	assert.ErrorAs(t, err, &numErr)
+}
+
+func TestClient_Reload_TolerateVersionParseErrors(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().TolerateVersionParseErrors = true
+	c.State.Store(&State{ProjectVersion: 3, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	mockHTTP.expect(makeVersionResponse("<html>Bad Gateway</html>"), nil)
+	mockHTTP.expect(makeVersionResponse(""), nil)
+
+	assert.NoError(t, c.Reload())
+	assert.NoError(t, c.Reload())
+	assert.Len(t, mockHTTP.calls, 2)
+	assert.Equal(t, 3, c.GetStateVersion())
+	assert.True(t, c.Healthy())
+	assert.Equal(t, 2, c.versionTolerations)
+
+	mockHTTP.expect(makeVersionResponse("3"), nil)
+	mockHTTP.expect(makeAgentResponse(), nil)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, 0, c.versionTolerations)
+}
+
+func TestClient_Reload_TolerateVersionParseErrors_Exhausted(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().TolerateVersionParseErrors = true
+	c.config().MaxVersionParseTolerations = 2
+	c.State.Store(&State{ProjectVersion: 3, RedirectMatcher: types.NewRedirectTreeMatcher()})
+
+	for i := 0; i < 3; i++ {
+		mockHTTP.expect(makeVersionResponse("<html>Bad Gateway</html>"), nil)
+	}
+
+	assert.NoError(t, c.Reload())
+	assert.NoError(t, c.Reload())
+	err := c.Reload()
+
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+	assert.Equal(t, 3, c.GetStateVersion())
+}
+
+func TestClient_Reload_TolerateVersionParseErrors_OtherErrors(t *testing.T) {
+	c, mockHTTP, _ := newTestClient()
+	c.config().TolerateVersionParseErrors = true
+
+	mockHTTP.expect(makeErrorResponse(http.StatusBadGateway), nil)
+
+	assert.Error(t, c.Reload())
+	assert.Equal(t, 0, c.versionTolerations)
+}